@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+)
+
+// client calls the grader API with a personal access token or a Google ID token.
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newClient(baseURL string, token string) *client {
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// problem and submission are the parts of the API responses we use.
+type problem struct {
+	ID            uint
+	Name          string
+	TestcaseNum   uint
+	BestScore     *float64
+	AllowLanguage []struct{ Name string }
+	EditableFile  []struct{ Name string }
+}
+
+type submission struct {
+	ID      uint
+	Status  string
+	Verdict string
+}
+
+func (c *client) getProblems() ([]problem, error) {
+	var res dto.PaginationResponse[problem]
+	if err := c.do(http.MethodGet, "/problems?limit=50&page=1", nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+func (c *client) getProblem(id uint) (problem, error) {
+	var res dto.SuccessResponse[problem]
+	if err := c.do(http.MethodGet, fmt.Sprintf("/problems/%d", id), nil, &res); err != nil {
+		return problem{}, err
+	}
+	return res.Data, nil
+}
+
+func (c *client) getTemplates(id uint) ([]dto.TemplateFileResponse, error) {
+	var res dto.SuccessResponse[[]dto.TemplateFileResponse]
+	if err := c.do(http.MethodGet, fmt.Sprintf("/problems/%d/templates", id), nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+func (c *client) submit(body dto.SubmissionRequest) (submission, error) {
+	var res dto.SuccessResponse[submission]
+	if err := c.do(http.MethodPost, "/submissions", body, &res); err != nil {
+		return submission{}, err
+	}
+	return res.Data, nil
+}
+
+// download fetches a signed URL. It is sent without our token, the signature is the credential.
+func (c *client) download(url string, w io.Writer) error {
+	resp, err := c.http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download: unexpected status %s", resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// events follows the Server-Sent Events of a submission and calls handle for each one until the stream ends.
+func (c *client) events(id uint, handle func(event string, data []byte) error) error {
+	req, err := c.newRequest(http.MethodGet, fmt.Sprintf("/submissions/%d/events", id), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// The stream stays open for as long as grading takes
+	stream := &http.Client{}
+	resp, err := stream.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+
+	var event string
+	var data bytes.Buffer
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event != "" || data.Len() > 0 {
+				if err := handle(event, data.Bytes()); err != nil {
+					return err
+				}
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *client) do(method string, path string, body any, out any) error {
+	req, err := c.newRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return apiError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *client) newRequest(method string, path string, body any) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+func apiError(resp *http.Response) error {
+	var res dto.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil || res.Error == "" {
+		return fmt.Errorf("request failed: %s", resp.Status)
+	}
+	return errors.New(res.Error)
+}
@@ -0,0 +1,240 @@
+// grader-cli lists problems, downloads their templates and submits solutions from a terminal.
+//
+//	grader-cli problems
+//	grader-cli fetch [-dir DIR] PROBLEM_ID
+//	grader-cli submit [-dir DIR] [-lang LANGUAGE] [-no-wait] PROBLEM_ID
+//	grader-cli watch SUBMISSION_ID
+//
+// The API is read from GRADER_URL and the token from GRADER_TOKEN, create one with POST /tokens.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+)
+
+const defaultURL = "http://localhost:8080"
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	baseURL := os.Getenv("GRADER_URL")
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	token := os.Getenv("GRADER_TOKEN")
+	if token == "" {
+		fail(errors.New("GRADER_TOKEN is not set"))
+	}
+	c := newClient(baseURL, token)
+
+	var err error
+	switch os.Args[1] {
+	case "problems":
+		err = listProblems(c)
+	case "fetch":
+		err = fetch(c, os.Args[2:])
+	case "submit":
+		err = submit(c, os.Args[2:])
+	case "watch":
+		var id uint
+		id, err = idArg(flag.NewFlagSet("watch", flag.ExitOnError), os.Args[2:])
+		if err == nil {
+			err = watch(c, id)
+		}
+	default:
+		usage()
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func listProblems(c *client) error {
+	problems, err := c.getProblems()
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		best := "-"
+		if p.BestScore != nil {
+			best = strconv.FormatFloat(*p.BestScore, 'f', -1, 64)
+		}
+		fmt.Printf("%5d  %-40s  %3d tests  best %s\n", p.ID, p.Name, p.TestcaseNum, best)
+	}
+	return nil
+}
+
+func fetch(c *client, args []string) error {
+	flags := flag.NewFlagSet("fetch", flag.ExitOnError)
+	dir := flags.String("dir", ".", "directory to write the templates to")
+	force := flags.Bool("force", false, "overwrite files that already exist")
+	id, err := idArg(flags, args)
+	if err != nil {
+		return err
+	}
+
+	templates, err := c.getTemplates(id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	for _, t := range templates {
+		path, err := localPath(*dir, t.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err == nil && !*force {
+			fmt.Printf("skip %s, it already exists\n", path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = c.download(t.URL, f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("download %s: %w", t.Name, err)
+		}
+		fmt.Printf("wrote %s\n", path)
+	}
+	return nil
+}
+
+func submit(c *client, args []string) error {
+	flags := flag.NewFlagSet("submit", flag.ExitOnError)
+	dir := flags.String("dir", ".", "directory holding the edited files")
+	lang := flags.String("lang", "", "language to submit in, the first one the problem allows by default")
+	noWait := flags.Bool("no-wait", false, "don't wait for the verdict")
+	id, err := idArg(flags, args)
+	if err != nil {
+		return err
+	}
+
+	p, err := c.getProblem(id)
+	if err != nil {
+		return err
+	}
+	if *lang == "" {
+		if len(p.AllowLanguage) == 0 {
+			return errors.New("problem doesn't allow any language")
+		}
+		*lang = p.AllowLanguage[0].Name
+	}
+
+	body := dto.SubmissionRequest{Language: *lang, ProblemID: id}
+	for _, file := range p.EditableFile {
+		path, err := localPath(*dir, file.Name)
+		if err != nil {
+			return err
+		}
+		code, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		body.Codes = append(body.Codes, dto.CodeFile{Code: string(code), TemplateFileName: file.Name})
+	}
+
+	s, err := c.submit(body)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("submission %d queued\n", s.ID)
+	if *noWait {
+		return nil
+	}
+	return watch(c, s.ID)
+}
+
+// watch prints grading progress and exits non-zero unless the submission is accepted.
+func watch(c *client, id uint) error {
+	var verdict *dto.SubmissionStatusEvent
+	err := c.events(id, func(event string, data []byte) error {
+		switch event {
+		case "testcase":
+			var tc dto.TestcaseResultEvent
+			if err := json.Unmarshal(data, &tc); err != nil {
+				return err
+			}
+			fmt.Printf("  %-8s %s\n", tc.Result, tc.Name)
+			if tc.Message != "" {
+				fmt.Printf("           %s\n", tc.Message)
+			}
+		case "status":
+			var status dto.SubmissionStatusEvent
+			if err := json.Unmarshal(data, &status); err != nil {
+				return err
+			}
+			fmt.Printf("%s\n", status.Status)
+		case "verdict":
+			verdict = new(dto.SubmissionStatusEvent)
+			return json.Unmarshal(data, verdict)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if verdict == nil {
+		return errors.New("event stream ended before grading finished")
+	}
+
+	fmt.Printf("%s  %s/%s\n", verdict.Verdict, strconv.FormatFloat(verdict.Score, 'f', -1, 64), strconv.FormatFloat(verdict.MaxScore, 'f', -1, 64))
+	if verdict.Verdict != "ACCEPTED" {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// localPath places a template file under dir at its path within the project. Names that would end up
+// outside of dir are rejected, the server is not trusted with the local file system.
+func localPath(dir string, name string) (string, error) {
+	local := filepath.FromSlash(name)
+	if !filepath.IsLocal(local) || slices.Contains(strings.Split(filepath.ToSlash(local), "/"), "..") {
+		return "", fmt.Errorf("invalid template file name %q", name)
+	}
+	return filepath.Join(dir, local), nil
+}
+
+func idArg(flags *flag.FlagSet, args []string) (uint, error) {
+	if err := flags.Parse(args); err != nil {
+		return 0, err
+	}
+	if flags.NArg() != 1 {
+		return 0, fmt.Errorf("%s needs exactly one ID", flags.Name())
+	}
+	id, err := strconv.ParseUint(flags.Arg(0), 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID %q", flags.Arg(0))
+	}
+	return uint(id), nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: grader-cli problems | fetch [-dir DIR] ID | submit [-dir DIR] [-lang LANG] [-no-wait] ID | watch SUBMISSION_ID")
+	os.Exit(2)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "grader-cli: %v\n", err)
+	os.Exit(1)
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	"github.com/yokeTH/our-grader-backend/api/pkg/config"
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/service"
 	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"github.com/yokeTH/our-grader-backend/api/pkg/repository"
+	"github.com/yokeTH/our-grader-backend/api/pkg/storage"
 )
 
 func main() {
@@ -18,15 +22,49 @@ func main() {
 	}
 
 	if err := db.AutoMigrate(
+		&domain.AccessToken{},
+		&domain.Assignment{},
+		&domain.AssignmentExtension{},
+		&domain.Contest{},
+		&domain.ContestParticipant{},
+		&domain.Course{},
+		&domain.CourseMember{},
+		&domain.Diagnostic{},
+		&domain.GradingJob{},
 		&domain.Language{},
 		&domain.Problem{},
+		&domain.ProblemTestcase{},
+		&domain.ProblemVersion{},
+		&domain.ProblemVersionTestcase{},
 		&domain.SubmissionFile{},
 		&domain.Submission{},
 		&domain.TemplateFile{},
 		&domain.Testcase{},
+		&domain.User{},
+		&domain.UserRole{},
 	); err != nil {
 		log.Fatalf("Migration failed: %v", err)
 	}
 
 	fmt.Println("Migration completed")
+
+	store, err := storage.NewR2Storage(config.R2)
+	if err != nil {
+		log.Fatalf("Storage connection failed: %v", err)
+	}
+
+	// Problems and versions created before testcase definitions were stored can't be graded without them
+	problemService := service.NewProblemService(
+		repository.NewProblemRepository(db),
+		repository.NewTemplateFileRepository(db),
+		repository.NewSubmissionRepository(db),
+		repository.NewGradingJobRepository(db),
+		repository.NewCourseRepository(db),
+		store,
+	)
+	filled, err := problemService.BackfillTestcases(context.Background())
+	if err != nil {
+		log.Printf("Testcase backfill failed for some problems: %v", err)
+	}
+	fmt.Printf("Testcase definitions backfilled for %d problems and versions\n", filled)
 }
@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"github.com/yokeTH/our-grader-backend/api/pkg/config"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/service"
 	"github.com/yokeTH/our-grader-backend/api/pkg/database"
 	"github.com/yokeTH/our-grader-backend/api/pkg/handler"
@@ -33,27 +34,53 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to create storage: %v", err)
 	}
+
 	conn, err := grpc.NewClient(fmt.Sprintf("%s:50051", config.VerilogServer), grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
-		{
-			panic(err)
-		}
+		log.Fatalf("failed to create grader client: %v", err)
+	}
+	defer conn.Close()
+	grader := verilog.NewSomeServiceClient(conn)
+
+	userRepo := repository.NewUserRepository(db)
+	userService := service.NewUserService(userRepo)
+	userHandler := handler.NewUserHandler(userService)
+	if err := userService.Bootstrap(config.Auth.BootstrapAdmins); err != nil {
+		log.Fatalf("failed to bootstrap admins: %v", err)
+	}
+	verifier, err := middleware.NewGoogleVerifier(config.Auth)
+	if err != nil {
+		log.Fatalf("failed to set up token verification: %v", err)
 	}
-	verilogGradingServer := verilog.NewSomeServiceClient(conn)
+	tokenRepo := repository.NewAccessTokenRepository(db)
+	tokenService := service.NewAccessTokenService(tokenRepo)
+	tokenHandler := handler.NewAccessTokenHandler(tokenService)
+	auth := middleware.NewAuthMiddleware(verifier, config.Auth.AccessPolicy(), userService, tokenService)
 
-	auth := middleware.NewAuthMiddleware()
+	courseRepo := repository.NewCourseRepository(db)
+	courseService := service.NewCourseService(courseRepo, userRepo)
+	courseHandler := handler.NewCourseHandler(courseService)
 
 	templateRepo := repository.NewTemplateFileRepository(db)
 	problemRepo := repository.NewProblemRepository(db)
-	problemService := service.NewProblemService(problemRepo, templateRepo, store)
+	submissionRepo := repository.NewSubmissionRepository(db)
+	gradingJobRepo := repository.NewGradingJobRepository(db)
+	problemService := service.NewProblemService(problemRepo, templateRepo, submissionRepo, gradingJobRepo, courseRepo, store)
 	problemHandler := handler.NewProblemHandler(problemService)
 
 	languageRepo := repository.NewLanguageRepository(db)
 	languageService := service.NewLanguageService(languageRepo)
 	languageHandler := handler.NewLanguageHandler(languageService)
 
-	submissionRepo := repository.NewSubmissionRepository(db)
-	submissionService := service.NewSubmissionService(store, submissionRepo, problemRepo, verilogGradingServer)
+	assignmentRepo := repository.NewAssignmentRepository(db)
+	assignmentService := service.NewAssignmentService(assignmentRepo, courseRepo, problemRepo, submissionRepo, userRepo)
+	assignmentHandler := handler.NewAssignmentHandler(assignmentService)
+
+	contestRepo := repository.NewContestRepository(db)
+	contestService := service.NewContestService(contestRepo, courseRepo, problemRepo, submissionRepo, userRepo)
+	contestHandler := handler.NewContestHandler(contestService)
+
+	submissionService := service.NewSubmissionService(store, submissionRepo, problemRepo, gradingJobRepo, courseRepo, assignmentRepo, contestRepo, grader)
 	submissionHandler := handler.NewSubmissionHandler(submissionService)
 
 	s := server.New(
@@ -62,18 +89,83 @@ func main() {
 		server.WithPort(config.Server.Port),
 	)
 
-	problemRoute := s.App.Group("/problems")
-	problemRoute.Get("/", auth.Auth, problemHandler.GetProblems)
-	problemRoute.Get("/:id", auth.Auth, problemHandler.GetProblemByID)
-	problemRoute.Post("/", auth.Auth, auth.Owner, problemHandler.CreateProblem)
+	manageProblems := auth.Require(domain.PermissionManageProblems)
+
+	problemRoute := s.App.Group("/problems", auth.Auth)
+	problemRoute.Get("/", problemHandler.GetProblems)
+	problemRoute.Get("/:id", problemHandler.GetProblemByID)
+	problemRoute.Get("/:id/templates", problemHandler.GetTemplates)
+	problemRoute.Get("/:id/validation", manageProblems, problemHandler.GetValidation)
+	problemRoute.Post("/", manageProblems, problemHandler.CreateProblem)
+	problemRoute.Put("/:id", manageProblems, problemHandler.UpdateProblem)
+	problemRoute.Patch("/:id", manageProblems, problemHandler.UpdateProblem)
+	problemRoute.Delete("/:id", manageProblems, problemHandler.DeleteProblem)
+	problemRoute.Put("/:id/scoring", manageProblems, problemHandler.UpdateScoring)
+	problemRoute.Put("/:id/visibility", manageProblems, problemHandler.UpdateVisibility)
+	problemRoute.Post("/:id/regrade", auth.Require(domain.PermissionRegradeProblems), problemHandler.Regrade)
+
+	languageRoute := s.App.Group("/languages", auth.Auth)
+	languageRoute.Get("/", languageHandler.GetAll)
+	languageRoute.Post("/", auth.Require(domain.PermissionManageLanguages), languageHandler.Create)
+
+	submissionRoute := s.App.Group("/submissions", auth.Auth)
+	submissionRoute.Post("/", auth.Scope(domain.ScopeSubmit), submissionHandler.Submit)
+	submissionRoute.Get("/problem/:problemID", submissionHandler.GetSubmissions)
+	submissionRoute.Get("/:id", submissionHandler.GetSubmission)
+	submissionRoute.Get("/:id/events", submissionHandler.Events)
+
+	adminRoute := s.App.Group("/admin", auth.Auth)
+	adminRoute.Get("/submissions", auth.Require(domain.PermissionViewAllSubmission), submissionHandler.SearchSubmissions)
+	adminRoute.Get("/submissions/:id", auth.Require(domain.PermissionViewAllSubmission), submissionHandler.GetSubmissionDetails)
+	adminRoute.Get("/submissions/:id/source", auth.Require(domain.PermissionViewAllSubmission), submissionHandler.GetSource)
+
+	manageRoles := auth.Require(domain.PermissionManageRoles)
+
+	userRoute := s.App.Group("/users", auth.Auth)
+	userRoute.Get("/me", userHandler.Me)
+	userRoute.Get("/", manageRoles, userHandler.GetUsers)
+	userRoute.Put("/:email/roles/:role", manageRoles, userHandler.GrantRole)
+	userRoute.Delete("/:email/roles/:role", manageRoles, userHandler.RevokeRole)
+
+	// Course staff are checked by the course service, their roles only apply within the course
+	courseRoute := s.App.Group("/courses", auth.Auth)
+	courseRoute.Get("/", courseHandler.GetCourses)
+	courseRoute.Post("/", auth.Require(domain.PermissionManageCourses), courseHandler.Create)
+	courseRoute.Post("/join", auth.NoAccessToken, courseHandler.Join)
+	courseRoute.Post("/:id/invite-code", auth.NoAccessToken, courseHandler.RotateInviteCode)
+	courseRoute.Get("/:id/members", courseHandler.GetMembers)
+	courseRoute.Post("/:id/members/import", auth.NoAccessToken, courseHandler.ImportRoster)
+	courseRoute.Put("/:id/members/:email/role/:role", auth.NoAccessToken, courseHandler.SetMember)
+	courseRoute.Delete("/:id/members/:email", auth.NoAccessToken, courseHandler.RemoveMember)
+	courseRoute.Get("/:id/assignments", assignmentHandler.GetByCourse)
+	courseRoute.Get("/:id/gradebook", assignmentHandler.ExportCourse)
+
+	// Like courses, assignments are checked against the course role of the user
+	assignmentRoute := s.App.Group("/assignments", auth.Auth)
+	assignmentRoute.Post("/", auth.NoAccessToken, assignmentHandler.Create)
+	assignmentRoute.Get("/:id", assignmentHandler.Get)
+	assignmentRoute.Put("/:id", auth.NoAccessToken, assignmentHandler.Update)
+	assignmentRoute.Delete("/:id", auth.NoAccessToken, assignmentHandler.Delete)
+	assignmentRoute.Get("/:id/scores", assignmentHandler.Scores)
+	assignmentRoute.Get("/:id/gradebook", assignmentHandler.Export)
+	assignmentRoute.Put("/:id/extensions/:email", auth.NoAccessToken, assignmentHandler.SetExtension)
+	assignmentRoute.Delete("/:id/extensions/:email", auth.NoAccessToken, assignmentHandler.RemoveExtension)
 
-	languageRoute := s.App.Group("/languages")
-	languageRoute.Get("/", auth.Auth, auth.Owner, languageHandler.GetAll)
-	languageRoute.Post("/", auth.Auth, auth.Owner, languageHandler.Create)
+	contestRoute := s.App.Group("/contests", auth.Auth)
+	contestRoute.Get("/", contestHandler.GetContests)
+	contestRoute.Post("/", auth.NoAccessToken, contestHandler.Create)
+	contestRoute.Get("/:id", contestHandler.Get)
+	contestRoute.Put("/:id", auth.NoAccessToken, contestHandler.Update)
+	contestRoute.Delete("/:id", auth.NoAccessToken, contestHandler.Delete)
+	contestRoute.Get("/:id/scoreboard", contestHandler.Scoreboard)
+	contestRoute.Post("/:id/register", auth.NoAccessToken, contestHandler.Register)
+	contestRoute.Put("/:id/participants/:email", auth.NoAccessToken, contestHandler.AddParticipant)
+	contestRoute.Delete("/:id/participants/:email", auth.NoAccessToken, contestHandler.RemoveParticipant)
 
-	submissionRoute := s.App.Group("/submissions")
-	submissionRoute.Post("/", auth.Auth, submissionHandler.Submit)
-	submissionRoute.Get("/problem/:problemID", auth.Auth, submissionHandler.GetSubmissions)
+	tokenRoute := s.App.Group("/tokens", auth.Auth, auth.NoAccessToken)
+	tokenRoute.Get("/", tokenHandler.GetTokens)
+	tokenRoute.Post("/", tokenHandler.Create)
+	tokenRoute.Delete("/:id", tokenHandler.Revoke)
 
 	s.Start(ctx, stop)
 }
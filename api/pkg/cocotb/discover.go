@@ -0,0 +1,132 @@
+package cocotb
+
+import (
+	"archive/zip"
+	"bufio"
+	"errors"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var ErrNoTests = errors.New("no cocotb tests found in project")
+
+// Test identifies a cocotb test the same way the JUnit report does.
+type Test struct {
+	// Classname is the python module the test lives in
+	Classname string
+	Name      string
+}
+
+// Key is how a test result is matched to its definition, e.g. "test_adder.test_add".
+func (t Test) Key() string {
+	return t.Classname + "." + t.Name
+}
+
+var (
+	decoratorPattern = regexp.MustCompile(`^@cocotb\.test\b`)
+	functionPattern  = regexp.MustCompile(`^(?:async\s+)?def\s+(\w+)\s*\(`)
+	// MODULE is the cocotb 1.x name, COCOTB_TEST_MODULES the 2.x one
+	modulePattern = regexp.MustCompile(`^\s*(?:MODULE|COCOTB_TEST_MODULES)\s*[:?+]?=\s*(.+)$`)
+)
+
+// Discover lists the tests of a cocotb project zip by reading the @cocotb.test functions of its python files.
+// When a Makefile names the test modules, only those modules are considered.
+func Discover(r *zip.Reader) ([]Test, error) {
+	modules := make(map[string]bool)
+	tests := make([]Test, 0)
+
+	for _, file := range r.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		name := path.Base(file.Name)
+		switch {
+		case name == "Makefile":
+			found, err := readModules(file)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range found {
+				modules[m] = true
+			}
+		case strings.HasSuffix(name, ".py"):
+			found, err := readTests(file, strings.TrimSuffix(name, ".py"))
+			if err != nil {
+				return nil, err
+			}
+			tests = append(tests, found...)
+		}
+	}
+
+	result := make([]Test, 0, len(tests))
+	seen := make(map[string]bool)
+	for _, t := range tests {
+		if len(modules) > 0 && !modules[t.Classname] {
+			continue
+		}
+		if seen[t.Key()] {
+			continue
+		}
+		seen[t.Key()] = true
+		result = append(result, t)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoTests
+	}
+	return result, nil
+}
+
+func readModules(file *zip.File) ([]string, error) {
+	modules := make([]string, 0)
+	err := scanLines(file, func(line string) {
+		m := modulePattern.FindStringSubmatch(line)
+		if m == nil {
+			return
+		}
+		for _, module := range strings.FieldsFunc(m[1], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			// Only plain module names are understood, not make variables
+			if !strings.Contains(module, "$") {
+				modules = append(modules, module)
+			}
+		}
+	})
+	return modules, err
+}
+
+func readTests(file *zip.File, module string) ([]Test, error) {
+	tests := make([]Test, 0)
+	decorated := false
+	err := scanLines(file, func(line string) {
+		if decoratorPattern.MatchString(line) {
+			decorated = true
+			return
+		}
+		if !decorated {
+			return
+		}
+		if m := functionPattern.FindStringSubmatch(line); m != nil {
+			tests = append(tests, Test{Classname: module, Name: m[1]})
+			decorated = false
+		}
+	})
+	return tests, err
+}
+
+func scanLines(file *zip.File, fn func(line string)) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	scanner := bufio.NewScanner(io.LimitReader(rc, 1<<20))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	return scanner.Err()
+}
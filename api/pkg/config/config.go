@@ -5,6 +5,7 @@ import (
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/joho/godotenv"
 	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"github.com/yokeTH/our-grader-backend/api/pkg/middleware"
 	"github.com/yokeTH/our-grader-backend/api/pkg/server"
 	"github.com/yokeTH/our-grader-backend/api/pkg/storage"
 )
@@ -13,6 +14,7 @@ type Config struct {
 	Server        server.Config           `envPrefix:"SERVER_"`
 	PSQL          database.PostgresConfig `envPrefix:"POSTGRES_"`
 	R2            storage.R2Config        `envPrefix:"R2_"`
+	Auth          middleware.AuthConfig   `envPrefix:"AUTH_"`
 	VerilogServer string                  `env:"VERILOG_SERVER"`
 }
 
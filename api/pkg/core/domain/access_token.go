@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+	"gorm.io/gorm"
+)
+
+// AccessTokenPrefix marks personal access tokens, so they can be told apart from Google ID tokens.
+const AccessTokenPrefix = "ogp_"
+
+type Scope string
+
+const (
+	// ScopeRead allows GET requests
+	ScopeRead Scope = "read"
+	// ScopeSubmit allows creating submissions
+	ScopeSubmit Scope = "submit"
+)
+
+func (s Scope) IsValid() bool {
+	return s == ScopeRead || s == ScopeSubmit
+}
+
+// AccessToken is a personal access token for scripts and editors.
+// Only the hash of the token is stored, it is shown to its owner once when created.
+type AccessToken struct {
+	gorm.Model
+	UserID uint `gorm:"index"`
+	User   User
+	Name   string
+	// Prefix is the start of the token, enough for its owner to recognise it
+	Prefix     string
+	Hash       string `gorm:"uniqueIndex"`
+	Scopes     string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+func HashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *AccessToken) ScopeList() []Scope {
+	var scopes []Scope
+	for _, s := range strings.Split(t.Scopes, ",") {
+		if s != "" {
+			scopes = append(scopes, Scope(s))
+		}
+	}
+	return scopes
+}
+
+func (t *AccessToken) HasScope(scope Scope) bool {
+	return slices.Contains(t.ScopeList(), scope)
+}
+
+func (t *AccessToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && now.After(*t.ExpiresAt)
+}
+
+func (t *AccessToken) ToDTO() dto.AccessTokenResponse {
+	scopes := make([]string, 0)
+	for _, scope := range t.ScopeList() {
+		scopes = append(scopes, string(scope))
+	}
+	return dto.AccessTokenResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     scopes,
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+	}
+}
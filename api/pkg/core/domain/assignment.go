@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LatePolicy string
+
+const (
+	// LatePolicyNone gives full marks until the hard close
+	LatePolicyNone LatePolicy = "NONE"
+	// LatePolicyLinear takes off up to LatePenalty, growing evenly from the due time to the hard close
+	LatePolicyLinear LatePolicy = "LINEAR"
+	// LatePolicyPerDay takes off LatePenalty for every started day after the due time
+	LatePolicyPerDay LatePolicy = "PER_DAY"
+)
+
+func (p LatePolicy) IsValid() bool {
+	return p == LatePolicyNone || p == LatePolicyLinear || p == LatePolicyPerDay
+}
+
+// Assignment groups problems of a course under one set of deadlines.
+type Assignment struct {
+	gorm.Model
+	CourseID uint `gorm:"index"`
+	Name     string
+	OpenAt   time.Time
+	DueAt    time.Time
+	// CloseAt is the hard close, no submissions are taken after it
+	CloseAt    time.Time
+	LatePolicy LatePolicy `gorm:"default:NONE"`
+	// LatePenalty is a fraction of the score, e.g. 0.1 for 10%
+	LatePenalty float64
+	Problems    []Problem `gorm:"many2many:assignment_problems;"`
+	Extensions  []AssignmentExtension
+}
+
+// AssignmentExtension moves the deadlines of an assignment for one student.
+type AssignmentExtension struct {
+	gorm.Model
+	AssignmentID uint `gorm:"uniqueIndex:idx_assignment_extension"`
+	UserID       uint `gorm:"uniqueIndex:idx_assignment_extension"`
+	User         User
+	DueAt        time.Time
+	CloseAt      time.Time
+	GrantedBy    string
+}
+
+// ExtensionFor returns the extension of the user, or nil. Extensions must be loaded.
+func (a *Assignment) ExtensionFor(userID uint) *AssignmentExtension {
+	for i := range a.Extensions {
+		if a.Extensions[i].UserID == userID {
+			return &a.Extensions[i]
+		}
+	}
+	return nil
+}
+
+// Deadlines returns the due time and hard close that apply with the extension, which may be nil.
+func (a *Assignment) Deadlines(ext *AssignmentExtension) (time.Time, time.Time) {
+	if ext == nil {
+		return a.DueAt, a.CloseAt
+	}
+	return ext.DueAt, ext.CloseAt
+}
+
+// AcceptsAt reports whether the assignment takes submissions at the given time.
+func (a *Assignment) AcceptsAt(now time.Time, ext *AssignmentExtension) bool {
+	_, closeAt := a.Deadlines(ext)
+	return !now.Before(a.OpenAt) && now.Before(closeAt)
+}
+
+// Multiplier is the share of the score a submission made at the given time keeps after the late penalty.
+func (a *Assignment) Multiplier(at time.Time, ext *AssignmentExtension) float64 {
+	dueAt, closeAt := a.Deadlines(ext)
+	if !at.After(dueAt) {
+		return 1
+	}
+	if !at.Before(closeAt) {
+		return 0
+	}
+
+	switch a.LatePolicy {
+	case LatePolicyLinear:
+		late := at.Sub(dueAt).Seconds() / closeAt.Sub(dueAt).Seconds()
+		return math.Max(0, 1-a.LatePenalty*late)
+	case LatePolicyPerDay:
+		days := math.Ceil(at.Sub(dueAt).Hours() / 24)
+		return math.Max(0, 1-a.LatePenalty*days)
+	}
+	return 1
+}
+
+// FinalScore is the best score of the given submissions of one student to one problem after the late penalty.
+// It also returns the submission the score comes from, nil when none of them was graded.
+func (a *Assignment) FinalScore(submissions []Submission, ext *AssignmentExtension) (float64, *Submission) {
+	var best float64
+	var from *Submission
+	for i := range submissions {
+		s := &submissions[i]
+		if s.Status != SubmissionGraded {
+			continue
+		}
+		score := s.Score * a.Multiplier(s.CreatedAt, ext)
+		if from == nil || score > best {
+			best, from = score, s
+		}
+	}
+	return best, from
+}
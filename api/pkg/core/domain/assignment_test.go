@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	testDue   = time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC)
+	testClose = testDue.Add(4 * 24 * time.Hour)
+)
+
+func TestAssignmentMultiplier(t *testing.T) {
+	extension := &AssignmentExtension{DueAt: testDue.Add(48 * time.Hour), CloseAt: testClose.Add(48 * time.Hour)}
+	tests := []struct {
+		name    string
+		policy  LatePolicy
+		penalty float64
+		at      time.Time
+		ext     *AssignmentExtension
+		want    float64
+	}{
+		{name: "on time", policy: LatePolicyLinear, penalty: 0.2, at: testDue.Add(-time.Hour), want: 1},
+		{name: "exactly at due", policy: LatePolicyPerDay, penalty: 0.2, at: testDue, want: 1},
+		{name: "no policy", policy: LatePolicyNone, penalty: 0.2, at: testDue.Add(72 * time.Hour), want: 1},
+		{name: "at close", policy: LatePolicyNone, at: testClose, want: 0},
+		{name: "after close", policy: LatePolicyLinear, penalty: 0.2, at: testClose.Add(time.Minute), want: 0},
+		{name: "linear a quarter late", policy: LatePolicyLinear, penalty: 0.2, at: testDue.Add(24 * time.Hour), want: 0.95},
+		{name: "linear halfway", policy: LatePolicyLinear, penalty: 0.5, at: testDue.Add(48 * time.Hour), want: 0.75},
+		{name: "linear just before close", policy: LatePolicyLinear, penalty: 1, at: testClose.Add(-time.Second), want: 1.0 / (4 * 24 * 3600)},
+		{name: "per day rounds a started day up", policy: LatePolicyPerDay, penalty: 0.2, at: testDue.Add(time.Minute), want: 0.8},
+		{name: "per day one full day", policy: LatePolicyPerDay, penalty: 0.2, at: testDue.Add(24 * time.Hour), want: 0.8},
+		{name: "per day into the second day", policy: LatePolicyPerDay, penalty: 0.2, at: testDue.Add(25 * time.Hour), want: 0.6},
+		{name: "per day never below zero", policy: LatePolicyPerDay, penalty: 0.5, at: testDue.Add(70 * time.Hour), want: 0},
+		{name: "extension keeps it on time", policy: LatePolicyPerDay, penalty: 0.2, at: testDue.Add(24 * time.Hour), ext: extension, want: 1},
+		{name: "late after the extension", policy: LatePolicyPerDay, penalty: 0.2, at: testDue.Add(49 * time.Hour), ext: extension, want: 0.8},
+		{name: "extension moves the close", policy: LatePolicyNone, at: testClose.Add(time.Hour), ext: extension, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Assignment{DueAt: testDue, CloseAt: testClose, LatePolicy: tt.policy, LatePenalty: tt.penalty}
+			if got := a.Multiplier(tt.at, tt.ext); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Multiplier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssignmentFinalScore(t *testing.T) {
+	a := Assignment{DueAt: testDue, CloseAt: testClose, LatePolicy: LatePolicyPerDay, LatePenalty: 0.2}
+	submission := func(id uint, status SubmissionStatus, score float64, at time.Time) Submission {
+		return Submission{Model: gorm.Model{ID: id, CreatedAt: at}, Status: status, Score: score}
+	}
+	tests := []struct {
+		name        string
+		submissions []Submission
+		ext         *AssignmentExtension
+		want        float64
+		wantFrom    uint
+	}{
+		{name: "no submissions"},
+		{
+			name:        "nothing graded",
+			submissions: []Submission{submission(1, SubmissionRunning, 0, testDue), submission(2, SubmissionFailed, 0, testDue)},
+		},
+		{
+			name: "late submission beats a weaker one on time",
+			submissions: []Submission{
+				submission(1, SubmissionGraded, 60, testDue.Add(-time.Hour)),
+				submission(2, SubmissionGraded, 100, testDue.Add(time.Hour)),
+			},
+			want:     80,
+			wantFrom: 2,
+		},
+		{
+			name: "penalty makes the earlier one better",
+			submissions: []Submission{
+				submission(1, SubmissionGraded, 90, testDue.Add(-time.Hour)),
+				submission(2, SubmissionGraded, 100, testDue.Add(time.Hour)),
+			},
+			want:     90,
+			wantFrom: 1,
+		},
+		{
+			name: "ties keep the first",
+			submissions: []Submission{
+				submission(1, SubmissionGraded, 50, testDue.Add(-2*time.Hour)),
+				submission(2, SubmissionGraded, 50, testDue.Add(-time.Hour)),
+			},
+			want:     50,
+			wantFrom: 1,
+		},
+		{
+			name:        "after close scores nothing",
+			submissions: []Submission{submission(1, SubmissionGraded, 100, testClose)},
+			want:        0,
+			wantFrom:    1,
+		},
+		{
+			name:        "extension",
+			submissions: []Submission{submission(1, SubmissionGraded, 100, testDue.Add(time.Hour))},
+			ext:         &AssignmentExtension{DueAt: testDue.Add(24 * time.Hour), CloseAt: testClose},
+			want:        100,
+			wantFrom:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, from := a.FinalScore(tt.submissions, tt.ext)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("score = %v, want %v", got, tt.want)
+			}
+			switch {
+			case tt.wantFrom == 0 && from != nil:
+				t.Errorf("from = %d, want none", from.ID)
+			case tt.wantFrom != 0 && (from == nil || from.ID != tt.wantFrom):
+				t.Errorf("from = %v, want %d", from, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func TestAssignmentAcceptsAt(t *testing.T) {
+	a := Assignment{OpenAt: testDue.Add(-7 * 24 * time.Hour), DueAt: testDue, CloseAt: testClose}
+	extension := &AssignmentExtension{DueAt: testClose, CloseAt: testClose.Add(24 * time.Hour)}
+	tests := []struct {
+		name string
+		at   time.Time
+		ext  *AssignmentExtension
+		want bool
+	}{
+		{name: "before open", at: a.OpenAt.Add(-time.Second), want: false},
+		{name: "at open", at: a.OpenAt, want: true},
+		{name: "late", at: testDue.Add(time.Hour), want: true},
+		{name: "at close", at: testClose, want: false},
+		{name: "after close with an extension", at: testClose.Add(time.Hour), ext: extension, want: true},
+		{name: "extensions don't open early", at: a.OpenAt.Add(-time.Second), ext: extension, want: false},
+	}
+	for _, tt := range tests {
+		if got := a.AcceptsAt(tt.at, tt.ext); got != tt.want {
+			t.Errorf("%s: AcceptsAt() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ContestStyle string
+
+const (
+	// ContestICPC ranks by solved problems, then by penalty time
+	ContestICPC ContestStyle = "ICPC"
+	// ContestIOI ranks by the sum of the best score on every problem
+	ContestIOI ContestStyle = "IOI"
+)
+
+func (s ContestStyle) IsValid() bool {
+	return s == ContestICPC || s == ContestIOI
+}
+
+// Contest is a timed round on a set of problems for registered participants.
+type Contest struct {
+	gorm.Model
+	Name string
+	// CourseID is nil for a contest anyone can register for
+	CourseID *uint `gorm:"index"`
+	Style    ContestStyle
+	StartAt  time.Time
+	EndAt    time.Time
+	// FreezeMinutes hides results of submissions made in the last minutes of the contest from participants
+	FreezeMinutes int
+	// PenaltyMinutes is added to the ICPC penalty time for every rejected attempt on a solved problem
+	PenaltyMinutes int `gorm:"default:20"`
+	CreatedBy      string
+	Problems       []Problem `gorm:"many2many:contest_problems;"`
+	Participants   []ContestParticipant
+}
+
+type ContestParticipant struct {
+	gorm.Model
+	ContestID uint `gorm:"uniqueIndex:idx_contest_participant"`
+	UserID    uint `gorm:"uniqueIndex:idx_contest_participant"`
+	User      User
+}
+
+func (c *Contest) Running(now time.Time) bool {
+	return !now.Before(c.StartAt) && now.Before(c.EndAt)
+}
+
+// FreezeAt is when the scoreboard stops showing new results. It is the end time when nothing is frozen.
+func (c *Contest) FreezeAt() time.Time {
+	return c.EndAt.Add(-time.Duration(c.FreezeMinutes) * time.Minute)
+}
+
+// Frozen reports whether participants see the frozen scoreboard. It thaws once the contest is over.
+func (c *Contest) Frozen(now time.Time) bool {
+	return c.FreezeMinutes > 0 && !now.Before(c.FreezeAt()) && now.Before(c.EndAt)
+}
+
+// IsParticipant reports whether the user registered. Participants must be loaded.
+func (c *Contest) IsParticipant(userID uint) bool {
+	for _, p := range c.Participants {
+		if p.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Course struct {
+	gorm.Model
+	Code string `gorm:"uniqueIndex"`
+	Name string
+	// InviteCode lets students enroll themselves, only course staff get to see it
+	InviteCode string `gorm:"uniqueIndex"`
+	Members    []CourseMember
+}
+
+// CourseMember enrolls a user in a course. Role is STUDENT, TA or INSTRUCTOR and only applies within the course.
+type CourseMember struct {
+	gorm.Model
+	CourseID uint `gorm:"uniqueIndex:idx_course_member"`
+	Course   Course
+	UserID   uint `gorm:"uniqueIndex:idx_course_member"`
+	User     User
+	Role     Role
+}
+
+// IsCourseRole reports whether the role can be given within a course. Admins are only global.
+func (r Role) IsCourseRole() bool {
+	return r == RoleStudent || r == RoleTA || r == RoleInstructor
+}
+
+// IsCourseStaff reports whether the role lets a member see the course's hidden problems and everyone's submissions.
+func (r Role) IsCourseStaff() bool {
+	return r == RoleTA || r == RoleInstructor
+}
+
+// Enrollment is what a user can reach through the courses they belong to.
+type Enrollment struct {
+	CourseIDs      []uint
+	StaffCourseIDs []uint
+}
+
+func NewEnrollment(memberships []CourseMember) Enrollment {
+	var e Enrollment
+	for _, m := range memberships {
+		e.CourseIDs = append(e.CourseIDs, m.CourseID)
+		if m.Role.IsCourseStaff() {
+			e.StaffCourseIDs = append(e.StaffCourseIDs, m.CourseID)
+		}
+	}
+	return e
+}
+
+// IsMember reports whether the user belongs to the course. Everyone belongs to the nil course of global problems.
+func (e Enrollment) IsMember(courseID *uint) bool {
+	return courseID == nil || slices.Contains(e.CourseIDs, *courseID)
+}
+
+func (e Enrollment) IsStaff(courseID *uint) bool {
+	return courseID != nil && slices.Contains(e.StaffCourseIDs, *courseID)
+}
+
+// CanSee reports whether the problem is listed for the user. Global staff see every problem regardless.
+func (e Enrollment) CanSee(p *Problem, now time.Time) bool {
+	return e.IsStaff(p.CourseID) || (e.IsMember(p.CourseID) && p.VisibleAt(now))
+}
+
+// CanSubmit reports whether the user may submit to the problem. Course staff may try out problems before students can.
+func (e Enrollment) CanSubmit(p *Problem, now time.Time) bool {
+	return e.IsStaff(p.CourseID) || (e.IsMember(p.CourseID) && p.OpenAt(now))
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "gorm.io/gorm"
+
+type Diagnostic struct {
+	gorm.Model
+	SubmissionID uint `gorm:"index"`
+	File         string
+	Line         int
+	Severity     string
+	Message      string
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GradingJobStatus string
+
+const (
+	GradingJobPending GradingJobStatus = "PENDING"
+	GradingJobRunning GradingJobStatus = "RUNNING"
+	GradingJobDone    GradingJobStatus = "DONE"
+	GradingJobFailed  GradingJobStatus = "FAILED"
+)
+
+const defaultGradingJobMaxAttempts = 3
+
+type GradingJob struct {
+	gorm.Model
+	SubmissionID uint             `gorm:"index"`
+	Submission   Submission       `gorm:"foreignKey:SubmissionID"`
+	Status       GradingJobStatus `gorm:"default:PENDING;index"`
+	Stage        string
+	Attempts     uint
+	MaxAttempts  uint
+	RunAt        time.Time `gorm:"index"`
+	LockedAt     *time.Time
+	LastError    string
+}
+
+// NewGradingJob returns a pending job that grades the given submission as soon as a worker is free.
+func NewGradingJob(submissionID uint) *GradingJob {
+	return &GradingJob{
+		SubmissionID: submissionID,
+		Status:       GradingJobPending,
+		MaxAttempts:  defaultGradingJobMaxAttempts,
+		RunAt:        time.Now(),
+	}
+}
+
+// NewHeldGradingJob returns a pending job that isn't taken before the given time unless it is released first.
+// It is saved together with its submission, which sets SubmissionID.
+func NewHeldGradingJob(until time.Time) *GradingJob {
+	job := NewGradingJob(0)
+	job.RunAt = until
+	return job
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -10,6 +12,95 @@ type Problem struct {
 	Description    string
 	AllowLanguage  []Language `gorm:"many2many:support_languages;"`
 	TestcaseNum    uint
+	Testcases      []ProblemTestcase
 	EditableFile   []TemplateFile
 	ProjectZipFile string
+	// CurrentVersion is the version new submissions are graded against; ProjectZipFile is its zip
+	CurrentVersion uint
+	Versions       []ProblemVersion
+	// HideTestDetails keeps failure messages of hidden tests away from students
+	HideTestDetails bool
+	ScoringPolicy   ScoringPolicy `gorm:"default:SUM"`
+	// BestScore is the best score of the requesting user, filled in by the service
+	BestScore *float64 `gorm:"-"`
+	// ReferenceSubmissionID is the grading run of the reference solution of the current version, if it has one
+	ReferenceSubmissionID *uint
+	ValidationStatus      ValidationStatus `gorm:"default:NONE"`
+	// Visibility defaults to published for problems made before it existed, new problems start as drafts
+	Visibility Visibility `gorm:"default:PUBLISHED;index"`
+	// PublishAt is when a scheduled problem becomes visible
+	PublishAt *time.Time
+	// CloseAt is when the problem stops taking submissions, it stays visible
+	CloseAt *time.Time
+	// CourseID is nil for a problem open to every user
+	CourseID *uint `gorm:"index"`
+}
+
+// ProblemUpdate is a change to a problem that is written at once. Nil parts are left as they are.
+type ProblemUpdate struct {
+	Fields map[string]any
+	// Version is a new version to create, it is linked to Testcases
+	Version   *ProblemVersion
+	Templates []*TemplateFile
+	Testcases []ProblemTestcase
+	Languages []Language
+}
+
+type Visibility string
+
+const (
+	VisibilityDraft     Visibility = "DRAFT"
+	VisibilityScheduled Visibility = "SCHEDULED"
+	VisibilityPublished Visibility = "PUBLISHED"
+	VisibilityArchived  Visibility = "ARCHIVED"
+)
+
+func (v Visibility) IsValid() bool {
+	switch v {
+	case VisibilityDraft, VisibilityScheduled, VisibilityPublished, VisibilityArchived:
+		return true
+	}
+	return false
+}
+
+// VisibleAt reports whether students can see the problem at the given time. Staff see every problem.
+func (p *Problem) VisibleAt(now time.Time) bool {
+	switch p.Visibility {
+	case VisibilityPublished:
+		return true
+	case VisibilityScheduled:
+		return p.PublishAt != nil && !now.Before(*p.PublishAt)
+	}
+	return false
+}
+
+// OpenAt reports whether students can submit at the given time.
+func (p *Problem) OpenAt(now time.Time) bool {
+	return p.VisibleAt(now) && (p.CloseAt == nil || now.Before(*p.CloseAt))
+}
+
+type ValidationStatus string
+
+const (
+	// ValidationNone means the problem has no reference solution to check it with
+	ValidationNone    ValidationStatus = "NONE"
+	ValidationPending ValidationStatus = "PENDING"
+	ValidationPassed  ValidationStatus = "PASSED"
+	ValidationFailed  ValidationStatus = "FAILED"
+)
+
+// Gradable reports whether the problem can take submissions: its reference solution, if any, passed every test.
+func (p *Problem) Gradable() bool {
+	return p.ValidationStatus == "" || p.ValidationStatus == ValidationNone || p.ValidationStatus == ValidationPassed
+}
+
+// CurrentProblemVersion returns the version new submissions are graded against,
+// or nil for a problem created before versions existed. Versions must be loaded.
+func (p *Problem) CurrentProblemVersion() *ProblemVersion {
+	for i := range p.Versions {
+		if p.Versions[i].Version == p.CurrentVersion {
+			return &p.Versions[i]
+		}
+	}
+	return nil
 }
@@ -0,0 +1,16 @@
+package domain
+
+import "gorm.io/gorm"
+
+// ProblemTestcase is a cocotb test of a problem, found in its project when the problem is created.
+type ProblemTestcase struct {
+	gorm.Model
+	ProblemID uint `gorm:"uniqueIndex:idx_problem_testcase_key"`
+	Classname string
+	Name      string
+	// Key is "classname.name", the way results are matched to this definition
+	Key    string  `gorm:"uniqueIndex:idx_problem_testcase_key"`
+	Weight float64 `gorm:"default:1"`
+	// Group names the subtask of the test when the problem is scored by subtasks
+	Group string
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "gorm.io/gorm"
+
+// ProblemVersion is one uploaded project zip of a problem. Its zip is never overwritten,
+// so it is always possible to tell what a submission was graded against.
+type ProblemVersion struct {
+	gorm.Model
+	ProblemID uint `gorm:"uniqueIndex:idx_problem_version"`
+	Version   uint `gorm:"uniqueIndex:idx_problem_version"`
+	ZipFile   string
+	// Checksum is the hex encoded SHA-256 of the zip
+	Checksum  string
+	CreatedBy string
+	// Testcases are the definitions of the tests in this version's zip. A regrade against an older
+	// version uses them, so some may have been deleted from the problem since.
+	// Definitions are shared between versions, ApplyScoring puts back the weights this version had.
+	Testcases []ProblemTestcase `gorm:"many2many:problem_version_testcases"`
+}
+
+// ProblemVersionTestcase links a version to one of its testcase definitions. It keeps the weight and group
+// the test had in that version; links made before they were kept have neither and use the current ones.
+type ProblemVersionTestcase struct {
+	ProblemVersionID  uint `gorm:"primaryKey"`
+	ProblemTestcaseID uint `gorm:"primaryKey"`
+	Weight            *float64
+	Group             *string
+}
+
+// ApplyScoring sets the weight and group the links kept on the testcases of the version.
+func (v *ProblemVersion) ApplyScoring(links []ProblemVersionTestcase) {
+	byTestcase := make(map[uint]ProblemVersionTestcase, len(links))
+	for _, link := range links {
+		byTestcase[link.ProblemTestcaseID] = link
+	}
+	for i := range v.Testcases {
+		link, ok := byTestcase[v.Testcases[i].ID]
+		if !ok {
+			continue
+		}
+		if link.Weight != nil {
+			v.Testcases[i].Weight = *link.Weight
+		}
+		if link.Group != nil {
+			v.Testcases[i].Group = *link.Group
+		}
+	}
+}
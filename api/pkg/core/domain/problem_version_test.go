@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestProblemVersionApplyScoring(t *testing.T) {
+	weight := 5.0
+	group := "large"
+	version := ProblemVersion{Testcases: []ProblemTestcase{
+		{Model: gorm.Model{ID: 1}, Key: "t.a", Weight: 1, Group: "small"},
+		{Model: gorm.Model{ID: 2}, Key: "t.b", Weight: 2, Group: "small"},
+		{Model: gorm.Model{ID: 3}, Key: "t.c", Weight: 3},
+	}}
+
+	version.ApplyScoring([]ProblemVersionTestcase{
+		{ProblemTestcaseID: 1, Weight: &weight, Group: &group},
+		// Links made before weights were kept leave the current ones
+		{ProblemTestcaseID: 2},
+	})
+
+	want := []ProblemTestcase{
+		{Model: gorm.Model{ID: 1}, Key: "t.a", Weight: 5, Group: "large"},
+		{Model: gorm.Model{ID: 2}, Key: "t.b", Weight: 2, Group: "small"},
+		{Model: gorm.Model{ID: 3}, Key: "t.c", Weight: 3},
+	}
+	if !reflect.DeepEqual(version.Testcases, want) {
+		t.Errorf("testcases = %+v, want %+v", version.Testcases, want)
+	}
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+)
+
+// countsAsAttempt leaves out submissions that never got to run the tests, as ICPC does with compile errors.
+func countsAsAttempt(s *Submission) bool {
+	return s.Verdict != VerdictCompileError && s.Verdict != VerdictSystemError
+}
+
+// solved reports whether every testcase of the submission passed.
+func solved(s *Submission) bool {
+	if len(s.Testcases) == 0 {
+		return s.Verdict == VerdictAccepted
+	}
+	for _, t := range s.Testcases {
+		if t.Result != TestResultPass {
+			return false
+		}
+	}
+	return true
+}
+
+// Scoreboard ranks the participants on the submissions made during the contest. Participants and Problems
+// must be loaded, submissions sorted by time. When frozen, submissions made after the freeze show as pending.
+func (c *Contest) Scoreboard(submissions []Submission, frozen bool, now time.Time) dto.ScoreboardResponse {
+	res := dto.ScoreboardResponse{
+		ContestID:   c.ID,
+		Style:       string(c.Style),
+		Frozen:      frozen,
+		FreezeAt:    c.FreezeAt(),
+		GeneratedAt: now,
+		ProblemIDs:  make([]uint, len(c.Problems)),
+		Rows:        make([]dto.ScoreboardRow, 0, len(c.Participants)),
+	}
+	column := make(map[uint]int, len(c.Problems))
+	for i, p := range c.Problems {
+		res.ProblemIDs[i] = p.ID
+		column[p.ID] = i
+	}
+	row := make(map[string]int, len(c.Participants))
+	for _, p := range c.Participants {
+		row[p.User.Email] = len(res.Rows)
+		cells := make([]dto.ScoreboardCell, len(c.Problems))
+		for i, problem := range c.Problems {
+			cells[i].ProblemID = problem.ID
+		}
+		res.Rows = append(res.Rows, dto.ScoreboardRow{Email: p.User.Email, Name: p.User.Name, Problems: cells})
+	}
+
+	freezeAt := c.FreezeAt()
+	for i := range submissions {
+		s := &submissions[i]
+		r, ok := row[s.SubmissionBy]
+		col, known := column[s.ProblemID]
+		if !ok || !known || !c.Running(s.CreatedAt) {
+			continue
+		}
+		cell := &res.Rows[r].Problems[col]
+		if cell.Solved {
+			continue
+		}
+		if !s.IsFinished() || (frozen && !s.CreatedAt.Before(freezeAt)) {
+			cell.Pending++
+			continue
+		}
+		if !countsAsAttempt(s) {
+			continue
+		}
+		// Under ICPC an earlier attempt still pending may solve the problem first or add a rejection,
+		// so the ones after it wait for its result. IOI only takes the best score, whatever the order.
+		if c.Style != ContestIOI && cell.Pending > 0 {
+			cell.Pending++
+			continue
+		}
+
+		cell.Attempts++
+		if s.Status == SubmissionGraded {
+			cell.Score = max(cell.Score, s.Score)
+		}
+		if s.Status == SubmissionGraded && solved(s) {
+			minute := int(s.CreatedAt.Sub(c.StartAt) / time.Minute)
+			cell.Solved = true
+			cell.SolvedAt = &minute
+		}
+	}
+
+	for i := range res.Rows {
+		r := &res.Rows[i]
+		for _, cell := range r.Problems {
+			r.Score += cell.Score
+			if cell.Solved {
+				r.Solved++
+				r.Penalty += *cell.SolvedAt + (cell.Attempts-1)*c.PenaltyMinutes
+			}
+		}
+	}
+
+	compare := func(a, b dto.ScoreboardRow) int {
+		if c.Style == ContestIOI {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Or(cmp.Compare(b.Solved, a.Solved), cmp.Compare(a.Penalty, b.Penalty))
+	}
+	slices.SortStableFunc(res.Rows, func(a, b dto.ScoreboardRow) int {
+		return cmp.Or(compare(a, b), cmp.Compare(a.Email, b.Email))
+	})
+	// Tied rows share a rank
+	for i := range res.Rows {
+		if i > 0 && compare(res.Rows[i-1], res.Rows[i]) == 0 {
+			res.Rows[i].Rank = res.Rows[i-1].Rank
+		} else {
+			res.Rows[i].Rank = i + 1
+		}
+	}
+	return res
+}
@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var contestStart = time.Date(2026, 5, 2, 9, 0, 0, 0, time.UTC)
+
+func testContest(style ContestStyle, emails ...string) *Contest {
+	c := &Contest{
+		Style:          style,
+		StartAt:        contestStart,
+		EndAt:          contestStart.Add(5 * time.Hour),
+		FreezeMinutes:  60,
+		PenaltyMinutes: 20,
+		Problems:       []Problem{{Model: gorm.Model{ID: 1}}, {Model: gorm.Model{ID: 2}}},
+	}
+	for _, email := range emails {
+		c.Participants = append(c.Participants, ContestParticipant{User: User{Email: email}})
+	}
+	return c
+}
+
+// attempt is a submission made the given minutes into the contest. A pending verdict is still being graded.
+func attempt(email string, problemID uint, minute int, verdict Verdict, score float64) Submission {
+	status := SubmissionGraded
+	if verdict == VerdictPending {
+		status = SubmissionRunning
+	}
+	return Submission{
+		Model:        gorm.Model{CreatedAt: contestStart.Add(time.Duration(minute) * time.Minute)},
+		SubmissionBy: email,
+		ProblemID:    problemID,
+		Status:       status,
+		Verdict:      verdict,
+		Score:        score,
+	}
+}
+
+type rowWant struct {
+	email   string
+	rank    int
+	solved  int
+	penalty int
+	score   float64
+}
+
+func checkRows(t *testing.T, c *Contest, submissions []Submission, frozen bool, want []rowWant) {
+	t.Helper()
+	board := c.Scoreboard(submissions, frozen, contestStart.Add(6*time.Hour))
+	if len(board.Rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(board.Rows), len(want))
+	}
+	for i, w := range want {
+		r := board.Rows[i]
+		if r.Email != w.email || r.Rank != w.rank || r.Solved != w.solved || r.Penalty != w.penalty || r.Score != w.score {
+			t.Errorf("row %d = %s rank %d solved %d penalty %d score %v, want %+v", i, r.Email, r.Rank, r.Solved, r.Penalty, r.Score, w)
+		}
+	}
+}
+
+func TestScoreboardICPCRanking(t *testing.T) {
+	c := testContest(ContestICPC, "a@x", "b@x", "c@x", "d@x", "e@x")
+	submissions := []Submission{
+		attempt("a@x", 1, 5, VerdictWrongAnswer, 0),
+		attempt("c@x", 1, 8, VerdictCompileError, 0),
+		attempt("a@x", 1, 10, VerdictAccepted, 100),
+		attempt("c@x", 1, 15, VerdictAccepted, 100),
+		attempt("b@x", 1, 20, VerdictAccepted, 100),
+		attempt("e@x", 1, 20, VerdictAccepted, 100),
+		attempt("b@x", 2, 30, VerdictAccepted, 100),
+		attempt("a@x", 2, 50, VerdictAccepted, 100),
+		// After solving, further attempts don't count
+		attempt("b@x", 2, 60, VerdictWrongAnswer, 0),
+		// Someone who didn't register and a problem outside the contest
+		attempt("z@x", 1, 5, VerdictAccepted, 100),
+		attempt("d@x", 3, 5, VerdictAccepted, 100),
+	}
+	checkRows(t, c, submissions, false, []rowWant{
+		{email: "b@x", rank: 1, solved: 2, penalty: 50, score: 200},
+		{email: "a@x", rank: 2, solved: 2, penalty: 80, score: 200},
+		// A compile error isn't an attempt
+		{email: "c@x", rank: 3, solved: 1, penalty: 15, score: 100},
+		// Ties share a rank, the next row skips it
+		{email: "e@x", rank: 4, solved: 1, penalty: 20, score: 100},
+		{email: "d@x", rank: 5, solved: 0, penalty: 0, score: 0},
+	})
+
+	board := c.Scoreboard(submissions, false, contestStart)
+	if cell := board.Rows[1].Problems[0]; cell.Attempts != 2 || *cell.SolvedAt != 10 {
+		t.Errorf("a@x problem 1 = %+v, want 2 attempts solved at 10", cell)
+	}
+}
+
+func TestScoreboardTies(t *testing.T) {
+	c := testContest(ContestICPC, "b@x", "a@x", "c@x")
+	submissions := []Submission{
+		attempt("b@x", 1, 30, VerdictAccepted, 100),
+		attempt("a@x", 1, 10, VerdictWrongAnswer, 0),
+		attempt("a@x", 1, 10, VerdictAccepted, 100),
+	}
+	// b solved at 30, a at 10 with one rejection: both have 30 minutes of penalty
+	checkRows(t, c, submissions, false, []rowWant{
+		{email: "a@x", rank: 1, solved: 1, penalty: 30, score: 100},
+		{email: "b@x", rank: 1, solved: 1, penalty: 30, score: 100},
+		{email: "c@x", rank: 3},
+	})
+}
+
+func TestScoreboardFreeze(t *testing.T) {
+	c := testContest(ContestICPC, "a@x", "b@x")
+	submissions := []Submission{
+		attempt("a@x", 1, 100, VerdictAccepted, 100),
+		// The freeze starts an hour before the end, 240 minutes in
+		attempt("b@x", 1, 239, VerdictAccepted, 100),
+		attempt("b@x", 2, 245, VerdictAccepted, 100),
+		attempt("a@x", 2, 250, VerdictCompileError, 0),
+	}
+
+	checkRows(t, c, submissions, true, []rowWant{
+		{email: "a@x", rank: 1, solved: 1, penalty: 100, score: 100},
+		{email: "b@x", rank: 2, solved: 1, penalty: 239, score: 100},
+	})
+	board := c.Scoreboard(submissions, true, contestStart)
+	for _, row := range board.Rows {
+		// Even a compile error made during the freeze is hidden
+		if cell := row.Problems[1]; cell.Pending != 1 || cell.Solved || cell.Attempts != 0 {
+			t.Errorf("%s problem 2 = %+v, want a single pending attempt", row.Email, cell)
+		}
+	}
+
+	checkRows(t, c, submissions, false, []rowWant{
+		{email: "b@x", rank: 1, solved: 2, penalty: 484, score: 200},
+		{email: "a@x", rank: 2, solved: 1, penalty: 100, score: 100},
+	})
+}
+
+func TestScoreboardPending(t *testing.T) {
+	tests := []struct {
+		name        string
+		style       ContestStyle
+		first       Verdict
+		wantSolved  bool
+		wantPending int
+		wantAt      int
+		wantTries   int
+		wantScore   float64
+	}{
+		// The earlier attempt may still be accepted and solve it sooner, or add a rejection
+		{name: "ICPC waits for an earlier attempt", style: ContestICPC, first: VerdictPending, wantPending: 2},
+		{name: "ICPC once it is rejected", style: ContestICPC, first: VerdictWrongAnswer, wantSolved: true, wantAt: 10, wantTries: 2, wantScore: 100},
+		{name: "ICPC once it is accepted", style: ContestICPC, first: VerdictAccepted, wantSolved: true, wantAt: 5, wantTries: 1, wantScore: 100},
+		{name: "IOI takes the best score regardless", style: ContestIOI, first: VerdictPending, wantSolved: true, wantPending: 1, wantAt: 10, wantTries: 1, wantScore: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testContest(tt.style, "a@x")
+			firstScore := 0.0
+			if tt.first == VerdictAccepted {
+				firstScore = 100
+			}
+			board := c.Scoreboard([]Submission{
+				attempt("a@x", 1, 5, tt.first, firstScore),
+				attempt("a@x", 1, 10, VerdictAccepted, 100),
+			}, false, contestStart)
+			cell := board.Rows[0].Problems[0]
+			if cell.Solved != tt.wantSolved || cell.Pending != tt.wantPending || cell.Attempts != tt.wantTries || cell.Score != tt.wantScore {
+				t.Fatalf("cell = %+v", cell)
+			}
+			if tt.wantSolved && *cell.SolvedAt != tt.wantAt {
+				t.Errorf("solved at %d, want %d", *cell.SolvedAt, tt.wantAt)
+			}
+		})
+	}
+}
+
+func TestScoreboardIOIRanking(t *testing.T) {
+	c := testContest(ContestIOI, "a@x", "b@x", "c@x", "d@x")
+	submissions := []Submission{
+		attempt("a@x", 1, 5, VerdictWrongAnswer, 40),
+		attempt("a@x", 1, 20, VerdictWrongAnswer, 30),
+		attempt("a@x", 2, 30, VerdictAccepted, 100),
+		attempt("b@x", 1, 10, VerdictAccepted, 100),
+		attempt("b@x", 2, 15, VerdictWrongAnswer, 40),
+		attempt("c@x", 1, 60, VerdictWrongAnswer, 70),
+		attempt("c@x", 2, 61, VerdictWrongAnswer, 70),
+		// Not graded, so no score
+		attempt("d@x", 1, 1, VerdictSystemError, 100),
+		// Made before the start
+		attempt("d@x", 2, -5, VerdictAccepted, 100),
+	}
+	checkRows(t, c, submissions, false, []rowWant{
+		{email: "a@x", rank: 1, solved: 1, penalty: 30, score: 140},
+		{email: "b@x", rank: 1, solved: 1, penalty: 10, score: 140},
+		{email: "c@x", rank: 1, solved: 0, penalty: 0, score: 140},
+		{email: "d@x", rank: 4},
+	})
+}
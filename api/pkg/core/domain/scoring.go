@@ -0,0 +1,67 @@
+package domain
+
+type ScoringPolicy string
+
+const (
+	// ScoringAllOrNothing gives the full score only when every test passes
+	ScoringAllOrNothing ScoringPolicy = "ALL_OR_NOTHING"
+	// ScoringSum adds up the weights of the passed tests
+	ScoringSum ScoringPolicy = "SUM"
+	// ScoringSubtask adds up the weights of the groups whose tests all pass
+	ScoringSubtask ScoringPolicy = "SUBTASK"
+)
+
+func (p ScoringPolicy) IsValid() bool {
+	return p == ScoringAllOrNothing || p == ScoringSum || p == ScoringSubtask
+}
+
+// Score computes the score of testcase results under the problem's policy.
+// Testcases must belong to the problem and its Testcases must be loaded.
+func (p *Problem) Score(testcases []Testcase) (score float64, maxScore float64) {
+	passed := make(map[uint]bool, len(testcases))
+	for _, t := range testcases {
+		passed[t.ProblemTestcaseID] = t.Result == TestResultPass
+	}
+
+	allPassed := true
+	passedWeight := 0.0
+	groupPassed := make(map[string]bool)
+	groupWeight := make(map[string]float64)
+	for _, definition := range p.Testcases {
+		ok := passed[definition.ID]
+		maxScore += definition.Weight
+		if ok {
+			passedWeight += definition.Weight
+		} else {
+			allPassed = false
+		}
+
+		// A test without a group is a subtask of its own
+		group := definition.Group
+		if group == "" {
+			group = definition.Key
+		}
+		if _, seen := groupPassed[group]; !seen {
+			groupPassed[group] = true
+		}
+		groupPassed[group] = groupPassed[group] && ok
+		groupWeight[group] += definition.Weight
+	}
+
+	switch p.ScoringPolicy {
+	case ScoringAllOrNothing:
+		if allPassed {
+			score = maxScore
+		}
+	case ScoringSubtask:
+		for group, ok := range groupPassed {
+			if ok {
+				score += groupWeight[group]
+			}
+		}
+	default:
+		score = passedWeight
+	}
+
+	return score, maxScore
+}
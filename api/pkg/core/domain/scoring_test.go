@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestProblemScore(t *testing.T) {
+	definitions := []ProblemTestcase{
+		{Model: gorm.Model{ID: 1}, Key: "t.a", Weight: 1, Group: "small"},
+		{Model: gorm.Model{ID: 2}, Key: "t.b", Weight: 2, Group: "small"},
+		{Model: gorm.Model{ID: 3}, Key: "t.c", Weight: 3, Group: "large"},
+		{Model: gorm.Model{ID: 4}, Key: "t.d", Weight: 4},
+	}
+	results := func(passed ...uint) []Testcase {
+		testcases := make([]Testcase, len(definitions))
+		for i, d := range definitions {
+			testcases[i] = Testcase{ProblemTestcaseID: d.ID, Result: TestResultFail}
+			for _, id := range passed {
+				if id == d.ID {
+					testcases[i].Result = TestResultPass
+				}
+			}
+		}
+		return testcases
+	}
+
+	tests := []struct {
+		name      string
+		policy    ScoringPolicy
+		testcases []Testcase
+		want      float64
+	}{
+		{name: "sum of passed weights", policy: ScoringSum, testcases: results(1, 3), want: 4},
+		{name: "sum without passes", policy: ScoringSum, testcases: results(), want: 0},
+		{name: "default policy sums", policy: "", testcases: results(2, 4), want: 6},
+		{name: "all or nothing with a failure", policy: ScoringAllOrNothing, testcases: results(1, 2, 3), want: 0},
+		{name: "all or nothing all passed", policy: ScoringAllOrNothing, testcases: results(1, 2, 3, 4), want: 10},
+		{name: "subtask partly passed", policy: ScoringSubtask, testcases: results(1, 3), want: 3},
+		{name: "subtask whole group", policy: ScoringSubtask, testcases: results(1, 2), want: 3},
+		{name: "ungrouped test is its own subtask", policy: ScoringSubtask, testcases: results(4), want: 4},
+		{name: "missing results fail", policy: ScoringSum, testcases: []Testcase{{ProblemTestcaseID: 4, Result: TestResultPass}}, want: 4},
+		{name: "skipped is not passed", policy: ScoringSum, testcases: []Testcase{{ProblemTestcaseID: 1, Result: TestResultSkipped}}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := Problem{ScoringPolicy: tt.policy, Testcases: definitions}
+			score, maxScore := problem.Score(tt.testcases)
+			if score != tt.want {
+				t.Errorf("score = %v, want %v", score, tt.want)
+			}
+			if maxScore != 10 {
+				t.Errorf("max score = %v, want 10", maxScore)
+			}
+		})
+	}
+}
@@ -1,9 +1,36 @@
 package domain
 
 import (
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
 	"gorm.io/gorm"
 )
 
+type SubmissionStatus string
+
+const (
+	SubmissionQueued  SubmissionStatus = "QUEUED"
+	SubmissionRunning SubmissionStatus = "RUNNING"
+	SubmissionGraded  SubmissionStatus = "GRADED"
+	SubmissionFailed  SubmissionStatus = "FAILED"
+)
+
+type Verdict string
+
+const (
+	VerdictPending      Verdict = "PENDING"
+	VerdictAccepted     Verdict = "ACCEPTED"
+	VerdictWrongAnswer  Verdict = "WRONG_ANSWER"
+	VerdictCompileError Verdict = "COMPILE_ERROR"
+	VerdictRuntimeError Verdict = "RUNTIME_ERROR"
+	VerdictSystemError  Verdict = "SYSTEM_ERROR"
+
+	VerdictTimeLimitExceeded    Verdict = "TIME_LIMIT_EXCEEDED"
+	VerdictMemoryLimitExceeded  Verdict = "MEMORY_LIMIT_EXCEEDED"
+	VerdictProcessLimitExceeded Verdict = "PROCESS_LIMIT_EXCEEDED"
+)
+
 type Submission struct {
 	gorm.Model
 	SubmissionBy    string
@@ -14,6 +41,93 @@ type Submission struct {
 	Additional      string
 	ProblemID       uint
 	Problem         Problem `gorm:"foreignKey:ProblemID"`
-	MemoryUsageMB   uint
-	Testcases       []Testcase
+	// ProblemVersionID is nil for submissions made before problems were versioned
+	ProblemVersionID *uint
+	ProblemVersion   *ProblemVersion `gorm:"foreignKey:ProblemVersionID"`
+	MemoryUsageMB    uint
+	RunTimeMs        uint
+	Score            float64
+	MaxScore         float64
+	Status           SubmissionStatus `gorm:"default:QUEUED"`
+	Verdict          Verdict          `gorm:"default:PENDING"`
+	Testcases        []Testcase
+	Diagnostics      []Diagnostic
+	// Reference marks the grading run of a problem's reference solution, which isn't anyone's attempt
+	Reference bool `gorm:"default:false;index"`
+}
+
+// HideTestDetails clears the testcase messages when the problem asks to keep them hidden.
+// Problem must be loaded.
+func (s *Submission) HideTestDetails() {
+	if !s.Problem.HideTestDetails {
+		return
+	}
+	for i := range s.Testcases {
+		s.Testcases[i].Message = ""
+	}
+}
+
+// ProjectZipFile is the key of the project zip the submission is graded against.
+// Problem must be loaded.
+func (s *Submission) ProjectZipFile() string {
+	if s.ProblemVersion != nil {
+		return s.ProblemVersion.ZipFile
+	}
+	return s.Problem.ProjectZipFile
+}
+
+// IsFinished reports whether the grader is done with the submission, successfully or not.
+func (s *Submission) IsFinished() bool {
+	return s.Status == SubmissionGraded || s.Status == SubmissionFailed
+}
+
+// SubmissionFilter narrows down a search over every submission. Zero values don't filter.
+type SubmissionFilter struct {
+	ProblemID    uint
+	SubmissionBy string
+	Verdict      Verdict
+	LanguageName string
+	From         *time.Time
+	To           *time.Time
+	MinScore     *float64
+	MaxScore     *float64
+	// SortByScore sorts by score instead of by submission time, ties are broken by ID
+	SortByScore bool
+	Ascending   bool
+	// After continues a search past the last submission of the previous page
+	After *SubmissionCursor
+}
+
+// SubmissionCursor is the position of a submission in the sort order of a search.
+type SubmissionCursor struct {
+	ID    uint
+	Score float64
+}
+
+// ToDetailDTO describes the submission without the links to its files, which need signing.
+// Testcases and Diagnostics must be loaded.
+func (s *Submission) ToDetailDTO() dto.SubmissionDetailResponse {
+	res := dto.SubmissionDetailResponse{
+		ID:            s.ID,
+		ProblemID:     s.ProblemID,
+		SubmissionBy:  s.SubmissionBy,
+		Language:      s.LanguageName,
+		Status:        string(s.Status),
+		Verdict:       string(s.Verdict),
+		Score:         s.Score,
+		MaxScore:      s.MaxScore,
+		RunTimeMs:     s.RunTimeMs,
+		MemoryUsageMB: s.MemoryUsageMB,
+		CreatedAt:     s.CreatedAt,
+		Files:         []dto.TemplateFileResponse{},
+		Testcases:     make([]dto.TestcaseResultEvent, len(s.Testcases)),
+		Diagnostics:   make([]dto.DiagnosticResponse, len(s.Diagnostics)),
+	}
+	for i, t := range s.Testcases {
+		res.Testcases[i] = dto.TestcaseResultEvent{ID: t.ID, Name: t.Name, Result: string(t.Result), Message: t.Message}
+	}
+	for i, d := range s.Diagnostics {
+		res.Diagnostics[i] = dto.DiagnosticResponse{File: d.File, Line: d.Line, Severity: d.Severity, Message: d.Message}
+	}
+	return res
 }
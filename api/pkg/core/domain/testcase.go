@@ -11,12 +11,21 @@ const (
 	TestResultPass       TestcaseResult = "PASS"
 	TestResultFail       TestcaseResult = "FAIL"
 	TestResultCompile    TestcaseResult = "COMPILE_ERROR"
+	TestResultError      TestcaseResult = "ERROR"
+	TestResultSkipped    TestcaseResult = "SKIPPED"
 )
 
 type Testcase struct {
 	gorm.Model
-	Name         string
-	SubmissionID uint
-	Submission   Submission     `gorm:"foreignKey:SubmissionID"`
-	Result       TestcaseResult `gorm:"default:NOT_STARTED"`
+	// Name is the key of the problem testcase this is the result of
+	Name              string
+	ProblemTestcaseID uint
+	ProblemTestcase   ProblemTestcase `gorm:"foreignKey:ProblemTestcaseID"`
+	SubmissionID      uint
+	Submission        Submission     `gorm:"foreignKey:SubmissionID"`
+	Result            TestcaseResult `gorm:"default:NOT_STARTED"`
+	WallTimeSec       float64
+	SimTimeNs         float64
+	// Message explains why the test did not pass, e.g. the failed assertion
+	Message string
 }
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+type Role string
+
+const (
+	RoleStudent    Role = "STUDENT"
+	RoleTA         Role = "TA"
+	RoleInstructor Role = "INSTRUCTOR"
+	RoleAdmin      Role = "ADMIN"
+)
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+type Permission string
+
+const (
+	PermissionManageProblems    Permission = "problems:manage"
+	PermissionViewHiddenProblem Permission = "problems:view_hidden"
+	PermissionRegradeProblems   Permission = "problems:regrade"
+	PermissionManageLanguages   Permission = "languages:manage"
+	PermissionViewAllSubmission Permission = "submissions:view_all"
+	PermissionManageRoles       Permission = "roles:manage"
+	PermissionManageCourses     Permission = "courses:manage"
+)
+
+// rolePermissions lists what each role may do on top of what every signed in user may do.
+var rolePermissions = map[Role][]Permission{
+	RoleStudent: {},
+	RoleTA: {
+		PermissionViewAllSubmission,
+		PermissionViewHiddenProblem,
+	},
+	RoleInstructor: {
+		PermissionViewAllSubmission,
+		PermissionViewHiddenProblem,
+		PermissionManageProblems,
+		PermissionRegradeProblems,
+		PermissionManageLanguages,
+		PermissionManageCourses,
+	},
+	RoleAdmin: {
+		PermissionViewAllSubmission,
+		PermissionViewHiddenProblem,
+		PermissionManageProblems,
+		PermissionRegradeProblems,
+		PermissionManageLanguages,
+		PermissionManageRoles,
+		PermissionManageCourses,
+	},
+}
+
+type User struct {
+	gorm.Model
+	Email   string `gorm:"uniqueIndex"`
+	Name    string
+	Picture string
+	// HostedDomain is the hd claim of the user's last Google sign in, empty for a personal account
+	HostedDomain string
+	Roles        []UserRole
+}
+
+type UserRole struct {
+	gorm.Model
+	UserID    uint `gorm:"uniqueIndex:idx_user_role"`
+	Role      Role `gorm:"uniqueIndex:idx_user_role"`
+	GrantedBy string
+}
+
+func (u *User) HasRole(role Role) bool {
+	return slices.ContainsFunc(u.Roles, func(r UserRole) bool { return r.Role == role })
+}
+
+// Can reports whether any role of the user grants the permission. Roles must be loaded.
+func (u *User) Can(permission Permission) bool {
+	for _, r := range u.Roles {
+		if slices.Contains(rolePermissions[r.Role], permission) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package port
+
+import (
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+)
+
+type AccessTokenRepository interface {
+	Create(token *domain.AccessToken) error
+	GetByHash(hash string) (domain.AccessToken, error)
+	GetByUserID(userID uint) ([]domain.AccessToken, error)
+	Delete(userID uint, id uint) (int64, error)
+	TouchLastUsed(id uint, at time.Time) error
+}
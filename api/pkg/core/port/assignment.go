@@ -0,0 +1,14 @@
+package port
+
+import "github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+
+type AssignmentRepository interface {
+	Create(assignment *domain.Assignment) error
+	GetByID(id uint) (domain.Assignment, error)
+	GetByCourseID(courseID uint) ([]domain.Assignment, error)
+	GetByProblemID(problemID uint) ([]domain.Assignment, error)
+	Update(assignment *domain.Assignment) error
+	Delete(id uint) error
+	UpsertExtension(extension *domain.AssignmentExtension) error
+	DeleteExtension(assignmentID uint, userID uint) (int64, error)
+}
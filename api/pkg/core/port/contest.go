@@ -0,0 +1,15 @@
+package port
+
+import "github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+
+type ContestRepository interface {
+	Create(contest *domain.Contest) error
+	GetByID(id uint) (domain.Contest, error)
+	GetContests(courseIDs []uint) ([]domain.Contest, error)
+	GetByProblemID(problemID uint) ([]domain.Contest, error)
+	GetAll() ([]domain.Contest, error)
+	Update(contest *domain.Contest) error
+	Delete(id uint) error
+	AddParticipant(participant *domain.ContestParticipant) (bool, error)
+	RemoveParticipant(contestID uint, userID uint) (int64, error)
+}
@@ -0,0 +1,17 @@
+package port
+
+import "github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+
+type CourseRepository interface {
+	Create(course *domain.Course) error
+	GetByID(id uint) (domain.Course, error)
+	GetByInviteCode(code string) (domain.Course, error)
+	GetByUserID(userID uint) ([]domain.Course, error)
+	UpdateInviteCode(id uint, code string) error
+	GetMembers(courseID uint) ([]domain.CourseMember, error)
+	GetMembership(courseID uint, userID uint) (domain.CourseMember, error)
+	GetMemberships(userID uint) ([]domain.CourseMember, error)
+	AddMember(member *domain.CourseMember) (bool, error)
+	UpsertMember(member *domain.CourseMember) error
+	RemoveMember(courseID uint, userID uint) (int64, error)
+}
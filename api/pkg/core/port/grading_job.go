@@ -0,0 +1,17 @@
+package port
+
+import (
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+)
+
+type GradingJobRepository interface {
+	Release(id uint) error
+	EnqueueUnlessPending(job *domain.GradingJob) (bool, error)
+	Claim(now time.Time) (*domain.GradingJob, error)
+	Complete(job *domain.GradingJob) (bool, error)
+	UpdateStage(id uint, stage string) error
+	Fail(job *domain.GradingJob, cause error, retryAt time.Time) (bool, error)
+	RequeueStale(lockedBefore time.Time) (int64, []domain.GradingJob, error)
+}
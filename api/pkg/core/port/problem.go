@@ -2,6 +2,7 @@ package port
 
 import (
 	"mime/multipart"
+	"time"
 
 	"context"
 
@@ -14,22 +15,45 @@ type ProblemHandler interface {
 	CreateProblem(ctx *fiber.Ctx) error
 	GetProblems(ctx *fiber.Ctx) error
 	GetProblemByID(ctx *fiber.Ctx) error
+	GetTemplates(ctx *fiber.Ctx) error
+	GetValidation(ctx *fiber.Ctx) error
 	UpdateProblem(ctx *fiber.Ctx) error
 	DeleteProblem(ctx *fiber.Ctx) error
+	UpdateScoring(ctx *fiber.Ctx) error
+	UpdateVisibility(ctx *fiber.Ctx) error
+	Regrade(ctx *fiber.Ctx) error
 }
 
 type ProblemService interface {
-	CreateProblem(ctx context.Context, problem dto.ProblemRequestFrom, zip *multipart.FileHeader) (domain.Problem, error)
-	GetProblemByID(id uint) (domain.Problem, error)
-	GetProblems(limit int, page int) ([]domain.Problem, int, int, error)
-	UpdateProblem(id uint, problem domain.Problem) (domain.Problem, error)
-	DeleteProblem() error
+	CreateProblem(ctx context.Context, by string, problem dto.ProblemRequestFrom, zip *multipart.FileHeader) (domain.Problem, error)
+	GetProblemByID(user domain.User, id uint) (domain.Problem, error)
+	GetProblems(user domain.User, limit int, page int) ([]domain.Problem, int, int, error)
+	GetTemplates(ctx context.Context, user domain.User, id uint) ([]dto.TemplateFileResponse, error)
+	UpdateVisibility(id uint, body dto.ProblemVisibilityRequest) (domain.Problem, error)
+	GetValidation(ctx context.Context, id uint) (dto.ProblemValidationResponse, error)
+	UpdateProblem(ctx context.Context, by string, id uint, body dto.ProblemUpdateForm, zip *multipart.FileHeader) (domain.Problem, error)
+	DeleteProblem(ctx context.Context, id uint) error
+	UpdateScoring(id uint, body dto.ProblemScoringRequest) (domain.Problem, error)
+	Regrade(id uint, version uint) (dto.RegradeResponse, error)
+	BackfillTestcases(ctx context.Context) (int, error)
 }
 
 type ProblemRepository interface {
 	CreateProblem(problem *domain.Problem) error
 	GetProblems(limit int, page int) ([]domain.Problem, int, int, error)
 	GetProblemByID(id uint) (domain.Problem, error)
+	GetWithoutTestcases() ([]domain.Problem, error)
+	GetVisibleProblems(now time.Time, enrollment domain.Enrollment, limit int, page int) ([]domain.Problem, int, int, error)
+	GetVisibleProblemByID(id uint, now time.Time, enrollment domain.Enrollment) (domain.Problem, error)
 	UpdateProblem(id uint, problem domain.Problem) (domain.Problem, error)
 	DeleteProblem(id uint) error
+	UpdateScoring(id uint, policy domain.ScoringPolicy, testcases []domain.ProblemTestcase) error
+	UpdateFields(id uint, fields map[string]any) error
+	Update(id uint, update domain.ProblemUpdate) error
+	ReplaceTestcases(id uint, testcases []domain.ProblemTestcase) error
+	CreateVersion(version *domain.ProblemVersion) error
+	GetVersion(problemID uint, version uint) (domain.ProblemVersion, error)
+	GetVersionsWithoutTestcases() ([]domain.ProblemVersion, error)
+	SetVersionTestcases(version *domain.ProblemVersion, testcases []domain.ProblemTestcase) error
+	UpdateValidationByReference(submissionID uint, status domain.ValidationStatus) error
 }
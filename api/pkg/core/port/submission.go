@@ -1,9 +1,23 @@
 package port
 
-import "github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+import (
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+)
 
 type SubmissionRepository interface {
-	Create(s *domain.Submission) error
+	Create(s *domain.Submission, job *domain.GradingJob) error
 	GetSubmissionsByID(id uint) (domain.Submission, error)
 	GetSubmissionsByUserIDAndProblemID(email string, pid uint, limit int, page int) ([]domain.Submission, int, int, error)
+	UpdateStatus(id uint, status domain.SubmissionStatus) error
+	UpdateResult(id uint, status domain.SubmissionStatus, verdict domain.Verdict) error
+	ReplaceDiagnostics(id uint, diagnostics []domain.Diagnostic) error
+	UpdateScore(id uint, score float64, maxScore float64) error
+	GetBestScores(email string, problemIDs []uint) (map[uint]float64, error)
+	GetIDsByProblemID(problemID uint) ([]uint, error)
+	ResetForRegrade(id uint, problemVersionID uint, definitions []domain.ProblemTestcase) (bool, error)
+	GetByProblemIDs(problemIDs []uint) ([]domain.Submission, error)
+	GetBetween(problemIDs []uint, from time.Time, to time.Time) ([]domain.Submission, error)
+	Search(filter domain.SubmissionFilter, limit int) ([]domain.Submission, error)
 }
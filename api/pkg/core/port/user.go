@@ -0,0 +1,13 @@
+package port
+
+import "github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+
+type UserRepository interface {
+	Upsert(user *domain.User) error
+	FindOrCreateByEmail(email string) (domain.User, error)
+	GetByEmail(email string) (domain.User, error)
+	GetUsers(limit int, page int) ([]domain.User, int, int, error)
+	GrantRole(userID uint, role domain.Role, grantedBy string) error
+	RevokeRole(userID uint, role domain.Role) (int64, error)
+	CountByRole(role domain.Role) (int64, error)
+}
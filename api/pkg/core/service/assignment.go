@@ -0,0 +1,365 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/port"
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+	"gorm.io/gorm"
+)
+
+type AssignmentService struct {
+	assignmentRepo port.AssignmentRepository
+	courseRepo     port.CourseRepository
+	problemRepo    port.ProblemRepository
+	submissionRepo port.SubmissionRepository
+	userRepo       port.UserRepository
+}
+
+func NewAssignmentService(assignmentRepo port.AssignmentRepository, courseRepo port.CourseRepository, problemRepo port.ProblemRepository, submissionRepo port.SubmissionRepository, userRepo port.UserRepository) *AssignmentService {
+	return &AssignmentService{
+		assignmentRepo: assignmentRepo,
+		courseRepo:     courseRepo,
+		problemRepo:    problemRepo,
+		submissionRepo: submissionRepo,
+		userRepo:       userRepo,
+	}
+}
+
+func (s *AssignmentService) Create(by domain.User, body dto.AssignmentRequest) (domain.Assignment, error) {
+	if _, err := s.courseRepo.GetByID(body.CourseID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Assignment{}, apperror.NotFoundError(err, "course not found")
+		}
+		return domain.Assignment{}, apperror.InternalServerError(err, "get course error")
+	}
+	if !canManageCourse(s.courseRepo, by, body.CourseID) {
+		return domain.Assignment{}, apperror.ForbiddenError(errors.New("not course instructor"), "only course instructors can do this")
+	}
+
+	assignment := domain.Assignment{CourseID: body.CourseID}
+	if err := s.apply(&assignment, body); err != nil {
+		return assignment, err
+	}
+	if err := s.assignmentRepo.Create(&assignment); err != nil {
+		return assignment, apperror.InternalServerError(err, "create assignment error")
+	}
+	return assignment, nil
+}
+
+// Get returns the assignment to members of its course. Students only see their own extension.
+func (s *AssignmentService) Get(user domain.User, id uint) (domain.Assignment, error) {
+	assignment, err := s.assignment(id)
+	if err != nil {
+		return assignment, err
+	}
+	staff, err := s.viewable(user, assignment.CourseID)
+	if err != nil {
+		return assignment, err
+	}
+	if !staff {
+		if ext := assignment.ExtensionFor(user.ID); ext != nil {
+			assignment.Extensions = []domain.AssignmentExtension{*ext}
+		} else {
+			assignment.Extensions = []domain.AssignmentExtension{}
+		}
+	}
+	return assignment, nil
+}
+
+func (s *AssignmentService) GetByCourse(user domain.User, courseID uint) ([]domain.Assignment, error) {
+	if _, err := s.viewable(user, courseID); err != nil {
+		return nil, err
+	}
+	assignments, err := s.assignmentRepo.GetByCourseID(courseID)
+	if err != nil {
+		return nil, apperror.InternalServerError(err, "get assignments error")
+	}
+	return assignments, nil
+}
+
+// Update replaces the deadlines, late policy and problems. The course can't be changed.
+func (s *AssignmentService) Update(by domain.User, id uint, body dto.AssignmentRequest) (domain.Assignment, error) {
+	assignment, err := s.manageableAssignment(by, id)
+	if err != nil {
+		return assignment, err
+	}
+	if err := s.apply(&assignment, body); err != nil {
+		return assignment, err
+	}
+	if err := s.assignmentRepo.Update(&assignment); err != nil {
+		return assignment, apperror.InternalServerError(err, "update assignment error")
+	}
+	return assignment, nil
+}
+
+func (s *AssignmentService) Delete(by domain.User, id uint) error {
+	if _, err := s.manageableAssignment(by, id); err != nil {
+		return err
+	}
+	if err := s.assignmentRepo.Delete(id); err != nil {
+		return apperror.InternalServerError(err, "delete assignment error")
+	}
+	return nil
+}
+
+// SetExtension grants the student with the email their own deadlines, or changes the ones they have.
+func (s *AssignmentService) SetExtension(by domain.User, id uint, email string, body dto.AssignmentExtensionRequest) (domain.AssignmentExtension, error) {
+	assignment, err := s.manageableAssignment(by, id)
+	if err != nil {
+		return domain.AssignmentExtension{}, err
+	}
+	if body.DueAt.IsZero() || body.CloseAt.IsZero() {
+		return domain.AssignmentExtension{}, apperror.BadRequestError(errors.New("missing deadline"), "due_at and close_at are required")
+	}
+	if body.CloseAt.Before(body.DueAt) {
+		return domain.AssignmentExtension{}, apperror.BadRequestError(errors.New("close before due"), "close_at can't be before due_at")
+	}
+	user, err := s.userRepo.FindOrCreateByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return domain.AssignmentExtension{}, apperror.InternalServerError(err, "get user error")
+	}
+
+	ext := domain.AssignmentExtension{
+		AssignmentID: assignment.ID,
+		UserID:       user.ID,
+		DueAt:        body.DueAt,
+		CloseAt:      body.CloseAt,
+		GrantedBy:    by.Email,
+	}
+	if err := s.assignmentRepo.UpsertExtension(&ext); err != nil {
+		return ext, apperror.InternalServerError(err, "set extension error")
+	}
+	ext.User = user
+	return ext, nil
+}
+
+func (s *AssignmentService) RemoveExtension(by domain.User, id uint, email string) error {
+	if _, err := s.manageableAssignment(by, id); err != nil {
+		return err
+	}
+	user, err := userByEmail(s.userRepo, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return err
+	}
+	removed, err := s.assignmentRepo.DeleteExtension(id, user.ID)
+	if err != nil {
+		return apperror.InternalServerError(err, "remove extension error")
+	}
+	if removed == 0 {
+		return apperror.NotFoundError(errors.New("no extension"), "user has no extension for this assignment")
+	}
+	return nil
+}
+
+// Scores computes the final score of every student of the course,
+// taking the best submission per problem after the late penalty. Students only get their own row.
+func (s *AssignmentService) Scores(user domain.User, id uint) (dto.AssignmentScoresResponse, error) {
+	res := dto.AssignmentScoresResponse{AssignmentID: id, ProblemIDs: []uint{}, Students: []dto.AssignmentStudentScore{}}
+	assignment, err := s.assignment(id)
+	if err != nil {
+		return res, err
+	}
+	staff, err := s.viewable(user, assignment.CourseID)
+	if err != nil {
+		return res, err
+	}
+	for _, p := range assignment.Problems {
+		res.ProblemIDs = append(res.ProblemIDs, p.ID)
+	}
+	if res.Students, err = s.studentScores(&assignment, staff, user); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// studentScores computes the rows of Scores. Staff get every student of the course, others only their own row.
+// Only submissions made while the assignment took them from the student count, e.g. not earlier ones to a
+// problem that is also used elsewhere.
+func (s *AssignmentService) studentScores(assignment *domain.Assignment, staff bool, user domain.User) ([]dto.AssignmentStudentScore, error) {
+	rows := []dto.AssignmentStudentScore{}
+	problemIDs := make([]uint, len(assignment.Problems))
+	for i, p := range assignment.Problems {
+		problemIDs[i] = p.ID
+	}
+	if len(problemIDs) == 0 {
+		return rows, nil
+	}
+	submissions, err := s.submissionRepo.GetByProblemIDs(problemIDs)
+	if err != nil {
+		return nil, apperror.InternalServerError(err, "get submissions error")
+	}
+
+	// Submissions are grouped by email, then by problem
+	byStudent := make(map[string]map[uint][]domain.Submission)
+	names := make(map[string]string)
+	var emails []string
+	add := func(email string, name string) {
+		byStudent[email] = make(map[uint][]domain.Submission)
+		names[email] = name
+		emails = append(emails, email)
+	}
+	if staff {
+		members, err := s.courseRepo.GetMembers(assignment.CourseID)
+		if err != nil {
+			return nil, apperror.InternalServerError(err, "get members error")
+		}
+		for _, m := range members {
+			if m.Role == domain.RoleStudent {
+				add(m.User.Email, m.User.Name)
+			}
+		}
+	} else {
+		add(user.Email, user.Name)
+	}
+
+	extensions := make(map[string]*domain.AssignmentExtension)
+	for i := range assignment.Extensions {
+		extensions[assignment.Extensions[i].User.Email] = &assignment.Extensions[i]
+	}
+
+	for _, sub := range submissions {
+		problems, ok := byStudent[sub.SubmissionBy]
+		if !ok || !assignment.AcceptsAt(sub.CreatedAt, extensions[sub.SubmissionBy]) {
+			continue
+		}
+		problems[sub.ProblemID] = append(problems[sub.ProblemID], sub)
+	}
+
+	for _, email := range emails {
+		row := dto.AssignmentStudentScore{Email: email, Name: names[email], Problems: make([]dto.AssignmentProblemScore, 0, len(problemIDs))}
+		ext := extensions[email]
+		dueAt, _ := assignment.Deadlines(ext)
+		for _, problemID := range problemIDs {
+			attempts := byStudent[email][problemID]
+			score := dto.AssignmentProblemScore{ProblemID: problemID, Submissions: len(attempts)}
+			final, from := assignment.FinalScore(attempts, ext)
+			if from != nil {
+				score.Score = final
+				score.RawScore = from.Score
+				score.MaxScore = from.MaxScore
+				score.SubmissionID = &from.ID
+				score.Late = from.CreatedAt.After(dueAt)
+			}
+			row.Total += score.Score
+			row.Problems = append(row.Problems, score)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// apply validates the request and copies it onto the assignment.
+func (s *AssignmentService) apply(assignment *domain.Assignment, body dto.AssignmentRequest) error {
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		return apperror.BadRequestError(errors.New("empty name"), "name is required")
+	}
+	if body.OpenAt.IsZero() || body.DueAt.IsZero() || body.CloseAt.IsZero() {
+		return apperror.BadRequestError(errors.New("missing deadline"), "open_at, due_at and close_at are required")
+	}
+	if !body.OpenAt.Before(body.DueAt) || body.CloseAt.Before(body.DueAt) {
+		return apperror.BadRequestError(errors.New("deadlines out of order"), "open_at must be before due_at, and close_at can't be before due_at")
+	}
+	policy := domain.LatePolicy(strings.ToUpper(body.LatePolicy))
+	if policy == "" {
+		policy = domain.LatePolicyNone
+	}
+	if !policy.IsValid() {
+		return apperror.BadRequestError(errors.New("invalid late policy"), "late_policy must be NONE, LINEAR or PER_DAY")
+	}
+	if body.LatePenalty < 0 || body.LatePenalty > 1 {
+		return apperror.BadRequestError(errors.New("invalid late penalty"), "late_penalty must be between 0 and 1")
+	}
+
+	problems := make([]domain.Problem, 0, len(body.ProblemIDs))
+	for _, problemID := range body.ProblemIDs {
+		if slices.ContainsFunc(problems, func(p domain.Problem) bool { return p.ID == problemID }) {
+			continue
+		}
+		problem, err := s.problemRepo.GetProblemByID(problemID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.NotFoundError(err, "problem not found")
+			}
+			return apperror.InternalServerError(err, "get problem error")
+		}
+		// Global problems can be used by any course
+		if problem.CourseID != nil && *problem.CourseID != assignment.CourseID {
+			return apperror.BadRequestError(errors.New("problem of another course"), "problem belongs to another course")
+		}
+		problems = append(problems, domain.Problem{Model: gorm.Model{ID: problem.ID}, Name: problem.Name})
+	}
+
+	assignment.Name = name
+	assignment.OpenAt = body.OpenAt
+	assignment.DueAt = body.DueAt
+	assignment.CloseAt = body.CloseAt
+	assignment.LatePolicy = policy
+	assignment.LatePenalty = body.LatePenalty
+	assignment.Problems = problems
+	return nil
+}
+
+func (s *AssignmentService) assignment(id uint) (domain.Assignment, error) {
+	assignment, err := s.assignmentRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return assignment, apperror.NotFoundError(err, "assignment not found")
+		}
+		return assignment, apperror.InternalServerError(err, "get assignment error")
+	}
+	return assignment, nil
+}
+
+func (s *AssignmentService) manageableAssignment(by domain.User, id uint) (domain.Assignment, error) {
+	assignment, err := s.assignment(id)
+	if err != nil {
+		return assignment, err
+	}
+	if !canManageCourse(s.courseRepo, by, assignment.CourseID) {
+		return assignment, apperror.ForbiddenError(errors.New("not course instructor"), "only course instructors can do this")
+	}
+	return assignment, nil
+}
+
+// viewable checks the user may see the assignments of the course and reports whether they see them as staff.
+func (s *AssignmentService) viewable(user domain.User, courseID uint) (bool, error) {
+	if user.Can(domain.PermissionManageCourses) || user.Can(domain.PermissionViewAllSubmission) {
+		return true, nil
+	}
+	enrollment, err := enrollmentOf(s.courseRepo, user)
+	if err != nil {
+		return false, err
+	}
+	if !enrollment.IsMember(&courseID) {
+		return false, apperror.NotFoundError(errors.New("not a member"), "course not found")
+	}
+	return enrollment.IsStaff(&courseID), nil
+}
+
+// assignmentsOpen reports whether the user may still submit to the problem under the assignments it is part of.
+// Only assignments of courses the user studies in count, and one open assignment is enough.
+func assignmentsOpen(assignmentRepo port.AssignmentRepository, enrollment domain.Enrollment, user domain.User, problemID uint, now time.Time) (bool, error) {
+	assignments, err := assignmentRepo.GetByProblemID(problemID)
+	if err != nil {
+		return false, apperror.InternalServerError(err, "get assignments error")
+	}
+	restricted := false
+	for i := range assignments {
+		a := &assignments[i]
+		if !enrollment.IsMember(&a.CourseID) || enrollment.IsStaff(&a.CourseID) {
+			continue
+		}
+		if a.AcceptsAt(now, a.ExtensionFor(user.ID)) {
+			return true, nil
+		}
+		restricted = true
+	}
+	return !restricted, nil
+}
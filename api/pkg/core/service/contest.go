@@ -0,0 +1,363 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/port"
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+	"gorm.io/gorm"
+)
+
+// ScoreboardCacheTTL is how long a computed scoreboard is served before it is built again.
+// A busy contest polls the scoreboard far more often than it gets submissions.
+const ScoreboardCacheTTL = 10 * time.Second
+
+const defaultPenaltyMinutes = 20
+
+type scoreboardKey struct {
+	contestID uint
+	// staff see results frozen for participants
+	staff bool
+}
+
+type cachedScoreboard struct {
+	board     dto.ScoreboardResponse
+	expiresAt time.Time
+}
+
+type ContestService struct {
+	contestRepo    port.ContestRepository
+	courseRepo     port.CourseRepository
+	problemRepo    port.ProblemRepository
+	submissionRepo port.SubmissionRepository
+	userRepo       port.UserRepository
+
+	mu          sync.Mutex
+	scoreboards map[scoreboardKey]cachedScoreboard
+}
+
+func NewContestService(contestRepo port.ContestRepository, courseRepo port.CourseRepository, problemRepo port.ProblemRepository, submissionRepo port.SubmissionRepository, userRepo port.UserRepository) *ContestService {
+	return &ContestService{
+		contestRepo:    contestRepo,
+		courseRepo:     courseRepo,
+		problemRepo:    problemRepo,
+		submissionRepo: submissionRepo,
+		userRepo:       userRepo,
+		scoreboards:    make(map[scoreboardKey]cachedScoreboard),
+	}
+}
+
+func (s *ContestService) Create(by domain.User, body dto.ContestRequest) (domain.Contest, error) {
+	contest := domain.Contest{CourseID: body.CourseID, CreatedBy: by.Email}
+	if body.CourseID != nil {
+		if _, err := s.courseRepo.GetByID(*body.CourseID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return contest, apperror.NotFoundError(err, "course not found")
+			}
+			return contest, apperror.InternalServerError(err, "get course error")
+		}
+	}
+	if !s.canManage(by, &contest) {
+		return contest, apperror.ForbiddenError(errors.New("can't manage contest"), "only course instructors can do this")
+	}
+	if err := s.apply(&contest, body); err != nil {
+		return contest, err
+	}
+	if err := s.contestRepo.Create(&contest); err != nil {
+		return contest, apperror.InternalServerError(err, "create contest error")
+	}
+	return contest, nil
+}
+
+// GetContests lists the global contests and those of the courses the user belongs to.
+func (s *ContestService) GetContests(user domain.User) ([]domain.Contest, error) {
+	if user.Can(domain.PermissionManageCourses) {
+		contests, err := s.contestRepo.GetAll()
+		if err != nil {
+			return nil, apperror.InternalServerError(err, "get contests error")
+		}
+		return contests, nil
+	}
+	enrollment, err := enrollmentOf(s.courseRepo, user)
+	if err != nil {
+		return nil, err
+	}
+	contests, err := s.contestRepo.GetContests(enrollment.CourseIDs)
+	if err != nil {
+		return nil, apperror.InternalServerError(err, "get contests error")
+	}
+	return contests, nil
+}
+
+// Get returns the contest. Its problems stay hidden from participants until it starts.
+func (s *ContestService) Get(user domain.User, id uint) (domain.Contest, error) {
+	contest, staff, err := s.viewableContest(user, id)
+	if err != nil {
+		return contest, err
+	}
+	if !staff && time.Now().Before(contest.StartAt) {
+		contest.Problems = []domain.Problem{}
+	}
+	return contest, nil
+}
+
+// Update replaces the times, style and problems. The course can't be changed.
+func (s *ContestService) Update(by domain.User, id uint, body dto.ContestRequest) (domain.Contest, error) {
+	contest, err := s.manageableContest(by, id)
+	if err != nil {
+		return contest, err
+	}
+	if err := s.apply(&contest, body); err != nil {
+		return contest, err
+	}
+	if err := s.contestRepo.Update(&contest); err != nil {
+		return contest, apperror.InternalServerError(err, "update contest error")
+	}
+	s.invalidate(id)
+	return contest, nil
+}
+
+func (s *ContestService) Delete(by domain.User, id uint) error {
+	if _, err := s.manageableContest(by, id); err != nil {
+		return err
+	}
+	if err := s.contestRepo.Delete(id); err != nil {
+		return apperror.InternalServerError(err, "delete contest error")
+	}
+	s.invalidate(id)
+	return nil
+}
+
+// Register signs the user up for a contest that hasn't ended yet.
+func (s *ContestService) Register(user domain.User, id uint) error {
+	contest, _, err := s.viewableContest(user, id)
+	if err != nil {
+		return err
+	}
+	if !time.Now().Before(contest.EndAt) {
+		return apperror.ForbiddenError(errors.New("contest is over"), "contest is over")
+	}
+	if _, err := s.contestRepo.AddParticipant(&domain.ContestParticipant{ContestID: id, UserID: user.ID}); err != nil {
+		return apperror.InternalServerError(err, "register error")
+	}
+	s.invalidate(id)
+	return nil
+}
+
+func (s *ContestService) AddParticipant(by domain.User, id uint, email string) (domain.ContestParticipant, error) {
+	if _, err := s.manageableContest(by, id); err != nil {
+		return domain.ContestParticipant{}, err
+	}
+	user, err := s.userRepo.FindOrCreateByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return domain.ContestParticipant{}, apperror.InternalServerError(err, "get user error")
+	}
+	participant := domain.ContestParticipant{ContestID: id, UserID: user.ID}
+	if _, err := s.contestRepo.AddParticipant(&participant); err != nil {
+		return participant, apperror.InternalServerError(err, "add participant error")
+	}
+	s.invalidate(id)
+	participant.User = user
+	return participant, nil
+}
+
+func (s *ContestService) RemoveParticipant(by domain.User, id uint, email string) error {
+	if _, err := s.manageableContest(by, id); err != nil {
+		return err
+	}
+	user, err := userByEmail(s.userRepo, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return err
+	}
+	removed, err := s.contestRepo.RemoveParticipant(id, user.ID)
+	if err != nil {
+		return apperror.InternalServerError(err, "remove participant error")
+	}
+	if removed == 0 {
+		return apperror.NotFoundError(errors.New("not a participant"), "user isn't registered for this contest")
+	}
+	s.invalidate(id)
+	return nil
+}
+
+// Scoreboard returns the ranking, built at most once per ScoreboardCacheTTL for staff and once for everyone else.
+// Participants see the frozen scoreboard in the last minutes of the contest, staff always see the live one.
+func (s *ContestService) Scoreboard(user domain.User, id uint) (dto.ScoreboardResponse, error) {
+	contest, staff, err := s.viewableContest(user, id)
+	if err != nil {
+		return dto.ScoreboardResponse{}, err
+	}
+	now := time.Now()
+	key := scoreboardKey{contestID: id, staff: staff}
+
+	s.mu.Lock()
+	cached, ok := s.scoreboards[key]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.board, nil
+	}
+
+	var submissions []domain.Submission
+	if len(contest.Problems) > 0 && now.After(contest.StartAt) {
+		problemIDs := make([]uint, len(contest.Problems))
+		for i, p := range contest.Problems {
+			problemIDs[i] = p.ID
+		}
+		if submissions, err = s.submissionRepo.GetBetween(problemIDs, contest.StartAt, contest.EndAt); err != nil {
+			return dto.ScoreboardResponse{}, apperror.InternalServerError(err, "get submissions error")
+		}
+	}
+	board := contest.Scoreboard(submissions, !staff && contest.Frozen(now), now)
+
+	s.mu.Lock()
+	s.scoreboards[key] = cachedScoreboard{board: board, expiresAt: now.Add(ScoreboardCacheTTL)}
+	s.mu.Unlock()
+	return board, nil
+}
+
+// apply validates the request and copies it onto the contest.
+func (s *ContestService) apply(contest *domain.Contest, body dto.ContestRequest) error {
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		return apperror.BadRequestError(errors.New("empty name"), "name is required")
+	}
+	style := domain.ContestStyle(strings.ToUpper(body.Style))
+	if style == "" {
+		style = domain.ContestICPC
+	}
+	if !style.IsValid() {
+		return apperror.BadRequestError(errors.New("invalid style"), "style must be ICPC or IOI")
+	}
+	if body.StartAt.IsZero() || body.EndAt.IsZero() || !body.StartAt.Before(body.EndAt) {
+		return apperror.BadRequestError(errors.New("invalid times"), "start_at and end_at are required and start_at must be before end_at")
+	}
+	if body.FreezeMinutes < 0 || time.Duration(body.FreezeMinutes)*time.Minute > body.EndAt.Sub(body.StartAt) {
+		return apperror.BadRequestError(errors.New("invalid freeze"), "freeze_minutes must fit in the contest")
+	}
+	penalty := defaultPenaltyMinutes
+	if body.PenaltyMinutes != nil {
+		penalty = *body.PenaltyMinutes
+	}
+	if penalty < 0 {
+		return apperror.BadRequestError(errors.New("invalid penalty"), "penalty_minutes can't be negative")
+	}
+
+	problems := make([]domain.Problem, 0, len(body.ProblemIDs))
+	for _, problemID := range body.ProblemIDs {
+		if slices.ContainsFunc(problems, func(p domain.Problem) bool { return p.ID == problemID }) {
+			continue
+		}
+		problem, err := s.problemRepo.GetProblemByID(problemID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.NotFoundError(err, "problem not found")
+			}
+			return apperror.InternalServerError(err, "get problem error")
+		}
+		// Global problems can be used by any contest
+		if problem.CourseID != nil && (contest.CourseID == nil || *problem.CourseID != *contest.CourseID) {
+			return apperror.BadRequestError(errors.New("problem of another course"), "problem belongs to another course")
+		}
+		problems = append(problems, domain.Problem{Model: gorm.Model{ID: problem.ID}, Name: problem.Name})
+	}
+
+	contest.Name = name
+	contest.Style = style
+	contest.StartAt = body.StartAt
+	contest.EndAt = body.EndAt
+	contest.FreezeMinutes = body.FreezeMinutes
+	contest.PenaltyMinutes = penalty
+	contest.Problems = problems
+	return nil
+}
+
+func (s *ContestService) contest(id uint) (domain.Contest, error) {
+	contest, err := s.contestRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return contest, apperror.NotFoundError(err, "contest not found")
+		}
+		return contest, apperror.InternalServerError(err, "get contest error")
+	}
+	return contest, nil
+}
+
+// canManage reports whether the user may change the contest: an instructor of its course,
+// or someone who manages every course for a global contest.
+func (s *ContestService) canManage(by domain.User, contest *domain.Contest) bool {
+	if contest.CourseID == nil {
+		return by.Can(domain.PermissionManageCourses)
+	}
+	return canManageCourse(s.courseRepo, by, *contest.CourseID)
+}
+
+func (s *ContestService) manageableContest(by domain.User, id uint) (domain.Contest, error) {
+	contest, err := s.contest(id)
+	if err != nil {
+		return contest, err
+	}
+	if !s.canManage(by, &contest) {
+		return contest, apperror.ForbiddenError(errors.New("can't manage contest"), "only course instructors can do this")
+	}
+	return contest, nil
+}
+
+// viewableContest returns the contest if the user may see it and reports whether they see it as staff.
+func (s *ContestService) viewableContest(user domain.User, id uint) (domain.Contest, bool, error) {
+	contest, err := s.contest(id)
+	if err != nil {
+		return contest, false, err
+	}
+	if user.Can(domain.PermissionManageCourses) || user.Can(domain.PermissionViewAllSubmission) {
+		return contest, true, nil
+	}
+	enrollment, err := enrollmentOf(s.courseRepo, user)
+	if err != nil {
+		return contest, false, err
+	}
+	if !enrollment.IsMember(contest.CourseID) {
+		return contest, false, apperror.NotFoundError(errors.New("not a member"), "contest not found")
+	}
+	return contest, enrollment.IsStaff(contest.CourseID), nil
+}
+
+func (s *ContestService) invalidate(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.scoreboards, scoreboardKey{contestID: id, staff: true})
+	delete(s.scoreboards, scoreboardKey{contestID: id, staff: false})
+}
+
+// contestsAllow checks a submission against the contests the problem is part of. Their problems stay hidden
+// until they start, and while one runs only its participants may submit. Contests of courses the user
+// teaches don't apply.
+func contestsAllow(contestRepo port.ContestRepository, enrollment domain.Enrollment, user domain.User, problemID uint, now time.Time) error {
+	contests, err := contestRepo.GetByProblemID(problemID)
+	if err != nil {
+		return apperror.InternalServerError(err, "get contests error")
+	}
+	running, registered := false, false
+	for i := range contests {
+		c := &contests[i]
+		if enrollment.IsStaff(c.CourseID) {
+			continue
+		}
+		if now.Before(c.StartAt) {
+			return apperror.NotFoundError(errors.New("contest hasn't started"), "problem not found")
+		}
+		if c.Running(now) {
+			running = true
+			registered = registered || c.IsParticipant(user.ID)
+		}
+	}
+	if running && !registered {
+		return apperror.ForbiddenError(errors.New("not a contest participant"), "register for the contest to submit")
+	}
+	return nil
+}
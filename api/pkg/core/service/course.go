@@ -0,0 +1,265 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/port"
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+	"gorm.io/gorm"
+)
+
+// maxRosterRows keeps a roster import to a size one request can handle.
+const maxRosterRows = 5000
+
+type CourseService struct {
+	courseRepo port.CourseRepository
+	userRepo   port.UserRepository
+}
+
+func NewCourseService(courseRepo port.CourseRepository, userRepo port.UserRepository) *CourseService {
+	return &CourseService{courseRepo: courseRepo, userRepo: userRepo}
+}
+
+// Create makes a course with its creator as the first instructor.
+func (s *CourseService) Create(by domain.User, body dto.CourseRequest) (domain.Course, error) {
+	code := strings.TrimSpace(body.Code)
+	name := strings.TrimSpace(body.Name)
+	if code == "" || name == "" {
+		return domain.Course{}, apperror.BadRequestError(errors.New("empty code or name"), "code and name are required")
+	}
+	invite, err := newInviteCode()
+	if err != nil {
+		return domain.Course{}, apperror.InternalServerError(err, "generate invite code error")
+	}
+
+	course := domain.Course{
+		Code:       code,
+		Name:       name,
+		InviteCode: invite,
+		Members:    []domain.CourseMember{{UserID: by.ID, Role: domain.RoleInstructor}},
+	}
+	if err := s.courseRepo.Create(&course); err != nil {
+		return course, apperror.InternalServerError(err, "create course error")
+	}
+	return course, nil
+}
+
+// GetCourses lists the courses of the user. Invite codes are only shown to course staff.
+func (s *CourseService) GetCourses(user domain.User) ([]domain.Course, error) {
+	courses, err := s.courseRepo.GetByUserID(user.ID)
+	if err != nil {
+		return nil, apperror.InternalServerError(err, "get courses error")
+	}
+	enrollment, err := enrollmentOf(s.courseRepo, user)
+	if err != nil {
+		return nil, err
+	}
+	for i := range courses {
+		if !user.Can(domain.PermissionManageCourses) && !enrollment.IsStaff(&courses[i].ID) {
+			courses[i].InviteCode = ""
+		}
+	}
+	return courses, nil
+}
+
+// Join enrolls the user as a student of the course with the invite code.
+// Someone who already is a member keeps their role.
+func (s *CourseService) Join(user domain.User, inviteCode string) (domain.Course, error) {
+	course, err := s.courseRepo.GetByInviteCode(strings.ToUpper(strings.TrimSpace(inviteCode)))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return course, apperror.NotFoundError(err, "invalid invite code")
+		}
+		return course, apperror.InternalServerError(err, "get course error")
+	}
+	if _, err := s.courseRepo.AddMember(&domain.CourseMember{CourseID: course.ID, UserID: user.ID, Role: domain.RoleStudent}); err != nil {
+		return course, apperror.InternalServerError(err, "join course error")
+	}
+	course.InviteCode = ""
+	return course, nil
+}
+
+// RotateInviteCode replaces the invite code, e.g. after it leaked. Members stay enrolled.
+func (s *CourseService) RotateInviteCode(by domain.User, courseID uint) (domain.Course, error) {
+	course, err := s.manageableCourse(by, courseID)
+	if err != nil {
+		return course, err
+	}
+	if course.InviteCode, err = newInviteCode(); err != nil {
+		return course, apperror.InternalServerError(err, "generate invite code error")
+	}
+	if err := s.courseRepo.UpdateInviteCode(course.ID, course.InviteCode); err != nil {
+		return course, apperror.InternalServerError(err, "update invite code error")
+	}
+	return course, nil
+}
+
+// GetMembers lists the members of a course to its staff.
+func (s *CourseService) GetMembers(by domain.User, courseID uint) ([]domain.CourseMember, error) {
+	if _, err := s.course(courseID); err != nil {
+		return nil, err
+	}
+	if !by.Can(domain.PermissionManageCourses) {
+		member, err := s.courseRepo.GetMembership(courseID, by.ID)
+		if err != nil || !member.Role.IsCourseStaff() {
+			return nil, apperror.ForbiddenError(errors.New("not course staff"), "only course staff can see the members")
+		}
+	}
+	members, err := s.courseRepo.GetMembers(courseID)
+	if err != nil {
+		return nil, apperror.InternalServerError(err, "get members error")
+	}
+	return members, nil
+}
+
+// SetMember enrolls the user with the email or changes their role in the course.
+func (s *CourseService) SetMember(by domain.User, courseID uint, email string, role domain.Role) (domain.CourseMember, error) {
+	if _, err := s.manageableCourse(by, courseID); err != nil {
+		return domain.CourseMember{}, err
+	}
+	if !role.IsCourseRole() {
+		return domain.CourseMember{}, apperror.BadRequestError(errors.New("not a course role"), "invalid course role")
+	}
+	user, err := s.userRepo.FindOrCreateByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return domain.CourseMember{}, apperror.InternalServerError(err, "get user error")
+	}
+	member := domain.CourseMember{CourseID: courseID, UserID: user.ID, Role: role}
+	if err := s.courseRepo.UpsertMember(&member); err != nil {
+		return member, apperror.InternalServerError(err, "set member error")
+	}
+	member.User = user
+	return member, nil
+}
+
+func (s *CourseService) RemoveMember(by domain.User, courseID uint, email string) error {
+	if _, err := s.manageableCourse(by, courseID); err != nil {
+		return err
+	}
+	user, err := userByEmail(s.userRepo, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return err
+	}
+	removed, err := s.courseRepo.RemoveMember(courseID, user.ID)
+	if err != nil {
+		return apperror.InternalServerError(err, "remove member error")
+	}
+	if removed == 0 {
+		return apperror.NotFoundError(errors.New("not a member"), "user isn't a member of this course")
+	}
+	return nil
+}
+
+// ImportRoster enrolls every row of a CSV roster. Each row is an email, optionally followed by a role;
+// a header row is skipped. Rows that can't be imported are reported without stopping the import.
+func (s *CourseService) ImportRoster(by domain.User, courseID uint, roster io.Reader) (dto.RosterImportResponse, error) {
+	res := dto.RosterImportResponse{Errors: []dto.RosterImportError{}}
+	if _, err := s.manageableCourse(by, courseID); err != nil {
+		return res, err
+	}
+
+	reader := csv.NewReader(roster)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return res, apperror.BadRequestError(err, fmt.Sprintf("invalid CSV on line %d", line))
+		}
+		if line > maxRosterRows {
+			return res, apperror.BadRequestError(errors.New("roster too large"), fmt.Sprintf("a roster can have at most %d rows", maxRosterRows))
+		}
+
+		email := strings.ToLower(strings.TrimSpace(record[0]))
+		if line == 1 && email == "email" {
+			continue
+		}
+		if email == "" {
+			continue
+		}
+		if !strings.Contains(email, "@") {
+			res.Errors = append(res.Errors, dto.RosterImportError{Line: line, Error: fmt.Sprintf("'%s' is not an email address", email)})
+			continue
+		}
+		role := domain.RoleStudent
+		if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+			role = domain.Role(strings.ToUpper(strings.TrimSpace(record[1])))
+		}
+		if !role.IsCourseRole() {
+			res.Errors = append(res.Errors, dto.RosterImportError{Line: line, Error: fmt.Sprintf("'%s' is not a course role", role)})
+			continue
+		}
+
+		user, err := s.userRepo.FindOrCreateByEmail(email)
+		if err != nil {
+			return res, apperror.InternalServerError(err, "get user error")
+		}
+		if err := s.courseRepo.UpsertMember(&domain.CourseMember{CourseID: courseID, UserID: user.ID, Role: role}); err != nil {
+			return res, apperror.InternalServerError(err, "set member error")
+		}
+		res.Imported++
+	}
+	return res, nil
+}
+
+func (s *CourseService) course(courseID uint) (domain.Course, error) {
+	course, err := s.courseRepo.GetByID(courseID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return course, apperror.NotFoundError(err, "course not found")
+		}
+		return course, apperror.InternalServerError(err, "get course error")
+	}
+	return course, nil
+}
+
+// manageableCourse returns the course if the user may change its members: an instructor of the course
+// or someone who manages every course.
+func (s *CourseService) manageableCourse(by domain.User, courseID uint) (domain.Course, error) {
+	course, err := s.course(courseID)
+	if err != nil {
+		return course, err
+	}
+	if !canManageCourse(s.courseRepo, by, courseID) {
+		return course, apperror.ForbiddenError(errors.New("not course instructor"), "only course instructors can do this")
+	}
+	return course, nil
+}
+
+// canManageCourse reports whether the user is an instructor of the course or manages every course.
+func canManageCourse(courseRepo port.CourseRepository, by domain.User, courseID uint) bool {
+	if by.Can(domain.PermissionManageCourses) {
+		return true
+	}
+	member, err := courseRepo.GetMembership(courseID, by.ID)
+	return err == nil && member.Role == domain.RoleInstructor
+}
+
+// enrollmentOf looks up the courses a user belongs to.
+func enrollmentOf(courseRepo port.CourseRepository, user domain.User) (domain.Enrollment, error) {
+	memberships, err := courseRepo.GetMemberships(user.ID)
+	if err != nil {
+		return domain.Enrollment{}, apperror.InternalServerError(err, "get courses error")
+	}
+	return domain.NewEnrollment(memberships), nil
+}
+
+// newInviteCode returns a code that is easy to read out in class.
+func newInviteCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+	"github.com/yokeTH/our-grader-backend/api/pkg/gradebook"
+	"gorm.io/gorm"
+)
+
+var gradebookColumns = []string{"student_id", "email", "name", "score", "submissions", "late", "total"}
+
+// gradebookSection is the scores of one assignment in an export.
+type gradebookSection struct {
+	assignment *domain.Assignment
+	rows       []dto.AssignmentStudentScore
+}
+
+// ExportAssignment builds the gradebook of one assignment for course staff.
+// It returns the table, its format and a file name for it.
+func (s *AssignmentService) ExportAssignment(user domain.User, id uint, query dto.GradebookQuery) (gradebook.Table, gradebook.Format, string, error) {
+	format, columns, err := parseGradebookQuery(query)
+	if err != nil {
+		return gradebook.Table{}, format, "", err
+	}
+	assignment, err := s.assignment(id)
+	if err != nil {
+		return gradebook.Table{}, format, "", err
+	}
+	if err := s.requireStaff(user, assignment.CourseID); err != nil {
+		return gradebook.Table{}, format, "", err
+	}
+	rows, err := s.studentScores(&assignment, true, user)
+	if err != nil {
+		return gradebook.Table{}, format, "", err
+	}
+	table := buildGradebook([]gradebookSection{{assignment: &assignment, rows: rows}}, columns, false)
+	return table, format, fmt.Sprintf("assignment-%d-gradebook.%s", id, format), nil
+}
+
+// ExportCourse builds the gradebook of every assignment of the course for course staff.
+func (s *AssignmentService) ExportCourse(user domain.User, courseID uint, query dto.GradebookQuery) (gradebook.Table, gradebook.Format, string, error) {
+	format, columns, err := parseGradebookQuery(query)
+	if err != nil {
+		return gradebook.Table{}, format, "", err
+	}
+	course, err := s.courseRepo.GetByID(courseID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return gradebook.Table{}, format, "", apperror.NotFoundError(err, "course not found")
+		}
+		return gradebook.Table{}, format, "", apperror.InternalServerError(err, "get course error")
+	}
+	if err := s.requireStaff(user, courseID); err != nil {
+		return gradebook.Table{}, format, "", err
+	}
+	assignments, err := s.assignmentRepo.GetByCourseID(courseID)
+	if err != nil {
+		return gradebook.Table{}, format, "", apperror.InternalServerError(err, "get assignments error")
+	}
+
+	sections := make([]gradebookSection, 0, len(assignments))
+	for _, a := range assignments {
+		// The listing doesn't load extensions, which the scores depend on
+		assignment, err := s.assignment(a.ID)
+		if err != nil {
+			return gradebook.Table{}, format, "", err
+		}
+		rows, err := s.studentScores(&assignment, true, user)
+		if err != nil {
+			return gradebook.Table{}, format, "", err
+		}
+		sections = append(sections, gradebookSection{assignment: &assignment, rows: rows})
+	}
+	table := buildGradebook(sections, columns, true)
+	return table, format, fmt.Sprintf("%s-gradebook.%s", course.Code, format), nil
+}
+
+func (s *AssignmentService) requireStaff(user domain.User, courseID uint) error {
+	staff, err := s.viewable(user, courseID)
+	if err != nil {
+		return err
+	}
+	if !staff {
+		return apperror.ForbiddenError(errors.New("not course staff"), "only course staff can export the gradebook")
+	}
+	return nil
+}
+
+func parseGradebookQuery(query dto.GradebookQuery) (gradebook.Format, []string, error) {
+	format := gradebook.Format(strings.ToLower(query.Format))
+	if format == "" {
+		format = gradebook.FormatCSV
+	}
+	if !format.IsValid() {
+		return format, nil, apperror.BadRequestError(errors.New("invalid format"), "format must be csv or xlsx")
+	}
+	if strings.TrimSpace(query.Columns) == "" {
+		return format, gradebookColumns, nil
+	}
+	var columns []string
+	for _, c := range strings.Split(query.Columns, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if !slices.Contains(gradebookColumns, c) {
+			return format, nil, apperror.BadRequestError(errors.New("invalid column"), fmt.Sprintf("unknown column '%s', columns are %s", c, strings.Join(gradebookColumns, ", ")))
+		}
+		if !slices.Contains(columns, c) {
+			columns = append(columns, c)
+		}
+	}
+	return format, columns, nil
+}
+
+// buildGradebook lays the scores out with one row per student: the student columns in the order asked for,
+// then the score, submission count and late flag of each problem, then the total over every section.
+// Problem headers name the assignment when there is more than one section to tell them apart.
+func buildGradebook(sections []gradebookSection, columns []string, nameAssignments bool) gradebook.Table {
+	var table gradebook.Table
+	var studentColumns, problemColumns []string
+	for _, c := range columns {
+		switch c {
+		case "student_id", "email", "name":
+			studentColumns = append(studentColumns, c)
+		case "score", "submissions", "late":
+			problemColumns = append(problemColumns, c)
+		}
+	}
+	withTotal := slices.Contains(columns, "total")
+
+	headers := map[string]string{"student_id": "Student ID", "email": "Email", "name": "Name", "score": "Score", "submissions": "Submissions", "late": "Late"}
+	for _, c := range studentColumns {
+		table.Header = append(table.Header, headers[c])
+	}
+	for _, section := range sections {
+		for _, p := range section.assignment.Problems {
+			label := p.Name
+			if nameAssignments {
+				label = section.assignment.Name + " / " + p.Name
+			}
+			for _, c := range problemColumns {
+				table.Header = append(table.Header, label+" "+headers[c])
+			}
+		}
+	}
+	if withTotal {
+		table.Header = append(table.Header, "Total")
+	}
+
+	// A student may be missing from a section, e.g. after joining the course late, so rows are merged by email
+	type student struct {
+		name   string
+		scores map[int]dto.AssignmentStudentScore
+	}
+	students := make(map[string]*student)
+	var emails []string
+	for i, section := range sections {
+		for _, row := range section.rows {
+			st, ok := students[row.Email]
+			if !ok {
+				st = &student{scores: make(map[int]dto.AssignmentStudentScore)}
+				students[row.Email] = st
+				emails = append(emails, row.Email)
+			}
+			if row.Name != "" {
+				st.name = row.Name
+			}
+			st.scores[i] = row
+		}
+	}
+
+	for _, email := range emails {
+		st := students[email]
+		row := make([]any, 0, len(table.Header))
+		for _, c := range studentColumns {
+			switch c {
+			case "student_id":
+				id, _, _ := strings.Cut(email, "@")
+				row = append(row, id)
+			case "email":
+				row = append(row, email)
+			case "name":
+				row = append(row, st.name)
+			}
+		}
+		var total float64
+		for i, section := range sections {
+			scores := st.scores[i]
+			total += scores.Total
+			for j := range section.assignment.Problems {
+				var score dto.AssignmentProblemScore
+				if j < len(scores.Problems) {
+					score = scores.Problems[j]
+				}
+				for _, c := range problemColumns {
+					switch c {
+					case "score":
+						row = append(row, score.Score)
+					case "submissions":
+						row = append(row, score.Submissions)
+					case "late":
+						row = append(row, score.Late)
+					}
+				}
+			}
+		}
+		if withTotal {
+			row = append(row, total)
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
@@ -4,6 +4,8 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -11,27 +13,85 @@ import (
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/cocotb"
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/port"
 	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+	"github.com/yokeTH/our-grader-backend/api/pkg/manifest"
 	"github.com/yokeTH/our-grader-backend/api/pkg/storage"
+	"gorm.io/gorm"
 )
 
 type ProblemService struct {
-	ProblemRepository  port.ProblemRepository
-	TemplateRepository port.TemplateRepository
-	Storage            storage.IStorage
+	ProblemRepository    port.ProblemRepository
+	TemplateRepository   port.TemplateRepository
+	SubmissionRepository port.SubmissionRepository
+	GradingJobRepository port.GradingJobRepository
+	CourseRepository     port.CourseRepository
+	Storage              storage.IStorage
 }
 
-func NewProblemService(p port.ProblemRepository, t port.TemplateRepository, s storage.IStorage) port.ProblemService {
-	return &ProblemService{ProblemRepository: p, TemplateRepository: t, Storage: s}
+func NewProblemService(p port.ProblemRepository, t port.TemplateRepository, sub port.SubmissionRepository, job port.GradingJobRepository, c port.CourseRepository, s storage.IStorage) port.ProblemService {
+	return &ProblemService{ProblemRepository: p, TemplateRepository: t, SubmissionRepository: sub, GradingJobRepository: job, CourseRepository: c, Storage: s}
 }
 
-func (s *ProblemService) CreateProblem(ctx context.Context, problemBody dto.ProblemRequestFrom, zipFile *multipart.FileHeader) (domain.Problem, error) {
-	// Convert languages from the request body to the domain structure
+func (s *ProblemService) CreateProblem(ctx context.Context, by string, problemBody dto.ProblemRequestFrom, zipFile *multipart.FileHeader) (domain.Problem, error) {
+	scoringPolicy := domain.ScoringSum
+	if problemBody.ScoringPolicy != "" {
+		scoringPolicy = domain.ScoringPolicy(problemBody.ScoringPolicy)
+	}
+	if !scoringPolicy.IsValid() {
+		return domain.Problem{}, apperror.BadRequestError(errors.New("unknown scoring policy"), "invalid scoring policy")
+	}
+
+	courseID, err := s.courseIDOf(problemBody.CourseID)
+	if err != nil {
+		return domain.Problem{}, err
+	}
+
+	fileData, zipReader, err := openProjectZip(zipFile)
+	if err != nil {
+		return domain.Problem{}, err
+	}
+	defer fileData.Close()
+
+	testcases, err := discoverTestcases(zipReader)
+	if err != nil {
+		return domain.Problem{}, err
+	}
+
+	// A manifest in the zip takes the place of the editable files and languages of the form
+	m, err := loadManifest(zipReader)
+	if err != nil {
+		return domain.Problem{}, err
+	}
+	if m != nil {
+		if len(m.EditableFiles) > 0 {
+			problemBody.EditableFile = m.EditableFiles
+		}
+		if len(m.Languages) > 0 {
+			problemBody.Language = m.Languages
+		}
+		if err := applyManifestTestcases(testcases, m); err != nil {
+			return domain.Problem{}, err
+		}
+	}
+	if len(problemBody.Language) == 0 {
+		return domain.Problem{}, apperror.BadRequestError(errors.New("no language"), "at least one language is required")
+	}
+	if err := checkEditableFiles(zipReader, problemBody.EditableFile); err != nil {
+		return domain.Problem{}, err
+	}
+	if err := checkReferenceFiles(zipReader, m, problemBody.EditableFile); err != nil {
+		return domain.Problem{}, err
+	}
+
 	language := make([]domain.Language, len(problemBody.Language))
 	for i, v := range problemBody.Language {
 		language[i] = domain.Language{Name: v}
@@ -39,12 +99,17 @@ func (s *ProblemService) CreateProblem(ctx context.Context, problemBody dto.Prob
 
 	// Initialize the problem struct
 	problem := domain.Problem{
-		Name:           problemBody.Name,
-		Description:    problemBody.Description,
-		AllowLanguage:  language,
-		TestcaseNum:    problemBody.TestcaseNum,
-		EditableFile:   []domain.TemplateFile{}, // will be populated later
-		ProjectZipFile: "",                      // to be set after uploading
+		Name:            problemBody.Name,
+		Description:     problemBody.Description,
+		AllowLanguage:   language,
+		TestcaseNum:     uint(len(testcases)),
+		Testcases:       testcases,
+		EditableFile:    []domain.TemplateFile{}, // will be populated later
+		ProjectZipFile:  "",                      // to be set after uploading
+		HideTestDetails: problemBody.HideTestDetails,
+		ScoringPolicy:   scoringPolicy,
+		Visibility:      domain.VisibilityDraft,
+		CourseID:        courseID,
 	}
 
 	// Save the problem to the repository
@@ -52,86 +117,230 @@ func (s *ProblemService) CreateProblem(ctx context.Context, problemBody dto.Prob
 		return domain.Problem{}, apperror.InternalServerError(err, "create problem error")
 	}
 
-	// Open the zip file
-	fileData, err := zipFile.Open()
+	// The uploaded zip is the first version of the problem
+	version, err := s.uploadVersion(ctx, problem.ID, 1, by, fileData, zipFile.Size)
 	if err != nil {
-		return problem, apperror.InternalServerError(err, "can't open zip file")
+		return problem, err
+	}
+	version.Testcases = problem.Testcases
+	if err := s.ProblemRepository.CreateVersion(&version); err != nil {
+		return problem, apperror.InternalServerError(err, "create problem version error")
 	}
-	defer fileData.Close()
 
-	// Validate that the uploaded file is a ZIP file
-	contentType := zipFile.Header.Get("Content-Type")
-	if contentType != "application/zip" {
-		return problem, apperror.BadRequestError(nil, "Uploaded file is not a ZIP file")
+	editableFile, err := s.uploadTemplates(ctx, problem.ID, version.Version, zipReader, problemBody.EditableFile)
+	if err != nil {
+		return problem, err
+	}
+
+	// Set the editable files and project zip file in the problem struct
+	problem.EditableFile = editableFile
+	problem.ProjectZipFile = version.ZipFile
+	problem.CurrentVersion = version.Version
+
+	// Save Template to database
+	// Convert editableFile to a slice of pointers
+	editableFilePtrs := make([]*domain.TemplateFile, len(editableFile))
+	for i := range editableFile {
+		editableFilePtrs[i] = &editableFile[i]
+	}
+	if err := s.TemplateRepository.CreateMany(editableFilePtrs); err != nil {
+		return problem, err
 	}
 
-	// Generate a unique key for the project zip file
-	fileKey := fmt.Sprintf("problems/%d/zip.zip", problem.ID)
+	// Update the problem with editable files
+	problem, err = s.ProblemRepository.UpdateProblem(problem.ID, problem)
+	if err != nil {
+		return problem, apperror.InternalServerError(err, "can't update problem")
+	}
+
+	if m != nil && m.Reference != "" {
+		return s.validateWithReference(ctx, by, problem.ID, zipReader, m)
+	}
+
+	// Return the created/updated problem
+	return problem, nil
+}
+
+// courseIDOf checks the course a problem is put in. Nil or 0 means no course.
+func (s *ProblemService) courseIDOf(id *uint) (*uint, error) {
+	if id == nil || *id == 0 {
+		return nil, nil
+	}
+	if _, err := s.CourseRepository.GetByID(*id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.BadRequestError(err, "course not found")
+		}
+		return nil, apperror.InternalServerError(err, "get course error")
+	}
+	return id, nil
+}
+
+// uploadVersion stores a project zip as a new immutable version of the problem.
+// The caller saves the returned version once it is linked to its testcase definitions.
+func (s *ProblemService) uploadVersion(ctx context.Context, problemID uint, version uint, by string, zipData multipart.File, size int64) (domain.ProblemVersion, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(zipData, 0, size)); err != nil {
+		return domain.ProblemVersion{}, apperror.InternalServerError(err, "can't read zip file")
+	}
+
+	problemVersion := domain.ProblemVersion{
+		ProblemID: problemID,
+		Version:   version,
+		ZipFile:   fmt.Sprintf("problems/%d/versions/%d/zip.zip", problemID, version),
+		Checksum:  hex.EncodeToString(hash.Sum(nil)),
+		CreatedBy: by,
+	}
 
 	// Upload the zip file to storage (it is uploaded as is)
-	if err := s.Storage.UploadFile(ctx, fileKey, contentType, fileData); err != nil {
-		return problem, apperror.InternalServerError(err, "upload zip file error")
+	if err := s.Storage.UploadFile(ctx, problemVersion.ZipFile, "application/zip", io.NewSectionReader(zipData, 0, size)); err != nil {
+		return problemVersion, apperror.InternalServerError(err, "upload zip file error")
+	}
+	return problemVersion, nil
+}
+
+// openProjectZip opens an uploaded project zip; the caller closes the returned file.
+func openProjectZip(zipFile *multipart.FileHeader) (multipart.File, *zip.Reader, error) {
+	// Validate that the uploaded file is a ZIP file
+	if zipFile.Header.Get("Content-Type") != "application/zip" {
+		return nil, nil, apperror.BadRequestError(nil, "Uploaded file is not a ZIP file")
+	}
+
+	// Open the zip file
+	fileData, err := zipFile.Open()
+	if err != nil {
+		return nil, nil, apperror.InternalServerError(err, "can't open zip file")
 	}
 
 	// Read the contents of the zip file
 	zipReader, err := zip.NewReader(fileData, zipFile.Size)
 	if err != nil {
-		return problem, apperror.InternalServerError(err, "failed to read zip file")
+		fileData.Close()
+		return nil, nil, apperror.BadRequestError(err, "failed to read zip file")
+	}
+	return fileData, zipReader, nil
+}
+
+// discoverTestcases builds the testcase definitions of a project.
+// Results are matched to these definitions, so the project must declare its tests.
+func discoverTestcases(zipReader *zip.Reader) ([]domain.ProblemTestcase, error) {
+	tests, err := cocotb.Discover(zipReader)
+	if err != nil {
+		return nil, apperror.BadRequestError(err, err.Error())
+	}
+	testcases := make([]domain.ProblemTestcase, len(tests))
+	for i, t := range tests {
+		testcases[i] = domain.ProblemTestcase{Classname: t.Classname, Name: t.Name, Key: t.Key(), Weight: 1}
+	}
+	return testcases, nil
+}
+
+// loadManifest reads and validates the manifest of a project zip. It returns nil when the zip has none.
+func loadManifest(zipReader *zip.Reader) (*manifest.Manifest, error) {
+	m, err := manifest.Load(zipReader)
+	if err != nil {
+		return nil, apperror.BadRequestError(err, err.Error())
+	}
+	if m == nil {
+		return nil, nil
+	}
+	if err := m.Validate(zipReader); err != nil {
+		return nil, apperror.BadRequestError(err, err.Error())
+	}
+	return m, nil
+}
+
+// applyManifestTestcases sets the weights and groups the manifest declares on the discovered testcases.
+// A testcase is named by its key, or by its function name when no other test shares it.
+func applyManifestTestcases(testcases []domain.ProblemTestcase, m *manifest.Manifest) error {
+	for _, declared := range m.Testcases {
+		i := slices.IndexFunc(testcases, func(tc domain.ProblemTestcase) bool { return tc.Key == declared.Name })
+		if i < 0 {
+			for j, tc := range testcases {
+				if tc.Name != declared.Name {
+					continue
+				}
+				if i >= 0 {
+					return apperror.BadRequestError(fmt.Errorf("testcase '%s' is ambiguous", declared.Name), fmt.Sprintf("testcase '%s' matches more than one test, use its full key", declared.Name))
+				}
+				i = j
+			}
+		}
+		if i < 0 {
+			return apperror.BadRequestError(fmt.Errorf("testcase '%s' not in zip", declared.Name), fmt.Sprintf("testcase '%s' is not in the project zip", declared.Name))
+		}
+		if declared.Weight != nil {
+			testcases[i].Weight = *declared.Weight
+		}
+		testcases[i].Group = declared.Group
+	}
+	return nil
+}
+
+// keepScoring carries the weights and groups of the current definitions over to the same tests
+// of a new project, when it has no manifest to declare them.
+func keepScoring(testcases []domain.ProblemTestcase, current []domain.ProblemTestcase) {
+	for i := range testcases {
+		j := slices.IndexFunc(current, func(tc domain.ProblemTestcase) bool { return tc.Key == testcases[i].Key })
+		if j >= 0 {
+			testcases[i].Weight = current[j].Weight
+			testcases[i].Group = current[j].Group
+		}
+	}
+}
+
+// checkEditableFiles makes sure every editable file is part of the project.
+func checkEditableFiles(zipReader *zip.Reader, names []string) error {
+	for _, name := range names {
+		if !slices.ContainsFunc(zipReader.File, func(f *zip.File) bool { return f.Name == name }) {
+			return apperror.BadRequestError(fmt.Errorf("editable file '%s' not in zip", name), fmt.Sprintf("editable file '%s' is not in the project zip", name))
+		}
 	}
+	return nil
+}
 
+// checkReferenceFiles makes sure the reference solution of a manifest covers every editable file.
+func checkReferenceFiles(zipReader *zip.Reader, m *manifest.Manifest, names []string) error {
+	if m == nil || m.Reference == "" {
+		return nil
+	}
+	for _, name := range names {
+		reference := m.ReferenceFile(name)
+		if !slices.ContainsFunc(zipReader.File, func(f *zip.File) bool { return f.Name == reference }) {
+			return apperror.BadRequestError(fmt.Errorf("reference file '%s' not in zip", reference), fmt.Sprintf("reference solution has no '%s'", reference))
+		}
+	}
+	return nil
+}
+
+// uploadTemplates extracts the editable files of a project version and uploads them as the problem's templates.
+// They are stored under the version, so the templates in use are never overwritten before the problem points elsewhere.
+func (s *ProblemService) uploadTemplates(ctx context.Context, problemID uint, version uint, zipReader *zip.Reader, names []string) ([]domain.TemplateFile, error) {
 	// Prepare for storing file keys for editable files
 	var editableFile []domain.TemplateFile
 	uploadErrors := make([]error, 0)
+	var mu sync.Mutex
 
 	// Use a goroutine to upload editable files concurrently
 	var wg sync.WaitGroup
 	for _, file := range zipReader.File {
-		// Iterate over editable files in problemBody and check if it matches the file in the zip
-		for i, editableFileName := range problemBody.EditableFile {
+		// Iterate over editable files and check if it matches the file in the zip
+		for _, editableFileName := range names {
 			if file.Name == editableFileName {
 				wg.Add(1)
-				go func(i int, file *zip.File) {
+				go func(file *zip.File) {
 					defer wg.Done()
 
-					// Open the file from the zip archive
-					fileData, err := file.Open()
-					if err != nil {
-						uploadErrors = append(uploadErrors, fmt.Errorf("failed to open editable file '%s': %v", file.Name, err))
-						return
-					}
-					defer fileData.Close()
-
-					// Uncompress the file (i.e., extract it) and upload it uncompressed
-					fileKey := fmt.Sprintf("problems/%d/template/%s", problem.ID, file.Name)
+					template, err := s.uploadTemplate(ctx, problemID, version, file)
 
-					// Use a buffer to handle uncompressed file data
-					buf := new(bytes.Buffer)
-					_, err = io.Copy(buf, fileData) // Uncompress by copying the file data to a buffer
+					mu.Lock()
+					defer mu.Unlock()
 					if err != nil {
-						uploadErrors = append(uploadErrors, fmt.Errorf("failed to decompress file '%s': %v", file.Name, err))
+						uploadErrors = append(uploadErrors, err)
 						return
 					}
-
-					// Determine the content type based on the file extension or actual content
-					contentType := mime.TypeByExtension(filepath.Ext(file.Name))
-					if contentType == "" {
-						bufBytes := buf.Bytes()
-						contentType = http.DetectContentType(bufBytes)
-					}
-
-					// Upload the uncompressed file data to storage (S3)
-					if err := s.Storage.UploadFile(ctx, fileKey, contentType, buf); err != nil {
-						uploadErrors = append(uploadErrors, fmt.Errorf("failed to upload editable file '%s': %v", file.Name, err))
-						return
-					}
-
 					// Add the uploaded file to the editable files slice
-					editableFile = append(editableFile, domain.TemplateFile{
-						Name:      file.Name,
-						Key:       fileKey,
-						ProblemID: problem.ID,
-					})
-				}(i, file)
+					editableFile = append(editableFile, template)
+				}(file)
 			}
 		}
 	}
@@ -146,42 +355,616 @@ func (s *ProblemService) CreateProblem(ctx context.Context, problemBody dto.Prob
 		for _, err := range uploadErrors {
 			errorMessage += fmt.Sprintf("- %v\n", err)
 		}
-		return problem, apperror.InternalServerError(errors.New(errorMessage), "multiple file upload errors occurred")
+		return nil, apperror.InternalServerError(errors.New(errorMessage), "multiple file upload errors occurred")
 	}
 
-	// Set the editable files and project zip file in the problem struct
-	problem.EditableFile = editableFile
-	problem.ProjectZipFile = fileKey
+	return editableFile, nil
+}
 
-	// Save Template to database
-	// Convert editableFile to a slice of pointers
-	editableFilePtrs := make([]*domain.TemplateFile, len(editableFile))
-	for i := range editableFile {
-		editableFilePtrs[i] = &editableFile[i]
+func (s *ProblemService) uploadTemplate(ctx context.Context, problemID uint, version uint, file *zip.File) (domain.TemplateFile, error) {
+	// Open the file from the zip archive
+	fileData, err := file.Open()
+	if err != nil {
+		return domain.TemplateFile{}, fmt.Errorf("failed to open editable file '%s': %v", file.Name, err)
 	}
-	if err := s.TemplateRepository.CreateMany(editableFilePtrs); err != nil {
+	defer fileData.Close()
+
+	// Uncompress the file (i.e., extract it) and upload it uncompressed
+	fileKey := fmt.Sprintf("problems/%d/versions/%d/template/%s", problemID, version, file.Name)
+
+	// Use a buffer to handle uncompressed file data
+	buf := new(bytes.Buffer)
+	_, err = io.Copy(buf, fileData) // Uncompress by copying the file data to a buffer
+	if err != nil {
+		return domain.TemplateFile{}, fmt.Errorf("failed to decompress file '%s': %v", file.Name, err)
+	}
+
+	// Determine the content type based on the file extension or actual content
+	contentType := mime.TypeByExtension(filepath.Ext(file.Name))
+	if contentType == "" {
+		bufBytes := buf.Bytes()
+		contentType = http.DetectContentType(bufBytes)
+	}
+
+	// Upload the uncompressed file data to storage (S3)
+	if err := s.Storage.UploadFile(ctx, fileKey, contentType, buf); err != nil {
+		return domain.TemplateFile{}, fmt.Errorf("failed to upload editable file '%s': %v", file.Name, err)
+	}
+
+	return domain.TemplateFile{
+		Name:      file.Name,
+		Key:       fileKey,
+		ProblemID: problemID,
+	}, nil
+}
+
+func (s *ProblemService) GetProblems(user domain.User, limit int, page int) ([]domain.Problem, int, int, error) {
+	var problems []domain.Problem
+	var last, total int
+	var err error
+	if user.Can(domain.PermissionViewHiddenProblem) {
+		problems, last, total, err = s.ProblemRepository.GetProblems(limit, page)
+	} else {
+		enrollment, enrollmentErr := enrollmentOf(s.CourseRepository, user)
+		if enrollmentErr != nil {
+			return nil, 0, 0, enrollmentErr
+		}
+		problems, last, total, err = s.ProblemRepository.GetVisibleProblems(time.Now(), enrollment, limit, page)
+	}
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if err := s.fillBestScores(user.Email, problems); err != nil {
+		return nil, 0, 0, err
+	}
+	return problems, last, total, nil
+}
+
+func (s *ProblemService) GetProblemByID(user domain.User, id uint) (domain.Problem, error) {
+	problem, err := s.getProblemFor(user, id)
+	if err != nil {
 		return problem, err
 	}
+	problems := []domain.Problem{problem}
+	if err := s.fillBestScores(user.Email, problems); err != nil {
+		return problem, err
+	}
+	return problems[0], nil
+}
 
-	// Update the problem with editable files
-	problem, err = s.ProblemRepository.UpdateProblem(problem.ID, problem)
+// getProblemFor returns the problem if the user may see it. A hidden problem looks the same as a missing one.
+// Problems of contests that haven't started are hidden like drafts, except from staff of the contest's course
+// and from global staff, who prepare the contests.
+func (s *ProblemService) getProblemFor(user domain.User, id uint) (domain.Problem, error) {
+	if user.Can(domain.PermissionViewHiddenProblem) {
+		problem, err := s.ProblemRepository.GetProblemByID(id)
+		if err != nil {
+			return problem, apperror.NotFoundError(err, "problem not found")
+		}
+		return problem, nil
+	}
+
+	enrollment, err := enrollmentOf(s.CourseRepository, user)
+	if err != nil {
+		return domain.Problem{}, err
+	}
+	problem, err := s.ProblemRepository.GetVisibleProblemByID(id, time.Now(), enrollment)
 	if err != nil {
+		return problem, apperror.NotFoundError(err, "problem not found")
+	}
+	return problem, nil
+}
+
+// UpdateVisibility publishes, schedules, hides or archives a problem.
+// A problem can't be published before its reference solution, if it has one, passed.
+func (s *ProblemService) UpdateVisibility(id uint, body dto.ProblemVisibilityRequest) (domain.Problem, error) {
+	problem, err := s.ProblemRepository.GetProblemByID(id)
+	if err != nil {
+		return problem, apperror.NotFoundError(err, "problem not found")
+	}
+
+	visibility := domain.Visibility(strings.ToUpper(body.Visibility))
+	if !visibility.IsValid() {
+		return problem, apperror.BadRequestError(errors.New("unknown visibility"), "invalid visibility")
+	}
+	if visibility == domain.VisibilityScheduled && body.PublishAt == nil {
+		return problem, apperror.BadRequestError(errors.New("no publish time"), "publish_at is required to schedule a problem")
+	}
+	if body.PublishAt != nil && body.CloseAt != nil && !body.CloseAt.After(*body.PublishAt) {
+		return problem, apperror.BadRequestError(errors.New("close before publish"), "close_at must be after publish_at")
+	}
+	if (visibility == domain.VisibilityScheduled || visibility == domain.VisibilityPublished) && !problem.Gradable() {
+		return problem, apperror.ConflictError(fmt.Errorf("problem validation is %s", problem.ValidationStatus), "problem hasn't passed validation yet")
+	}
+
+	if err := s.ProblemRepository.UpdateFields(problem.ID, map[string]any{
+		"visibility": visibility,
+		"publish_at": body.PublishAt,
+		"close_at":   body.CloseAt,
+	}); err != nil {
 		return problem, apperror.InternalServerError(err, "can't update problem")
 	}
 
-	// Return the created/updated problem
+	problem, err = s.ProblemRepository.GetProblemByID(problem.ID)
+	if err != nil {
+		return problem, apperror.InternalServerError(err, "get problem error")
+	}
+	return problem, nil
+}
+
+// templateURLExpiry is how long the download links of templates stay valid.
+const templateURLExpiry = 15 * time.Minute
+
+// GetTemplates returns short lived download links for the editable files of a problem.
+func (s *ProblemService) GetTemplates(ctx context.Context, user domain.User, id uint) ([]dto.TemplateFileResponse, error) {
+	problem, err := s.getProblemFor(user, id)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(templateURLExpiry)
+	templates := make([]dto.TemplateFileResponse, len(problem.EditableFile))
+	for i, file := range problem.EditableFile {
+		url, err := s.Storage.GetSignedUrl(ctx, file.Key, templateURLExpiry)
+		if err != nil {
+			return nil, apperror.InternalServerError(err, "sign template url error")
+		}
+		templates[i] = dto.TemplateFileResponse{Name: file.Name, URL: url, ExpiresAt: expiresAt}
+	}
+	return templates, nil
+}
+
+// GetValidation returns the result of the latest reference run of a problem.
+func (s *ProblemService) GetValidation(ctx context.Context, id uint) (dto.ProblemValidationResponse, error) {
+	problem, err := s.ProblemRepository.GetProblemByID(id)
+	if err != nil {
+		return dto.ProblemValidationResponse{}, apperror.NotFoundError(err, "problem not found")
+	}
+	res := dto.ProblemValidationResponse{
+		Status:       string(problem.ValidationStatus),
+		SubmissionID: problem.ReferenceSubmissionID,
+		Testcases:    []dto.TestcaseResultEvent{},
+	}
+	if problem.ReferenceSubmissionID == nil {
+		return res, nil
+	}
+
+	submission, err := s.SubmissionRepository.GetSubmissionsByID(*problem.ReferenceSubmissionID)
+	if err != nil {
+		return res, apperror.InternalServerError(err, "get reference submission error")
+	}
+	res.Verdict = string(submission.Verdict)
+	for _, testcase := range submission.Testcases {
+		res.Testcases = append(res.Testcases, dto.TestcaseResultEvent{
+			ID:      testcase.ID,
+			Name:    testcase.Name,
+			Result:  string(testcase.Result),
+			Message: testcase.Message,
+		})
+	}
+	if submission.StdoutObjectKey != "" {
+		if res.OutputURL, err = s.Storage.GetSignedUrl(ctx, submission.StdoutObjectKey, templateURLExpiry); err != nil {
+			return res, apperror.InternalServerError(err, "sign output url error")
+		}
+	}
+	return res, nil
+}
+
+// fillBestScores sets BestScore on the problems the user has a graded submission for.
+func (s *ProblemService) fillBestScores(email string, problems []domain.Problem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(problems))
+	for i, p := range problems {
+		ids[i] = p.ID
+	}
+	scores, err := s.SubmissionRepository.GetBestScores(email, ids)
+	if err != nil {
+		return apperror.InternalServerError(err, "get best scores error")
+	}
+	for i := range problems {
+		if score, ok := scores[problems[i].ID]; ok {
+			problems[i].BestScore = &score
+		}
+	}
+	return nil
+}
+
+// UpdateProblem edits a problem. A new project zip replaces the old one, and its tests
+// replace the testcase definitions; tests that are still there keep their weight.
+func (s *ProblemService) UpdateProblem(ctx context.Context, by string, id uint, body dto.ProblemUpdateForm, zipFile *multipart.FileHeader) (domain.Problem, error) {
+	problem, err := s.ProblemRepository.GetProblemByID(id)
+	if err != nil {
+		return problem, apperror.NotFoundError(err, "problem not found")
+	}
+
+	fields := make(map[string]any)
+	if body.Name != nil {
+		if len(*body.Name) < 2 || len(*body.Name) > 40 {
+			return problem, apperror.BadRequestError(errors.New("invalid name length"), "name must be 2 to 40 characters")
+		}
+		fields["name"] = *body.Name
+	}
+	if body.Description != nil {
+		fields["description"] = *body.Description
+	}
+	if body.HideTestDetails != nil {
+		fields["hide_test_details"] = *body.HideTestDetails
+	}
+	if body.ScoringPolicy != nil {
+		policy := domain.ScoringPolicy(*body.ScoringPolicy)
+		if !policy.IsValid() {
+			return problem, apperror.BadRequestError(errors.New("unknown scoring policy"), "invalid scoring policy")
+		}
+		fields["scoring_policy"] = policy
+	}
+	if body.CourseID != nil {
+		courseID, err := s.courseIDOf(body.CourseID)
+		if err != nil {
+			return problem, err
+		}
+		fields["course_id"] = courseID
+	}
+	if body.Language != nil && len(body.Language) == 0 {
+		return problem, apperror.BadRequestError(errors.New("no language"), "at least one language is required")
+	}
+
+	// Everything is validated before the first write, so a bad zip leaves the problem as it was
+	var zipData multipart.File
+	var zipReader *zip.Reader
+	var testcases []domain.ProblemTestcase
+	var m *manifest.Manifest
+	if zipFile != nil {
+		if zipData, zipReader, err = openProjectZip(zipFile); err != nil {
+			return problem, err
+		}
+		defer zipData.Close()
+
+		if testcases, err = discoverTestcases(zipReader); err != nil {
+			return problem, err
+		}
+
+		if m, err = loadManifest(zipReader); err != nil {
+			return problem, err
+		}
+		if m != nil {
+			if body.EditableFile == nil && len(m.EditableFiles) > 0 {
+				body.EditableFile = m.EditableFiles
+			}
+			if body.Language == nil && len(m.Languages) > 0 {
+				body.Language = m.Languages
+			}
+			if err := applyManifestTestcases(testcases, m); err != nil {
+				return problem, err
+			}
+		} else {
+			keepScoring(testcases, problem.Testcases)
+		}
+	}
+
+	editableFile := body.EditableFile
+	if editableFile == nil && zipReader != nil {
+		for _, template := range problem.EditableFile {
+			editableFile = append(editableFile, template.Name)
+		}
+	}
+	if editableFile != nil && zipReader == nil {
+		// New editable files are taken from the current project
+		if zipReader, err = s.currentProjectZip(ctx, problem); err != nil {
+			return problem, err
+		}
+	}
+	if err := checkEditableFiles(zipReader, editableFile); err != nil {
+		return problem, err
+	}
+	if err := checkReferenceFiles(zipReader, m, editableFile); err != nil {
+		return problem, err
+	}
+
+	// Files are uploaded under the new version first, the database then switches to them in one transaction
+	update := domain.ProblemUpdate{Fields: fields}
+	templateVersion := problem.CurrentVersion
+	if zipData != nil {
+		// Past versions stay untouched, submissions graded against them can still be regraded the same way
+		version, err := s.uploadVersion(ctx, problem.ID, problem.CurrentVersion+1, by, zipData, zipFile.Size)
+		if err != nil {
+			return problem, err
+		}
+		update.Version = &version
+		templateVersion = version.Version
+		fields["project_zip_file"] = version.ZipFile
+		fields["current_version"] = version.Version
+		// The reference run of the previous version says nothing about this one
+		fields["reference_submission_id"] = nil
+		fields["validation_status"] = domain.ValidationNone
+	}
+
+	if editableFile != nil {
+		templates, err := s.uploadTemplates(ctx, problem.ID, templateVersion, zipReader, editableFile)
+		if err != nil {
+			return problem, err
+		}
+		update.Templates = make([]*domain.TemplateFile, len(templates))
+		for i := range templates {
+			update.Templates[i] = &templates[i]
+		}
+	}
+
+	if testcases != nil {
+		update.Testcases = testcases
+		fields["testcase_num"] = len(testcases)
+	}
+
+	if body.Language != nil {
+		update.Languages = make([]domain.Language, len(body.Language))
+		for i, v := range body.Language {
+			update.Languages[i] = domain.Language{Name: v}
+		}
+	}
+
+	if err := s.ProblemRepository.Update(problem.ID, update); err != nil {
+		return problem, apperror.InternalServerError(err, "can't update problem")
+	}
+
+	if m != nil && m.Reference != "" {
+		return s.validateWithReference(ctx, by, problem.ID, zipReader, m)
+	}
+
+	problem, err = s.ProblemRepository.GetProblemByID(problem.ID)
+	if err != nil {
+		return problem, apperror.InternalServerError(err, "get problem error")
+	}
+	return problem, nil
+}
+
+// validateWithReference queues the reference solution of the current version for grading like any submission.
+// The problem takes no submissions until the grader reports that it passed every test within the limits.
+func (s *ProblemService) validateWithReference(ctx context.Context, by string, id uint, zipReader *zip.Reader, m *manifest.Manifest) (domain.Problem, error) {
+	problem, err := s.ProblemRepository.GetProblemByID(id)
+	if err != nil {
+		return problem, apperror.InternalServerError(err, "get problem error")
+	}
+
+	submission := domain.Submission{
+		SubmissionBy:   by,
+		Reference:      true,
+		ProblemID:      problem.ID,
+		Status:         domain.SubmissionQueued,
+		SubmissionFile: make([]*domain.SubmissionFile, len(problem.EditableFile)),
+		Testcases:      make([]domain.Testcase, len(problem.Testcases)),
+	}
+	if len(problem.AllowLanguage) > 0 {
+		submission.LanguageName = problem.AllowLanguage[0].Name
+	}
+	if version := problem.CurrentProblemVersion(); version != nil {
+		submission.ProblemVersionID = &version.ID
+	}
+	for i, template := range problem.EditableFile {
+		submission.SubmissionFile[i] = &domain.SubmissionFile{TemplateFileID: template.ID}
+	}
+	for i, definition := range problem.Testcases {
+		submission.Testcases[i] = domain.Testcase{Name: definition.Key, ProblemTestcaseID: definition.ID}
+	}
+	job := domain.NewHeldGradingJob(time.Now().Add(uploadHold))
+	if err := s.SubmissionRepository.Create(&submission, job); err != nil {
+		return problem, apperror.InternalServerError(err, "create reference submission error")
+	}
+
+	for _, template := range problem.EditableFile {
+		if err := s.uploadReferenceFile(ctx, submission.ID, template, zipReader, m.ReferenceFile(template.Name)); err != nil {
+			return problem, err
+		}
+	}
+
+	// The problem must point at the run before the grader can report on it
+	if err := s.ProblemRepository.UpdateFields(problem.ID, map[string]any{
+		"reference_submission_id": submission.ID,
+		"validation_status":       domain.ValidationPending,
+	}); err != nil {
+		return problem, apperror.InternalServerError(err, "can't update problem")
+	}
+	if err := s.GradingJobRepository.Release(job.ID); err != nil {
+		return problem, apperror.InternalServerError(err, "release grading job error")
+	}
+
+	problem, err = s.ProblemRepository.GetProblemByID(problem.ID)
+	if err != nil {
+		return problem, apperror.InternalServerError(err, "get problem error")
+	}
 	return problem, nil
 }
 
-func (s *ProblemService) GetProblems(limit int, page int) ([]domain.Problem, int, int, error) {
-	return s.ProblemRepository.GetProblems(limit, page)
+// uploadReferenceFile stores a file of the reference solution where the grader looks for submitted code.
+func (s *ProblemService) uploadReferenceFile(ctx context.Context, submissionID uint, template domain.TemplateFile, zipReader *zip.Reader, name string) error {
+	file, err := zipReader.Open(name)
+	if err != nil {
+		return apperror.InternalServerError(err, "can't open reference file")
+	}
+	defer file.Close()
+
+	key := fmt.Sprintf("submissions/%d/%d", submissionID, template.ID)
+	if err := s.Storage.UploadFile(ctx, key, "text/plain", file); err != nil {
+		return apperror.InternalServerError(err, "upload reference file error")
+	}
+	return nil
+}
+
+func (s *ProblemService) currentProjectZip(ctx context.Context, problem domain.Problem) (*zip.Reader, error) {
+	return s.projectZip(ctx, problem.ProjectZipFile)
+}
+
+func (s *ProblemService) projectZip(ctx context.Context, key string) (*zip.Reader, error) {
+	body, err := s.Storage.GetFile(ctx, key)
+	if err != nil {
+		return nil, apperror.InternalServerError(err, "can't get project zip")
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, apperror.InternalServerError(err, "can't read project zip")
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, apperror.InternalServerError(err, "failed to read zip file")
+	}
+	return zipReader, nil
 }
-func (s *ProblemService) GetProblemByID(id uint) (domain.Problem, error) {
-	return s.ProblemRepository.GetProblemByID(id)
+
+// BackfillTestcases fills in the testcase definitions of problems and versions created before they were stored,
+// reading the tests from their project zips as an upload would. Submissions to a problem without definitions
+// have nothing to be graded against, and a version without them can't be regraded. It returns the number of
+// problems and versions filled in; one that fails is reported in the error and doesn't stop the others.
+func (s *ProblemService) BackfillTestcases(ctx context.Context) (int, error) {
+	problems, err := s.ProblemRepository.GetWithoutTestcases()
+	if err != nil {
+		return 0, apperror.InternalServerError(err, "get problems error")
+	}
+
+	filled := 0
+	var errs []error
+	for _, problem := range problems {
+		testcases, err := s.storedTestcases(ctx, problem.ProjectZipFile)
+		if err == nil {
+			err = s.ProblemRepository.ReplaceTestcases(problem.ID, testcases)
+		}
+		if err == nil {
+			err = s.ProblemRepository.UpdateFields(problem.ID, map[string]any{"testcase_num": len(testcases)})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("problem %d: %w", problem.ID, err))
+			continue
+		}
+		filled++
+	}
+
+	// Runs after the problems, so a current version is linked to the definitions just created
+	versions, err := s.ProblemRepository.GetVersionsWithoutTestcases()
+	if err != nil {
+		return filled, errors.Join(append(errs, err)...)
+	}
+	for _, version := range versions {
+		testcases, err := s.storedTestcases(ctx, version.ZipFile)
+		if err == nil {
+			err = s.ProblemRepository.SetVersionTestcases(&version, testcases)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("problem %d version %d: %w", version.ProblemID, version.Version, err))
+			continue
+		}
+		filled++
+	}
+	return filled, errors.Join(errs...)
 }
-func (s *ProblemService) UpdateProblem(id uint, problem domain.Problem) (domain.Problem, error) {
-	return domain.Problem{}, nil
+
+// storedTestcases reads the testcase definitions of a project zip in storage.
+func (s *ProblemService) storedTestcases(ctx context.Context, key string) ([]domain.ProblemTestcase, error) {
+	zipReader, err := s.projectZip(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	testcases, err := discoverTestcases(zipReader)
+	if err != nil {
+		return nil, err
+	}
+	m, err := loadManifest(zipReader)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		if err := applyManifestTestcases(testcases, m); err != nil {
+			return nil, err
+		}
+	}
+	return testcases, nil
 }
-func (s *ProblemService) DeleteProblem() error {
+
+// DeleteProblem soft deletes a problem and removes its files from storage.
+// Submissions are kept, but they can no longer be graded.
+func (s *ProblemService) DeleteProblem(ctx context.Context, id uint) error {
+	if _, err := s.ProblemRepository.GetProblemByID(id); err != nil {
+		return apperror.NotFoundError(err, "problem not found")
+	}
+	if err := s.ProblemRepository.DeleteProblem(id); err != nil {
+		return apperror.InternalServerError(err, "delete problem error")
+	}
+	if err := s.Storage.DeleteFolder(ctx, fmt.Sprintf("problems/%d/", id)); err != nil {
+		return apperror.InternalServerError(err, "delete problem files error")
+	}
 	return nil
 }
+
+// UpdateScoring changes how submissions of a problem are scored.
+// Submissions graded before the change keep their score, and older versions keep their weights.
+func (s *ProblemService) UpdateScoring(id uint, body dto.ProblemScoringRequest) (domain.Problem, error) {
+	policy := domain.ScoringPolicy(body.ScoringPolicy)
+	if !policy.IsValid() {
+		return domain.Problem{}, apperror.BadRequestError(errors.New("unknown scoring policy"), "invalid scoring policy")
+	}
+
+	testcases := make([]domain.ProblemTestcase, len(body.Testcases))
+	for i, t := range body.Testcases {
+		if t.Weight < 0 {
+			return domain.Problem{}, apperror.BadRequestError(errors.New("negative weight"), "testcase weight must not be negative")
+		}
+		testcases[i] = domain.ProblemTestcase{Model: gorm.Model{ID: t.ID}, Weight: t.Weight, Group: t.Group}
+	}
+
+	if err := s.ProblemRepository.UpdateScoring(id, policy, testcases); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Problem{}, apperror.NotFoundError(err, "testcase not found")
+		}
+		return domain.Problem{}, apperror.InternalServerError(err, "update scoring error")
+	}
+
+	problem, err := s.ProblemRepository.GetProblemByID(id)
+	if err != nil {
+		return problem, apperror.NotFoundError(err, "problem not found")
+	}
+	return problem, nil
+}
+
+// Regrade grades every submission of a problem again against the given version, 0 meaning the current one.
+// It returns the number of submissions that were queued and of those skipped because they were being graded.
+func (s *ProblemService) Regrade(id uint, version uint) (dto.RegradeResponse, error) {
+	problem, err := s.ProblemRepository.GetProblemByID(id)
+	if err != nil {
+		return dto.RegradeResponse{}, apperror.NotFoundError(err, "problem not found")
+	}
+	if version == 0 {
+		version = problem.CurrentVersion
+	}
+	problemVersion, err := s.ProblemRepository.GetVersion(problem.ID, version)
+	if err != nil {
+		return dto.RegradeResponse{}, apperror.NotFoundError(err, "problem version not found")
+	}
+
+	// The tests of another version may differ from the current ones
+	if len(problemVersion.Testcases) == 0 {
+		return dto.RegradeResponse{}, apperror.UnprocessableEntityError(errors.New("version has no testcases"), "problem version has no testcase definitions")
+	}
+
+	ids, err := s.SubmissionRepository.GetIDsByProblemID(problem.ID)
+	if err != nil {
+		return dto.RegradeResponse{}, apperror.InternalServerError(err, "get submissions error")
+	}
+
+	response := dto.RegradeResponse{Version: version}
+	for _, submissionID := range ids {
+		reset, err := s.SubmissionRepository.ResetForRegrade(submissionID, problemVersion.ID, problemVersion.Testcases)
+		if err != nil {
+			return dto.RegradeResponse{}, apperror.InternalServerError(err, "reset submission error")
+		}
+		// A submission being graded is left for the next regrade, its results would overwrite the reset
+		if !reset {
+			response.Skipped++
+			continue
+		}
+		if _, err := s.GradingJobRepository.EnqueueUnlessPending(domain.NewGradingJob(submissionID)); err != nil {
+			return dto.RegradeResponse{}, apperror.InternalServerError(err, "enqueue grading job error")
+		}
+		response.Submissions++
+	}
+
+	return response, nil
+}
@@ -2,10 +2,16 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/gofiber/fiber/v2/log"
 	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/port"
@@ -14,27 +20,77 @@ import (
 	"github.com/yokeTH/our-grader-backend/proto/verilog"
 )
 
+// submissionWatchInterval is how often Watch reads the submission besides relaying the grader's events.
+const submissionWatchInterval = 5 * time.Second
+const submissionWatchTimeout = 15 * time.Minute
+const submissionWatchPingInterval = 15 * time.Second
+
+const submissionSearchDefaultLimit = 50
+const submissionSearchMaxLimit = 200
+
+// uploadHold is how long a new grading job waits for the submitted files. The job is released as soon as
+// they are uploaded; if the API dies before that, a worker still takes the job and reports what is missing.
+const uploadHold = 5 * time.Minute
+
+// maxSourceBytes caps each file returned inline, simulator logs in particular can grow large.
+const maxSourceBytes = 1 << 20
+
 type SubmissionService struct {
 	storage        storage.IStorage
 	submissionRepo port.SubmissionRepository
 	problemRepo    port.ProblemRepository
-	client         verilog.SomeServiceClient
+	jobRepo        port.GradingJobRepository
+	courseRepo     port.CourseRepository
+	assignmentRepo port.AssignmentRepository
+	contestRepo    port.ContestRepository
+	grader         verilog.SomeServiceClient
 }
 
-func NewSubmissionService(storage storage.IStorage, submissionRepo port.SubmissionRepository, problemRepo port.ProblemRepository, client verilog.SomeServiceClient) *SubmissionService {
+func NewSubmissionService(storage storage.IStorage, submissionRepo port.SubmissionRepository, problemRepo port.ProblemRepository, jobRepo port.GradingJobRepository, courseRepo port.CourseRepository, assignmentRepo port.AssignmentRepository, contestRepo port.ContestRepository, grader verilog.SomeServiceClient) *SubmissionService {
 	return &SubmissionService{
 		storage:        storage,
 		problemRepo:    problemRepo,
 		submissionRepo: submissionRepo,
-		client:         client,
+		jobRepo:        jobRepo,
+		courseRepo:     courseRepo,
+		assignmentRepo: assignmentRepo,
+		contestRepo:    contestRepo,
+		grader:         grader,
 	}
 }
 
-func (s *SubmissionService) Create(ctx context.Context, by string, body dto.SubmissionRequest) error {
+func (s *SubmissionService) Create(ctx context.Context, user domain.User, body dto.SubmissionRequest) (domain.Submission, error) {
 	submissionFiles := make([]*domain.SubmissionFile, len(body.Codes))
 	problem, err := s.problemRepo.GetProblemByID(body.ProblemID)
 	if err != nil {
-		return err
+		return domain.Submission{}, err
+	}
+	// Staff may try out problems that students can't see or submit to yet
+	if !user.Can(domain.PermissionViewHiddenProblem) {
+		enrollment, err := enrollmentOf(s.courseRepo, user)
+		if err != nil {
+			return domain.Submission{}, err
+		}
+		now := time.Now()
+		if !enrollment.CanSee(&problem, now) {
+			return domain.Submission{}, apperror.NotFoundError(errors.New("problem is hidden"), "problem not found")
+		}
+		if !enrollment.CanSubmit(&problem, now) {
+			return domain.Submission{}, apperror.ForbiddenError(errors.New("problem is closed"), "problem is closed for submissions")
+		}
+		open, err := assignmentsOpen(s.assignmentRepo, enrollment, user, problem.ID, now)
+		if err != nil {
+			return domain.Submission{}, err
+		}
+		if !open {
+			return domain.Submission{}, apperror.ForbiddenError(errors.New("assignment is closed"), "assignment is not open for submissions")
+		}
+		if err := contestsAllow(s.contestRepo, enrollment, user, problem.ID, now); err != nil {
+			return domain.Submission{}, err
+		}
+	}
+	if !problem.Gradable() {
+		return domain.Submission{}, apperror.ConflictError(fmt.Errorf("problem validation is %s", problem.ValidationStatus), "problem hasn't passed validation yet")
 	}
 	for i, v := range body.Codes {
 		var id uint = 0
@@ -45,7 +101,7 @@ func (s *SubmissionService) Create(ctx context.Context, by string, body dto.Subm
 			}
 		}
 		if id == 0 {
-			return apperror.BadRequestError(errors.New("mismatch"), "invalid request")
+			return domain.Submission{}, apperror.BadRequestError(errors.New("mismatch"), "invalid request")
 		}
 		submissionFiles[i] = &domain.SubmissionFile{
 			TemplateFileID: id,
@@ -53,34 +109,411 @@ func (s *SubmissionService) Create(ctx context.Context, by string, body dto.Subm
 	}
 
 	submission := domain.Submission{
-		SubmissionBy:   by,
+		SubmissionBy:   user.Email,
 		SubmissionFile: submissionFiles,
 		LanguageName:   body.Language,
 		ProblemID:      body.ProblemID,
-		Testcases:      make([]domain.Testcase, problem.TestcaseNum),
+		Status:         domain.SubmissionQueued,
+		Testcases:      make([]domain.Testcase, len(problem.Testcases)),
+	}
+	if version := problem.CurrentProblemVersion(); version != nil {
+		submission.ProblemVersionID = &version.ID
+	}
+	for i, definition := range problem.Testcases {
+		submission.Testcases[i] = domain.Testcase{Name: definition.Key, ProblemTestcaseID: definition.ID}
 	}
 
-	if err := s.submissionRepo.Create(&submission); err != nil {
-		return apperror.InternalServerError(err, "create submission error")
+	// Grading happens in the background; the worker picks the job up from the queue once the files are there
+	job := domain.NewHeldGradingJob(time.Now().Add(uploadHold))
+	if err := s.submissionRepo.Create(&submission, job); err != nil {
+		return submission, apperror.InternalServerError(err, "create submission error")
 	}
 
 	for i, v := range body.Codes {
 		data := strings.NewReader(v.Code)
 		key := fmt.Sprintf("submissions/%d/%d", submission.ID, submissionFiles[i].TemplateFileID)
 		if err := s.storage.UploadFile(ctx, key, "text/plain", data); err != nil {
-			return apperror.InternalServerError(err, "upload error")
+			return submission, apperror.InternalServerError(err, "upload error")
 		}
 	}
 
-	if _, err := s.client.Run(ctx, &verilog.VerilogRequest{
-		SubmissionID: uint32(submission.ID),
-	}); err != nil {
-		return apperror.InternalServerError(err, "verilog service run error")
+	if err := s.jobRepo.Release(job.ID); err != nil {
+		return submission, apperror.InternalServerError(err, "release grading job error")
+	}
+
+	return submission, nil
+}
+
+func (s *SubmissionService) GetSubmissionByID(id uint) (domain.Submission, error) {
+	submission, err := s.submissionRepo.GetSubmissionsByID(id)
+	if err != nil {
+		return submission, apperror.NotFoundError(err, "submission not found")
+	}
+	submission.HideTestDetails()
+	return submission, nil
+}
+
+// CanView reports whether the user may see the submission: it is theirs, they are staff,
+// or they are staff of the course the problem belongs to. Problem must be loaded.
+func (s *SubmissionService) CanView(user domain.User, submission domain.Submission) (bool, error) {
+	if submission.SubmissionBy == user.Email {
+		return true, nil
+	}
+	return s.isStaffFor(user, submission)
+}
+
+// isStaffFor reports whether the user is staff, globally or of the course of the submission's problem.
+// Problem must be loaded.
+func (s *SubmissionService) isStaffFor(user domain.User, submission domain.Submission) (bool, error) {
+	if user.Can(domain.PermissionViewAllSubmission) {
+		return true, nil
+	}
+	enrollment, err := enrollmentOf(s.courseRepo, user)
+	if err != nil {
+		return false, err
+	}
+	return enrollment.IsStaff(submission.Problem.CourseID), nil
+}
+
+// GetDetail returns the submission to its owner or to staff, with signed links to the submitted files
+// and the simulator output. Testcase messages the problem hides are only shown to staff.
+func (s *SubmissionService) GetDetail(ctx context.Context, user domain.User, id uint) (dto.SubmissionDetailResponse, error) {
+	submission, err := s.submissionRepo.GetSubmissionsByID(id)
+	if err != nil {
+		return dto.SubmissionDetailResponse{}, apperror.NotFoundError(err, "submission not found")
+	}
+	staff, err := s.isStaffFor(user, submission)
+	if err != nil {
+		return dto.SubmissionDetailResponse{}, err
+	}
+	if !staff && submission.SubmissionBy != user.Email {
+		return dto.SubmissionDetailResponse{}, apperror.ForbiddenError(errors.New("submission belongs to another user"), "you can't see this submission")
+	}
+	if !staff {
+		submission.HideTestDetails()
 	}
 
-	return nil
+	res := submission.ToDetailDTO()
+	res.ExpiresAt = time.Now().Add(templateURLExpiry)
+	for _, file := range submission.SubmissionFile {
+		url, err := s.storage.GetSignedUrl(ctx, fmt.Sprintf("submissions/%d/%d", submission.ID, file.TemplateFileID), templateURLExpiry)
+		if err != nil {
+			return res, apperror.InternalServerError(err, "sign submission file url error")
+		}
+		res.Files = append(res.Files, dto.TemplateFileResponse{Name: file.TemplateFile.Name, URL: url, ExpiresAt: res.ExpiresAt})
+	}
+	if submission.StdoutObjectKey != "" {
+		if res.StdoutURL, err = s.storage.GetSignedUrl(ctx, submission.StdoutObjectKey, templateURLExpiry); err != nil {
+			return res, apperror.InternalServerError(err, "sign output url error")
+		}
+	}
+	return res, nil
+}
+
+// Watch relays the grader's progress of the submission and calls send for every status, stage or testcase
+// change until grading is finished. The first call describes the current state, so a late subscriber does
+// not miss anything. The submission is still read every few seconds: the stream only carries what happens
+// after it is opened, and the worker grading the submission may be another grader than the one it reaches.
+func (s *SubmissionService) Watch(ctx context.Context, id uint, send func(dto.SubmissionEvent) error) error {
+	ctx, cancel := context.WithTimeout(ctx, submissionWatchTimeout)
+	defer cancel()
+
+	// Subscribe before reading the submission so that nothing happens in between
+	events := s.watchGrader(ctx, id)
+
+	ticker := time.NewTicker(submissionWatchInterval)
+	defer ticker.Stop()
+
+	lastSent := time.Now()
+	emit := func(ev dto.SubmissionEvent) error {
+		lastSent = time.Now()
+		return send(ev)
+	}
+
+	var lastStatus dto.SubmissionStatusEvent
+	lastResults := make(map[uint]dto.TestcaseResultEvent)
+	testcaseIDs := make(map[string]uint)
+	sendTestcase := func(ev dto.TestcaseResultEvent) error {
+		if last, ok := lastResults[ev.ID]; ok && last.Result == ev.Result && last.Message == ev.Message {
+			return nil
+		}
+		lastResults[ev.ID] = ev
+		return emit(dto.SubmissionEvent{Event: "testcase", Data: ev})
+	}
+
+	// sync sends what changed in the stored submission and reports whether grading is finished
+	sync := func() (bool, error) {
+		submission, err := s.submissionRepo.GetSubmissionsByID(id)
+		if err != nil {
+			return false, err
+		}
+		submission.HideTestDetails()
+
+		for _, testcase := range submission.Testcases {
+			testcaseIDs[testcase.Name] = testcase.ID
+			if err := sendTestcase(dto.TestcaseResultEvent{
+				ID:      testcase.ID,
+				Name:    testcase.Name,
+				Result:  string(testcase.Result),
+				Message: testcase.Message,
+			}); err != nil {
+				return false, err
+			}
+		}
+
+		status := dto.SubmissionStatusEvent{
+			Status:   string(submission.Status),
+			Verdict:  string(submission.Verdict),
+			Score:    submission.Score,
+			MaxScore: submission.MaxScore,
+		}
+		if submission.IsFinished() {
+			return true, emit(dto.SubmissionEvent{Event: "verdict", Data: status})
+		}
+		if status != lastStatus {
+			lastStatus = status
+			return false, emit(dto.SubmissionEvent{Event: "status", Data: status})
+		}
+		return false, nil
+	}
+
+	if done, err := sync(); done || err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case ev, ok := <-events:
+			if !ok {
+				// The stream is gone, reading the submission still gets the client to the verdict
+				events = nil
+				continue
+			}
+			switch e := ev.Event.(type) {
+			case *verilog.GradingEvent_Stage:
+				if err := emit(dto.SubmissionEvent{Event: "stage", Data: dto.SubmissionStageEvent{Stage: e.Stage.Stage.String()}}); err != nil {
+					return err
+				}
+			case *verilog.GradingEvent_Testcase:
+				name := e.Testcase.Classname + "." + e.Testcase.Name
+				testcaseID, known := testcaseIDs[name]
+				if !known {
+					continue
+				}
+				result := domain.TestResultFail
+				if e.Testcase.Passed {
+					result = domain.TestResultPass
+				}
+				// The message, if any, is stored with the final results and sent on the next sync
+				if err := sendTestcase(dto.TestcaseResultEvent{ID: testcaseID, Name: name, Result: string(result)}); err != nil {
+					return err
+				}
+			case *verilog.GradingEvent_Summary:
+				if done, err := sync(); done || err != nil {
+					return err
+				}
+			}
+
+		case <-ticker.C:
+			if done, err := sync(); done || err != nil {
+				return err
+			}
+			if time.Since(lastSent) >= submissionWatchPingInterval {
+				// Writing is the only way to notice that the client has gone away
+				if err := emit(dto.SubmissionEvent{Event: "ping"}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// watchGrader streams the grading events of the submission from the grader. The channel is closed when
+// the stream ends or fails.
+func (s *SubmissionService) watchGrader(ctx context.Context, id uint) <-chan *verilog.GradingEvent {
+	events := make(chan *verilog.GradingEvent)
+	go func() {
+		defer close(events)
+		stream, err := s.grader.Watch(ctx, &verilog.WatchRequest{SubmissionID: uint32(id)})
+		if err != nil {
+			log.Warnf("watch submission %d on grader: %v", id, err)
+			return
+		}
+		for {
+			ev, err := stream.Recv()
+			if err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					log.Warnf("watch submission %d on grader: %v", id, err)
+				}
+				return
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
 }
 
 func (s *SubmissionService) GetSubmissionsByUserIDAndProblemID(email string, pid uint, limit int, page int) ([]domain.Submission, int, int, error) {
-	return s.submissionRepo.GetSubmissionsByUserIDAndProblemID(email, pid, limit, page)
+	submissions, last, total, err := s.submissionRepo.GetSubmissionsByUserIDAndProblemID(email, pid, limit, page)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	for i := range submissions {
+		submissions[i].HideTestDetails()
+	}
+	return submissions, last, total, nil
+}
+
+// SearchSubmissions lists every submission matching the query, a page at a time. It returns the cursor
+// of the next page, empty on the last one.
+func (s *SubmissionService) SearchSubmissions(query dto.SubmissionSearchQuery) ([]domain.Submission, string, error) {
+	filter := domain.SubmissionFilter{
+		ProblemID:    query.ProblemID,
+		SubmissionBy: strings.ToLower(strings.TrimSpace(query.Email)),
+		Verdict:      domain.Verdict(strings.ToUpper(query.Verdict)),
+		LanguageName: query.Language,
+		MinScore:     query.MinScore,
+		MaxScore:     query.MaxScore,
+	}
+	var err error
+	if filter.From, err = parseQueryTime("from", query.From); err != nil {
+		return nil, "", err
+	}
+	if filter.To, err = parseQueryTime("to", query.To); err != nil {
+		return nil, "", err
+	}
+
+	switch query.Sort {
+	case "", "-created_at":
+	case "created_at":
+		filter.Ascending = true
+	case "score":
+		filter.SortByScore, filter.Ascending = true, true
+	case "-score":
+		filter.SortByScore = true
+	default:
+		return nil, "", apperror.BadRequestError(errors.New("invalid sort"), "sort must be created_at or score, optionally prefixed with -")
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeSubmissionCursor(query.Cursor)
+		if err != nil {
+			return nil, "", apperror.BadRequestError(err, "invalid cursor")
+		}
+		filter.After = &cursor
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = submissionSearchDefaultLimit
+	}
+	limit = min(limit, submissionSearchMaxLimit)
+
+	// One more than asked tells whether there is a next page
+	submissions, err := s.submissionRepo.Search(filter, limit+1)
+	if err != nil {
+		return nil, "", apperror.InternalServerError(err, "search submissions error")
+	}
+	if len(submissions) <= limit {
+		return submissions, "", nil
+	}
+	submissions = submissions[:limit]
+	last := submissions[limit-1]
+	return submissions, encodeSubmissionCursor(domain.SubmissionCursor{ID: last.ID, Score: last.Score}), nil
+}
+
+// GetSubmissionWithDetails returns the submission with every testcase message, for staff.
+func (s *SubmissionService) GetSubmissionWithDetails(id uint) (domain.Submission, error) {
+	submission, err := s.submissionRepo.GetSubmissionsByID(id)
+	if err != nil {
+		return submission, apperror.NotFoundError(err, "submission not found")
+	}
+	return submission, nil
+}
+
+// GetSource reads the submitted files and the simulator output of a submission from storage.
+// Each of them is cut at maxSourceBytes.
+func (s *SubmissionService) GetSource(ctx context.Context, id uint) (dto.SubmissionSourceResponse, error) {
+	res := dto.SubmissionSourceResponse{Files: []dto.SubmissionSourceFile{}}
+	submission, err := s.submissionRepo.GetSubmissionsByID(id)
+	if err != nil {
+		return res, apperror.NotFoundError(err, "submission not found")
+	}
+
+	for _, file := range submission.SubmissionFile {
+		key := fmt.Sprintf("submissions/%d/%d", submission.ID, file.TemplateFileID)
+		content, truncated, err := s.readLimited(ctx, key)
+		if err != nil {
+			return res, apperror.InternalServerError(err, "read submission file error")
+		}
+		res.Files = append(res.Files, dto.SubmissionSourceFile{Name: file.TemplateFile.Name, Content: content, Truncated: truncated})
+	}
+	if submission.StdoutObjectKey != "" {
+		if res.Stdout, res.StdoutTruncated, err = s.readLimited(ctx, submission.StdoutObjectKey); err != nil {
+			return res, apperror.InternalServerError(err, "read output error")
+		}
+	}
+	return res, nil
+}
+
+func (s *SubmissionService) readLimited(ctx context.Context, key string) (string, bool, error) {
+	file, err := s.storage.GetFile(ctx, key)
+	if err != nil {
+		return "", false, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxSourceBytes+1))
+	if err != nil {
+		return "", false, err
+	}
+	if len(data) > maxSourceBytes {
+		return string(data[:maxSourceBytes]), true, nil
+	}
+	return string(data), false, nil
+}
+
+// parseQueryTime parses an optional RFC 3339 query parameter.
+func parseQueryTime(name string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, apperror.BadRequestError(err, fmt.Sprintf("%s must be an RFC 3339 time", name))
+	}
+	return &t, nil
+}
+
+func encodeSubmissionCursor(cursor domain.SubmissionCursor) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%s", cursor.ID, strconv.FormatFloat(cursor.Score, 'g', -1, 64)))
+}
+
+func decodeSubmissionCursor(s string) (domain.SubmissionCursor, error) {
+	var cursor domain.SubmissionCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, err
+	}
+	id, score, ok := strings.Cut(string(data), ":")
+	if !ok {
+		return cursor, errors.New("malformed cursor")
+	}
+	parsedID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return cursor, err
+	}
+	if cursor.Score, err = strconv.ParseFloat(score, 64); err != nil {
+		return cursor, err
+	}
+	if math.IsNaN(cursor.Score) || math.IsInf(cursor.Score, 0) {
+		return cursor, errors.New("malformed cursor")
+	}
+	cursor.ID = uint(parsedID)
+	return cursor, nil
 }
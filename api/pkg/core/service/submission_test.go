@@ -0,0 +1,143 @@
+package service
+
+import (
+	"cmp"
+	"encoding/base64"
+	"slices"
+	"testing"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/port"
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+	"gorm.io/gorm"
+)
+
+func TestSubmissionCursorRoundTrip(t *testing.T) {
+	for _, cursor := range []domain.SubmissionCursor{
+		{ID: 1},
+		{ID: 42, Score: 87.5},
+		{ID: 7, Score: -3},
+		{ID: 1<<32 + 5, Score: 1.0 / 3},
+		{ID: 9, Score: 1e21},
+	} {
+		got, err := decodeSubmissionCursor(encodeSubmissionCursor(cursor))
+		if err != nil {
+			t.Fatalf("decode %+v: %v", cursor, err)
+		}
+		if got != cursor {
+			t.Errorf("round trip of %+v gave %+v", cursor, got)
+		}
+	}
+}
+
+func TestDecodeSubmissionCursorRejects(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("1:25"))},
+		{"no separator", encode("12")},
+		{"negative id", encode("-1:2")},
+		{"id not a number", encode("a:2")},
+		{"score not a number", encode("1:a")},
+		{"NaN score", encode("1:NaN")},
+		{"infinite score", encode("1:+Inf")},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		if _, err := decodeSubmissionCursor(tt.cursor); err == nil {
+			t.Errorf("%s: decoded %q", tt.name, tt.cursor)
+		}
+	}
+}
+
+// searchRepo searches an in-memory list the way SubmissionRepository.Search does. Other methods aren't used.
+type searchRepo struct {
+	port.SubmissionRepository
+	submissions []domain.Submission
+}
+
+func (r *searchRepo) Search(filter domain.SubmissionFilter, limit int) ([]domain.Submission, error) {
+	order := func(a, b domain.Submission) int {
+		c := cmp.Compare(a.ID, b.ID)
+		if filter.SortByScore {
+			c = cmp.Or(cmp.Compare(a.Score, b.Score), c)
+		}
+		if !filter.Ascending {
+			c = -c
+		}
+		return c
+	}
+	sorted := slices.SortedFunc(slices.Values(r.submissions), order)
+	var res []domain.Submission
+	for _, s := range sorted {
+		if filter.After != nil && order(s, domain.Submission{Model: gorm.Model{ID: filter.After.ID}, Score: filter.After.Score}) <= 0 {
+			continue
+		}
+		if len(res) == limit {
+			break
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func TestSearchSubmissionsPages(t *testing.T) {
+	repo := &searchRepo{}
+	for i, score := range []float64{50, 100, 50, 0, 100, 50, 75} {
+		repo.submissions = append(repo.submissions, domain.Submission{Model: gorm.Model{ID: uint(i + 1)}, Score: score})
+	}
+	s := &SubmissionService{submissionRepo: repo}
+
+	tests := []struct {
+		sort string
+		want []uint
+	}{
+		{"", []uint{7, 6, 5, 4, 3, 2, 1}},
+		{"created_at", []uint{1, 2, 3, 4, 5, 6, 7}},
+		{"-score", []uint{5, 2, 7, 6, 3, 1, 4}},
+		{"score", []uint{4, 1, 3, 6, 7, 2, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			var got []uint
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(tt.want) {
+					t.Fatal("search doesn't end")
+				}
+				page, next, err := s.SearchSubmissions(dto.SubmissionSearchQuery{Sort: tt.sort, Limit: 2, Cursor: cursor})
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, sub := range page {
+					got = append(got, sub.ID)
+				}
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchSubmissionsRejects(t *testing.T) {
+	s := &SubmissionService{submissionRepo: &searchRepo{}}
+	for _, query := range []dto.SubmissionSearchQuery{
+		{Sort: "verdict"},
+		{Cursor: "!!!"},
+		{From: "yesterday"},
+	} {
+		_, _, err := s.SearchSubmissions(query)
+		if e, ok := err.(*apperror.AppError); !ok || e.Code != 400 {
+			t.Errorf("%+v: err = %v, want a bad request", query, err)
+		}
+	}
+}
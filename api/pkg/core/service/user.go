@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/port"
+	"gorm.io/gorm"
+)
+
+type UserService struct {
+	userRepo port.UserRepository
+}
+
+func NewUserService(userRepo port.UserRepository) *UserService {
+	return &UserService{userRepo: userRepo}
+}
+
+// SignIn returns the user behind a verified profile, creating them on their first request.
+// The user is only written when their name, picture or hosted domain changed.
+func (s *UserService) SignIn(profile domain.Profile) (domain.User, error) {
+	user := domain.User{
+		Email:        strings.ToLower(strings.TrimSpace(profile.Email)),
+		Name:         strings.TrimSpace(profile.GivenName + " " + profile.FamilyName),
+		Picture:      profile.Picture,
+		HostedDomain: profile.HD,
+	}
+	existing, err := s.userRepo.GetByEmail(user.Email)
+	if err == nil && existing.Name == user.Name && existing.Picture == user.Picture && existing.HostedDomain == user.HostedDomain {
+		return existing, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, apperror.InternalServerError(err, "sign in error")
+	}
+	if err := s.userRepo.Upsert(&user); err != nil {
+		return user, apperror.InternalServerError(err, "sign in error")
+	}
+	return user, nil
+}
+
+// Bootstrap makes sure the given emails are admins, so a fresh deployment has someone who can grant roles.
+func (s *UserService) Bootstrap(adminEmails []string) error {
+	for _, email := range adminEmails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email == "" {
+			continue
+		}
+		user, err := s.userRepo.FindOrCreateByEmail(email)
+		if err != nil {
+			return err
+		}
+		if err := s.userRepo.GrantRole(user.ID, domain.RoleAdmin, "bootstrap"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *UserService) GetUsers(limit int, page int) ([]domain.User, int, int, error) {
+	return s.userRepo.GetUsers(limit, page)
+}
+
+func (s *UserService) GrantRole(by domain.User, email string, role domain.Role) (domain.User, error) {
+	if !role.IsValid() {
+		return domain.User{}, apperror.BadRequestError(errors.New("unknown role"), "invalid role")
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	user, err := s.userRepo.FindOrCreateByEmail(email)
+	if err != nil {
+		return user, apperror.InternalServerError(err, "get user error")
+	}
+	if err := s.userRepo.GrantRole(user.ID, role, by.Email); err != nil {
+		return user, apperror.InternalServerError(err, "grant role error")
+	}
+	return s.userRepo.GetByEmail(email)
+}
+
+func (s *UserService) RevokeRole(by domain.User, email string, role domain.Role) (domain.User, error) {
+	if !role.IsValid() {
+		return domain.User{}, apperror.BadRequestError(errors.New("unknown role"), "invalid role")
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	user, err := userByEmail(s.userRepo, email)
+	if err != nil {
+		return user, err
+	}
+	if !user.HasRole(role) {
+		return user, apperror.NotFoundError(errors.New("role not granted"), "user doesn't have this role")
+	}
+
+	// Someone must always be left to grant roles
+	if role == domain.RoleAdmin {
+		admins, err := s.userRepo.CountByRole(domain.RoleAdmin)
+		if err != nil {
+			return user, apperror.InternalServerError(err, "count admins error")
+		}
+		if admins <= 1 {
+			return user, apperror.ConflictError(errors.New("last admin"), "can't revoke the last admin")
+		}
+	}
+
+	if _, err := s.userRepo.RevokeRole(user.ID, role); err != nil {
+		return user, apperror.InternalServerError(err, "revoke role error")
+	}
+	return s.userRepo.GetByEmail(email)
+}
+
+// userByEmail looks up someone who has to exist already, e.g. to take something away from them.
+func userByEmail(userRepo port.UserRepository, email string) (domain.User, error) {
+	user, err := userRepo.GetByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, apperror.NotFoundError(err, "user not found")
+	}
+	if err != nil {
+		return user, apperror.InternalServerError(err, "get user error")
+	}
+	return user, nil
+}
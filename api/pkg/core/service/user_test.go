@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/port"
+	"gorm.io/gorm"
+)
+
+// userRepo keeps users by email, the way the unique index on the column does.
+type userRepo struct {
+	port.UserRepository
+	users map[string]*domain.User
+}
+
+func newUserRepo() *userRepo {
+	return &userRepo{users: make(map[string]*domain.User)}
+}
+
+func (r *userRepo) Upsert(user *domain.User) error {
+	if existing, ok := r.users[user.Email]; ok {
+		user.ID = existing.ID
+		user.Roles = existing.Roles
+	} else {
+		user.ID = uint(len(r.users) + 1)
+	}
+	stored := *user
+	r.users[user.Email] = &stored
+	return nil
+}
+
+func (r *userRepo) FindOrCreateByEmail(email string) (domain.User, error) {
+	if _, ok := r.users[email]; !ok {
+		if err := r.Upsert(&domain.User{Email: email}); err != nil {
+			return domain.User{}, err
+		}
+	}
+	return *r.users[email], nil
+}
+
+func (r *userRepo) GetByEmail(email string) (domain.User, error) {
+	user, ok := r.users[email]
+	if !ok {
+		return domain.User{}, gorm.ErrRecordNotFound
+	}
+	return *user, nil
+}
+
+func (r *userRepo) GrantRole(userID uint, role domain.Role, grantedBy string) error {
+	for _, user := range r.users {
+		if user.ID == userID && !user.HasRole(role) {
+			user.Roles = append(user.Roles, domain.UserRole{UserID: userID, Role: role, GrantedBy: grantedBy})
+		}
+	}
+	return nil
+}
+
+func TestUserServiceNormalizesEmails(t *testing.T) {
+	repo := newUserRepo()
+	s := NewUserService(repo)
+
+	if err := s.Bootstrap([]string{" Admin@Example.com "}); err != nil {
+		t.Fatal(err)
+	}
+	admin, err := s.SignIn(domain.Profile{Email: "ADMIN@example.com", GivenName: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if admin.Email != "admin@example.com" || !admin.HasRole(domain.RoleAdmin) {
+		t.Errorf("signed in as %q with roles %+v, want the bootstrapped admin", admin.Email, admin.Roles)
+	}
+
+	ta, err := s.GrantRole(admin, "  TA@Example.com", domain.RoleTA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ta.Email != "ta@example.com" || !ta.HasRole(domain.RoleTA) {
+		t.Errorf("granted to %q with roles %+v, want ta@example.com as TA", ta.Email, ta.Roles)
+	}
+
+	if len(repo.users) != 2 {
+		t.Errorf("repository has %d users, want 2", len(repo.users))
+	}
+}
+
+func TestSignInUpdatesHostedDomain(t *testing.T) {
+	repo := newUserRepo()
+	s := NewUserService(repo)
+
+	for _, hd := range []string{"", "uni.ac.th", "uni.ac.th", ""} {
+		user, err := s.SignIn(domain.Profile{Email: "student@uni.ac.th", GivenName: "Sam", HD: hd})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.HostedDomain != hd || repo.users["student@uni.ac.th"].HostedDomain != hd {
+			t.Errorf("after signing in with hd %q the user has %q, stored %q", hd, user.HostedDomain, repo.users["student@uni.ac.th"].HostedDomain)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package dto
+
+import "time"
+
+type AssignmentRequest struct {
+	CourseID   uint      `json:"course_id"`
+	Name       string    `json:"name"`
+	OpenAt     time.Time `json:"open_at"`
+	DueAt      time.Time `json:"due_at"`
+	CloseAt    time.Time `json:"close_at"`
+	LatePolicy string    `json:"late_policy"`
+	// LatePenalty is a fraction of the score, e.g. 0.1 for 10%
+	LatePenalty float64 `json:"late_penalty"`
+	ProblemIDs  []uint  `json:"problem_ids"`
+}
+
+type AssignmentExtensionRequest struct {
+	DueAt   time.Time `json:"due_at"`
+	CloseAt time.Time `json:"close_at"`
+}
+
+type AssignmentScoresResponse struct {
+	AssignmentID uint                     `json:"assignment_id"`
+	ProblemIDs   []uint                   `json:"problem_ids"`
+	Students     []AssignmentStudentScore `json:"students"`
+}
+
+type AssignmentStudentScore struct {
+	Email    string                   `json:"email"`
+	Name     string                   `json:"name"`
+	Total    float64                  `json:"total"`
+	Problems []AssignmentProblemScore `json:"problems"`
+}
+
+type AssignmentProblemScore struct {
+	ProblemID uint `json:"problem_id"`
+	// Score is after the late penalty, RawScore before it
+	Score        float64 `json:"score"`
+	RawScore     float64 `json:"raw_score"`
+	MaxScore     float64 `json:"max_score"`
+	SubmissionID *uint   `json:"submission_id"`
+	Late         bool    `json:"late"`
+	// Submissions counts every attempt, graded or not
+	Submissions int `json:"submissions"`
+}
+
+// GradebookQuery picks the format and columns of a gradebook export. Columns is a comma separated
+// list of student_id, email, name, score, submissions, late and total; all of them by default.
+type GradebookQuery struct {
+	Format  string `query:"format"`
+	Columns string `query:"columns"`
+}
@@ -0,0 +1,47 @@
+package dto
+
+import "time"
+
+type ContestRequest struct {
+	Name           string    `json:"name"`
+	CourseID       *uint     `json:"course_id"`
+	Style          string    `json:"style"`
+	StartAt        time.Time `json:"start_at"`
+	EndAt          time.Time `json:"end_at"`
+	FreezeMinutes  int       `json:"freeze_minutes"`
+	PenaltyMinutes *int      `json:"penalty_minutes"`
+	ProblemIDs     []uint    `json:"problem_ids"`
+}
+
+type ScoreboardResponse struct {
+	ContestID uint   `json:"contest_id"`
+	Style     string `json:"style"`
+	// Frozen is set when results of submissions made after FreezeAt are hidden
+	Frozen      bool            `json:"frozen"`
+	FreezeAt    time.Time       `json:"freeze_at"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	ProblemIDs  []uint          `json:"problem_ids"`
+	Rows        []ScoreboardRow `json:"rows"`
+}
+
+type ScoreboardRow struct {
+	Rank  int    `json:"rank"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	// Solved and Penalty are the ICPC ranking, Score the IOI one
+	Solved   int              `json:"solved"`
+	Penalty  int              `json:"penalty"`
+	Score    float64          `json:"score"`
+	Problems []ScoreboardCell `json:"problems"`
+}
+
+type ScoreboardCell struct {
+	ProblemID uint `json:"problem_id"`
+	// Attempts counts the attempts up to and including the first accepted one
+	Attempts int  `json:"attempts"`
+	Pending  int  `json:"pending"`
+	Solved   bool `json:"solved"`
+	// SolvedAt is in minutes since the start of the contest
+	SolvedAt *int    `json:"solved_at"`
+	Score    float64 `json:"score"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+type CourseRequest struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type JoinCourseRequest struct {
+	InviteCode string `json:"invite_code"`
+}
+
+type RosterImportResponse struct {
+	Imported int                 `json:"imported"`
+	Errors   []RosterImportError `json:"errors"`
+}
+
+type RosterImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
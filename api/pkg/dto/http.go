@@ -13,6 +13,12 @@ type PaginationResponse[T any] struct {
 	Pagination Pagination `json:"pagination"`
 }
 
+// CursorResponse is a page of a listing that continues from NextCursor, which is empty on the last page.
+type CursorResponse[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor"`
+}
+
 type Pagination struct {
 	CurrentPage int `json:"current_page"`
 	LastPage    int `json:"last_page"`
@@ -27,3 +33,7 @@ func Success[T any](data T) SuccessResponse[T] {
 func SuccessPagination[T any](data []T, pagination Pagination) PaginationResponse[T] {
 	return PaginationResponse[T]{Data: data, Pagination: pagination}
 }
+
+func SuccessCursor[T any](data []T, nextCursor string) CursorResponse[T] {
+	return CursorResponse[T]{Data: data, NextCursor: nextCursor}
+}
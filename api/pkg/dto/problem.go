@@ -1,9 +1,73 @@
 package dto
 
+import "time"
+
+// ProblemRequestFrom creates a problem. Language and EditableFile may be left out when the zip has a problem.yaml.
 type ProblemRequestFrom struct {
-	Name         string   `form:"name" validate:"required,min=2,max=40"`
-	Description  string   `form:"description" validate:"required,omitempty"`
-	Language     []string `form:"language" validate:"required,min=1"`
-	TestcaseNum  uint     `form:"testcase_num" validate:"required,min=1"`
-	EditableFile []string `form:"editable_file" validate:"required"`
+	Name            string   `form:"name" validate:"required,min=2,max=40"`
+	Description     string   `form:"description" validate:"required,omitempty"`
+	Language        []string `form:"language" validate:"required,min=1"`
+	EditableFile    []string `form:"editable_file" validate:"required"`
+	HideTestDetails bool     `form:"hide_test_details"`
+	ScoringPolicy   string   `form:"scoring_policy"`
+	// CourseID leaves the problem open to every user when empty
+	CourseID *uint `form:"course_id"`
+}
+
+// ProblemUpdateForm changes only the fields that are sent.
+// Sending a new zip without editable_file keeps the current editable file names.
+type ProblemUpdateForm struct {
+	Name            *string  `form:"name" json:"name"`
+	Description     *string  `form:"description" json:"description"`
+	Language        []string `form:"language" json:"language"`
+	EditableFile    []string `form:"editable_file" json:"editable_file"`
+	HideTestDetails *bool    `form:"hide_test_details" json:"hide_test_details"`
+	ScoringPolicy   *string  `form:"scoring_policy" json:"scoring_policy"`
+	// CourseID of 0 takes the problem out of its course
+	CourseID *uint `form:"course_id" json:"course_id"`
+}
+
+type RegradeRequest struct {
+	// Version defaults to the current version of the problem
+	Version uint `json:"version"`
+}
+
+type RegradeResponse struct {
+	Version     uint `json:"version"`
+	Submissions int  `json:"submissions"`
+	// Skipped counts the submissions left out because they were being graded
+	Skipped int `json:"skipped"`
+}
+
+type ProblemScoringRequest struct {
+	ScoringPolicy string                   `json:"scoring_policy"`
+	Testcases     []ProblemTestcaseScoring `json:"testcases"`
+}
+
+type ProblemTestcaseScoring struct {
+	ID     uint    `json:"id"`
+	Weight float64 `json:"weight"`
+	Group  string  `json:"group"`
+}
+
+type TemplateFileResponse struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ProblemValidationResponse is how the reference solution of a problem did, with a link to its simulator output.
+type ProblemValidationResponse struct {
+	Status       string                `json:"status"`
+	SubmissionID *uint                 `json:"submission_id"`
+	Verdict      string                `json:"verdict,omitempty"`
+	Testcases    []TestcaseResultEvent `json:"testcases"`
+	OutputURL    string                `json:"output_url,omitempty"`
+}
+
+type ProblemVisibilityRequest struct {
+	Visibility string `json:"visibility"`
+	// PublishAt is required for a scheduled problem
+	PublishAt *time.Time `json:"publish_at"`
+	CloseAt   *time.Time `json:"close_at"`
 }
@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type SubmissionRequest struct {
 	Language  string     `json:"language"`
 	ProblemID uint       `json:"problem_id"`
@@ -10,3 +12,85 @@ type CodeFile struct {
 	Code             string `json:"code"`
 	TemplateFileName string `json:"template_name"`
 }
+
+type SubmissionEvent struct {
+	Event string
+	Data  any
+}
+
+type SubmissionStatusEvent struct {
+	Status   string  `json:"status"`
+	Verdict  string  `json:"verdict"`
+	Score    float64 `json:"score"`
+	MaxScore float64 `json:"max_score"`
+}
+
+// SubmissionStageEvent tells which step of grading the grader is at, e.g. STAGE_RUNNING_TESTS.
+type SubmissionStageEvent struct {
+	Stage string `json:"stage"`
+}
+
+type TestcaseResultEvent struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Result  string `json:"result"`
+	Message string `json:"message,omitempty"`
+}
+
+// SubmissionSearchQuery filters GET /admin/submissions. Times are RFC 3339; Sort is created_at or score,
+// prefixed with - for descending, which is the default.
+type SubmissionSearchQuery struct {
+	ProblemID uint     `query:"problem_id"`
+	Email     string   `query:"email"`
+	Verdict   string   `query:"verdict"`
+	Language  string   `query:"language"`
+	From      string   `query:"from"`
+	To        string   `query:"to"`
+	MinScore  *float64 `query:"min_score"`
+	MaxScore  *float64 `query:"max_score"`
+	Sort      string   `query:"sort"`
+	Cursor    string   `query:"cursor"`
+	Limit     int      `query:"limit"`
+}
+
+// SubmissionSourceResponse is what a submission was graded on and what the simulator printed.
+type SubmissionSourceResponse struct {
+	Files  []SubmissionSourceFile `json:"files"`
+	Stdout string                 `json:"stdout"`
+	// StdoutTruncated is set when the output was cut at the size limit
+	StdoutTruncated bool `json:"stdout_truncated"`
+}
+
+type SubmissionSourceFile struct {
+	Name      string `json:"name"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated"`
+}
+
+// SubmissionDetailResponse is a submission with its results and short-lived links to its files.
+type SubmissionDetailResponse struct {
+	ID            uint                   `json:"id"`
+	ProblemID     uint                   `json:"problem_id"`
+	SubmissionBy  string                 `json:"submission_by"`
+	Language      string                 `json:"language"`
+	Status        string                 `json:"status"`
+	Verdict       string                 `json:"verdict"`
+	Score         float64                `json:"score"`
+	MaxScore      float64                `json:"max_score"`
+	RunTimeMs     uint                   `json:"run_time_ms"`
+	MemoryUsageMB uint                   `json:"memory_usage_mb"`
+	CreatedAt     time.Time              `json:"created_at"`
+	Files         []TemplateFileResponse `json:"files"`
+	Testcases     []TestcaseResultEvent  `json:"testcases"`
+	Diagnostics   []DiagnosticResponse   `json:"diagnostics"`
+	// StdoutURL is empty until the grader has uploaded the simulator output
+	StdoutURL string    `json:"stdout_url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type DiagnosticResponse struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
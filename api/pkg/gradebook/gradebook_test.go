@@ -0,0 +1,145 @@
+package gradebook
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"alice@uni.ac.th", "alice@uni.ac.th"},
+		{"", ""},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1", "'+1"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := escapeFormula(tt.in); got != tt.want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+var testTable = Table{
+	Header: []string{"email", "name", "score", "late", "=header"},
+	Rows: [][]any{
+		{"a@uni.ac.th", "=cmd|' /C calc'!A0", 87.5, true, -3},
+		{"b@uni.ac.th", "Bob <& Co>", 0, false, "-3"},
+	},
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testTable.Write(&buf, FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	got, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"email", "name", "score", "late", "'=header"},
+		// Numbers stay numbers, even negative ones
+		{"a@uni.ac.th", "'=cmd|' /C calc'!A0", "87.5", "true", "-3"},
+		{"b@uni.ac.th", "Bob <& Co>", "0", "false", "'-3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// sheet is the part of a worksheet the test reads back.
+type sheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestWriteXLSXRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testTable.Write(&buf, FormatXLSX); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every part must be well-formed XML
+		var doc struct{}
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			t.Errorf("%s: %v", f.Name, err)
+		}
+		parts[f.Name] = data
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	var s sheet
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &s); err != nil {
+		t.Fatal(err)
+	}
+	type cell struct{ ref, typ, value string }
+	var got [][]cell
+	for i, row := range s.Rows {
+		if row.R != i+1 {
+			t.Errorf("row %d is numbered %d", i, row.R)
+		}
+		var cells []cell
+		for _, c := range row.Cells {
+			value := c.Value
+			if c.Type == "inlineStr" {
+				value = c.Inline
+			}
+			cells = append(cells, cell{c.Ref, c.Type, value})
+		}
+		got = append(got, cells)
+	}
+	want := [][]cell{
+		{{"A1", "inlineStr", "email"}, {"B1", "inlineStr", "name"}, {"C1", "inlineStr", "score"}, {"D1", "inlineStr", "late"}, {"E1", "inlineStr", "'=header"}},
+		{{"A2", "inlineStr", "a@uni.ac.th"}, {"B2", "inlineStr", "'=cmd|' /C calc'!A0"}, {"C2", "", "87.5"}, {"D2", "b", "1"}, {"E2", "", "-3"}},
+		{{"A3", "inlineStr", "b@uni.ac.th"}, {"B3", "inlineStr", "Bob <& Co>"}, {"C3", "", "0"}, {"D3", "b", "0"}, {"E3", "inlineStr", "'-3"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
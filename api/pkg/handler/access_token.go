@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/service"
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+)
+
+type AccessTokenHandler struct {
+	tokenService *service.AccessTokenService
+}
+
+func NewAccessTokenHandler(tokenService *service.AccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{tokenService: tokenService}
+}
+
+func (h *AccessTokenHandler) Create(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	body := new(dto.AccessTokenRequest)
+	if err := c.BodyParser(body); err != nil {
+		return apperror.BadRequestError(err, "invalid request body")
+	}
+	plain, token, err := h.tokenService.Create(user, body.Name, body.Scopes, body.ExpiresInDays)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "create token error")
+	}
+	return c.Status(201).JSON(dto.Success(dto.CreatedAccessTokenResponse{
+		AccessTokenResponse: token.ToDTO(),
+		Token:               plain,
+	}))
+}
+
+func (h *AccessTokenHandler) GetTokens(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	tokens, err := h.tokenService.GetTokens(user)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get tokens error")
+	}
+	res := make([]dto.AccessTokenResponse, len(tokens))
+	for i, token := range tokens {
+		res[i] = token.ToDTO()
+	}
+	return c.JSON(dto.Success(res))
+}
+
+func (h *AccessTokenHandler) Revoke(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "bad request error")
+	}
+	if err := h.tokenService.Revoke(user, uint(id)); err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "revoke token error")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
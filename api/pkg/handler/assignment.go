@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"bytes"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/service"
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+	"github.com/yokeTH/our-grader-backend/api/pkg/gradebook"
+)
+
+type AssignmentHandler struct {
+	assignmentService *service.AssignmentService
+}
+
+func NewAssignmentHandler(assignmentService *service.AssignmentService) *AssignmentHandler {
+	return &AssignmentHandler{assignmentService: assignmentService}
+}
+
+func (h *AssignmentHandler) Create(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	body := new(dto.AssignmentRequest)
+	if err := c.BodyParser(body); err != nil {
+		return apperror.BadRequestError(err, "invalid request body")
+	}
+	assignment, err := h.assignmentService.Create(user, *body)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "create assignment error")
+	}
+	return c.Status(201).JSON(dto.Success(assignment))
+}
+
+func (h *AssignmentHandler) Get(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid assignment ID")
+	}
+	assignment, err := h.assignmentService.Get(user, uint(id))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get assignment error")
+	}
+	return c.JSON(dto.Success(assignment))
+}
+
+func (h *AssignmentHandler) GetByCourse(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid course ID")
+	}
+	assignments, err := h.assignmentService.GetByCourse(user, uint(id))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get assignments error")
+	}
+	return c.JSON(dto.Success(assignments))
+}
+
+func (h *AssignmentHandler) Update(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid assignment ID")
+	}
+	body := new(dto.AssignmentRequest)
+	if err := c.BodyParser(body); err != nil {
+		return apperror.BadRequestError(err, "invalid request body")
+	}
+	assignment, err := h.assignmentService.Update(user, uint(id), *body)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "update assignment error")
+	}
+	return c.JSON(dto.Success(assignment))
+}
+
+func (h *AssignmentHandler) Delete(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid assignment ID")
+	}
+	if err := h.assignmentService.Delete(user, uint(id)); err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "delete assignment error")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *AssignmentHandler) SetExtension(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid assignment ID")
+	}
+	body := new(dto.AssignmentExtensionRequest)
+	if err := c.BodyParser(body); err != nil {
+		return apperror.BadRequestError(err, "invalid request body")
+	}
+	ext, err := h.assignmentService.SetExtension(user, uint(id), c.Params("email"), *body)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "set extension error")
+	}
+	return c.JSON(dto.Success(ext))
+}
+
+func (h *AssignmentHandler) RemoveExtension(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid assignment ID")
+	}
+	if err := h.assignmentService.RemoveExtension(user, uint(id), c.Params("email")); err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "remove extension error")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *AssignmentHandler) Scores(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid assignment ID")
+	}
+	scores, err := h.assignmentService.Scores(user, uint(id))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get scores error")
+	}
+	return c.JSON(dto.Success(scores))
+}
+
+func (h *AssignmentHandler) Export(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid assignment ID")
+	}
+	query := new(dto.GradebookQuery)
+	if err := c.QueryParser(query); err != nil {
+		return apperror.BadRequestError(err, "invalid query")
+	}
+	table, format, filename, err := h.assignmentService.ExportAssignment(user, uint(id), *query)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "export gradebook error")
+	}
+	return sendGradebook(c, table, format, filename)
+}
+
+func (h *AssignmentHandler) ExportCourse(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid course ID")
+	}
+	query := new(dto.GradebookQuery)
+	if err := c.QueryParser(query); err != nil {
+		return apperror.BadRequestError(err, "invalid query")
+	}
+	table, format, filename, err := h.assignmentService.ExportCourse(user, uint(id), *query)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "export gradebook error")
+	}
+	return sendGradebook(c, table, format, filename)
+}
+
+func sendGradebook(c *fiber.Ctx, table gradebook.Table, format gradebook.Format, filename string) error {
+	var buf bytes.Buffer
+	if err := table.Write(&buf, format); err != nil {
+		return apperror.InternalServerError(err, "write gradebook error")
+	}
+	c.Attachment(filename)
+	c.Set(fiber.HeaderContentType, format.ContentType())
+	return c.Send(buf.Bytes())
+}
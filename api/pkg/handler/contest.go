@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/service"
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+)
+
+type ContestHandler struct {
+	contestService *service.ContestService
+}
+
+func NewContestHandler(contestService *service.ContestService) *ContestHandler {
+	return &ContestHandler{contestService: contestService}
+}
+
+func (h *ContestHandler) Create(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	body := new(dto.ContestRequest)
+	if err := c.BodyParser(body); err != nil {
+		return apperror.BadRequestError(err, "invalid request body")
+	}
+	contest, err := h.contestService.Create(user, *body)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "create contest error")
+	}
+	return c.Status(201).JSON(dto.Success(contest))
+}
+
+func (h *ContestHandler) GetContests(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	contests, err := h.contestService.GetContests(user)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get contests error")
+	}
+	return c.JSON(dto.Success(contests))
+}
+
+func (h *ContestHandler) Get(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid contest ID")
+	}
+	contest, err := h.contestService.Get(user, uint(id))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get contest error")
+	}
+	return c.JSON(dto.Success(contest))
+}
+
+func (h *ContestHandler) Update(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid contest ID")
+	}
+	body := new(dto.ContestRequest)
+	if err := c.BodyParser(body); err != nil {
+		return apperror.BadRequestError(err, "invalid request body")
+	}
+	contest, err := h.contestService.Update(user, uint(id), *body)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "update contest error")
+	}
+	return c.JSON(dto.Success(contest))
+}
+
+func (h *ContestHandler) Delete(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid contest ID")
+	}
+	if err := h.contestService.Delete(user, uint(id)); err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "delete contest error")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *ContestHandler) Register(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid contest ID")
+	}
+	if err := h.contestService.Register(user, uint(id)); err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "register error")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *ContestHandler) AddParticipant(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid contest ID")
+	}
+	participant, err := h.contestService.AddParticipant(user, uint(id), c.Params("email"))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "add participant error")
+	}
+	return c.JSON(dto.Success(participant))
+}
+
+func (h *ContestHandler) RemoveParticipant(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid contest ID")
+	}
+	if err := h.contestService.RemoveParticipant(user, uint(id), c.Params("email")); err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "remove participant error")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Scoreboard lets clients cache the scoreboard for as long as the service does. The cache is private
+// since staff and participants see different scoreboards while it is frozen.
+func (h *ContestHandler) Scoreboard(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid contest ID")
+	}
+	board, err := h.contestService.Scoreboard(user, uint(id))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get scoreboard error")
+	}
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int(service.ScoreboardCacheTTL.Seconds())))
+	return c.JSON(dto.Success(board))
+}
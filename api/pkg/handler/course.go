@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/service"
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+)
+
+type CourseHandler struct {
+	courseService *service.CourseService
+}
+
+func NewCourseHandler(courseService *service.CourseService) *CourseHandler {
+	return &CourseHandler{courseService: courseService}
+}
+
+func (h *CourseHandler) Create(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	body := new(dto.CourseRequest)
+	if err := c.BodyParser(body); err != nil {
+		return apperror.BadRequestError(err, "invalid request body")
+	}
+	course, err := h.courseService.Create(user, *body)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "create course error")
+	}
+	return c.Status(201).JSON(dto.Success(course))
+}
+
+func (h *CourseHandler) GetCourses(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	courses, err := h.courseService.GetCourses(user)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get courses error")
+	}
+	return c.JSON(dto.Success(courses))
+}
+
+func (h *CourseHandler) Join(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	body := new(dto.JoinCourseRequest)
+	if err := c.BodyParser(body); err != nil {
+		return apperror.BadRequestError(err, "invalid request body")
+	}
+	course, err := h.courseService.Join(user, body.InviteCode)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "join course error")
+	}
+	return c.JSON(dto.Success(course))
+}
+
+func (h *CourseHandler) RotateInviteCode(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid course ID")
+	}
+	course, err := h.courseService.RotateInviteCode(user, uint(id))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "rotate invite code error")
+	}
+	return c.JSON(dto.Success(course))
+}
+
+func (h *CourseHandler) GetMembers(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid course ID")
+	}
+	members, err := h.courseService.GetMembers(user, uint(id))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get members error")
+	}
+	return c.JSON(dto.Success(members))
+}
+
+func (h *CourseHandler) SetMember(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid course ID")
+	}
+	member, err := h.courseService.SetMember(user, uint(id), c.Params("email"), domain.Role(strings.ToUpper(c.Params("role"))))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "set member error")
+	}
+	return c.JSON(dto.Success(member))
+}
+
+func (h *CourseHandler) RemoveMember(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid course ID")
+	}
+	if err := h.courseService.RemoveMember(user, uint(id), c.Params("email")); err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "remove member error")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ImportRoster takes the CSV either as the "roster" file of a multipart form or as the request body.
+func (h *CourseHandler) ImportRoster(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid course ID")
+	}
+
+	var roster io.Reader = bytes.NewReader(c.Body())
+	if file, err := c.FormFile("roster"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return apperror.BadRequestError(err, "can't open roster")
+		}
+		defer f.Close()
+		roster = f
+	}
+
+	res, err := h.courseService.ImportRoster(user, uint(id), roster)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "import roster error")
+	}
+	return c.JSON(dto.Success(res))
+}
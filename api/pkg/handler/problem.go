@@ -2,9 +2,11 @@ package handler
 
 import (
 	"math"
+	"mime/multipart"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/port"
 	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
 )
@@ -26,7 +28,8 @@ func (h *ProblemHandler) CreateProblem(ctx *fiber.Ctx) error {
 	if err := ctx.BodyParser(body); err != nil {
 		return apperror.BadRequestError(err, "invalid request body")
 	}
-	problem, err := h.problemService.CreateProblem(ctx.Context(), *body, zipFile)
+	profile := ctx.Locals("profile").(domain.Profile)
+	problem, err := h.problemService.CreateProblem(ctx.Context(), profile.Email, *body, zipFile)
 	if err != nil {
 		if apperror.IsAppError(err) {
 			return err
@@ -37,9 +40,10 @@ func (h *ProblemHandler) CreateProblem(ctx *fiber.Ctx) error {
 }
 
 func (h *ProblemHandler) GetProblems(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
 	limit := math.Min(float64(c.QueryInt("limit", 10)), 50)
 	page := c.QueryInt("limit", 1)
-	problems, last, total, err := h.problemService.GetProblems(int(limit), page)
+	problems, last, total, err := h.problemService.GetProblems(user, int(limit), page)
 	if err != nil {
 		return err
 	}
@@ -52,11 +56,12 @@ func (h *ProblemHandler) GetProblems(c *fiber.Ctx) error {
 }
 
 func (h *ProblemHandler) GetProblemByID(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(domain.User)
 	id, err := ctx.ParamsInt("id")
 	if err != nil {
 		return apperror.BadRequestError(err, "bad request error")
 	}
-	problem, err := h.problemService.GetProblemByID(uint(id))
+	problem, err := h.problemService.GetProblemByID(user, uint(id))
 	if err != nil {
 		if apperror.IsAppError(err) {
 			return err
@@ -66,10 +71,133 @@ func (h *ProblemHandler) GetProblemByID(ctx *fiber.Ctx) error {
 	return ctx.JSON(dto.Success(problem))
 }
 
+func (h *ProblemHandler) GetTemplates(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(domain.User)
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "bad request error")
+	}
+	templates, err := h.problemService.GetTemplates(ctx.Context(), user, uint(id))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get templates error")
+	}
+	return ctx.JSON(dto.Success(templates))
+}
+
+func (h *ProblemHandler) GetValidation(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "bad request error")
+	}
+	validation, err := h.problemService.GetValidation(ctx.Context(), uint(id))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get validation error")
+	}
+	return ctx.JSON(dto.Success(validation))
+}
+
 func (h *ProblemHandler) UpdateProblem(ctx *fiber.Ctx) error {
-	return nil
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "bad request error")
+	}
+	body := new(dto.ProblemUpdateForm)
+	if err := ctx.BodyParser(body); err != nil {
+		return apperror.BadRequestError(err, "invalid request body")
+	}
+
+	// The project zip is optional when only metadata changes
+	var zipFile *multipart.FileHeader
+	if form, err := ctx.MultipartForm(); err == nil && len(form.File["zip"]) > 0 {
+		zipFile = form.File["zip"][0]
+	}
+
+	profile := ctx.Locals("profile").(domain.Profile)
+	problem, err := h.problemService.UpdateProblem(ctx.Context(), profile.Email, uint(id), *body, zipFile)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "update problem error")
+	}
+	return ctx.JSON(dto.Success(problem))
 }
 
 func (h *ProblemHandler) DeleteProblem(ctx *fiber.Ctx) error {
-	return nil
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "bad request error")
+	}
+	if err := h.problemService.DeleteProblem(ctx.Context(), uint(id)); err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "delete problem error")
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *ProblemHandler) UpdateScoring(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "bad request error")
+	}
+	body := new(dto.ProblemScoringRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return apperror.BadRequestError(err, "invalid request body")
+	}
+	problem, err := h.problemService.UpdateScoring(uint(id), *body)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "update scoring error")
+	}
+	return ctx.JSON(dto.Success(problem))
+}
+
+func (h *ProblemHandler) UpdateVisibility(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "bad request error")
+	}
+	body := new(dto.ProblemVisibilityRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return apperror.BadRequestError(err, "invalid request body")
+	}
+	problem, err := h.problemService.UpdateVisibility(uint(id), *body)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "update visibility error")
+	}
+	return ctx.JSON(dto.Success(problem))
+}
+
+func (h *ProblemHandler) Regrade(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "bad request error")
+	}
+	body := new(dto.RegradeRequest)
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(body); err != nil {
+			return apperror.BadRequestError(err, "invalid request body")
+		}
+	}
+	result, err := h.problemService.Regrade(uint(id), body.Version)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "regrade error")
+	}
+	return ctx.Status(fiber.StatusAccepted).JSON(dto.Success(result))
 }
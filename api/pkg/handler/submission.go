@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"bufio"
 	"errors"
+	"fmt"
 	"math"
 
+	"github.com/goccy/go-json"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
 	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/service"
@@ -21,16 +25,64 @@ func NewSubmissionHandler(ps *service.SubmissionService) *SubmissionHandler {
 
 func (h *SubmissionHandler) Submit(c *fiber.Ctx) error {
 	body := new(dto.SubmissionRequest)
-	profile := c.Locals("profile").(domain.Profile)
+	user := c.Locals("user").(domain.User)
 	if err := c.BodyParser(body); err != nil {
 		return apperror.BadRequestError(errors.New("request body invalid"), "request body invalid")
 	}
 
-	if err := h.problemService.Create(c.Context(), profile.Email, *body); err != nil {
+	submission, err := h.problemService.Create(c.Context(), user, *body)
+	if err != nil {
 		return err
 	}
 
-	return c.Status(201).JSON(fiber.Map{"success": true})
+	return c.Status(201).JSON(dto.Success(submission))
+}
+
+// Events streams status and testcase updates of a submission as Server-Sent Events until it is graded.
+func (h *SubmissionHandler) Events(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid submission ID")
+	}
+
+	submission, err := h.problemService.GetSubmissionByID(uint(id))
+	if err != nil {
+		return err
+	}
+	canView, err := h.problemService.CanView(user, submission)
+	if err != nil {
+		return err
+	}
+	if !canView {
+		return apperror.ForbiddenError(errors.New("submission belongs to another user"), "you can't watch this submission")
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	// The stream writer runs after this handler returns, so it must not touch c. The request context lives
+	// until the stream is written and ends with the server.
+	ctx := c.Context()
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		err := h.problemService.Watch(ctx, submission.ID, func(ev dto.SubmissionEvent) error {
+			data, err := json.Marshal(ev.Data)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Event, data); err != nil {
+				return err
+			}
+			return w.Flush()
+		})
+		if err != nil {
+			log.Infof("submission %d event stream closed: %v", submission.ID, err)
+		}
+	})
+
+	return nil
 }
 
 func (h *SubmissionHandler) GetSubmissions(c *fiber.Ctx) error {
@@ -54,3 +106,62 @@ func (h *SubmissionHandler) GetSubmissions(c *fiber.Ctx) error {
 		Total:       total,
 	}))
 }
+
+// SearchSubmissions lists every submission for staff, see dto.SubmissionSearchQuery for the filters.
+func (h *SubmissionHandler) SearchSubmissions(c *fiber.Ctx) error {
+	query := new(dto.SubmissionSearchQuery)
+	if err := c.QueryParser(query); err != nil {
+		return apperror.BadRequestError(err, "invalid query")
+	}
+	submissions, next, err := h.problemService.SearchSubmissions(*query)
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "search submissions error")
+	}
+	return c.JSON(dto.SuccessCursor(submissions, next))
+}
+
+func (h *SubmissionHandler) GetSubmissionDetails(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid submission ID")
+	}
+	submission, err := h.problemService.GetSubmissionWithDetails(uint(id))
+	if err != nil {
+		return err
+	}
+	return c.JSON(dto.Success(submission))
+}
+
+func (h *SubmissionHandler) GetSource(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid submission ID")
+	}
+	source, err := h.problemService.GetSource(c.Context(), uint(id))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get source error")
+	}
+	return c.JSON(dto.Success(source))
+}
+
+func (h *SubmissionHandler) GetSubmission(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return apperror.BadRequestError(err, "invalid submission ID")
+	}
+	submission, err := h.problemService.GetDetail(c.Context(), user, uint(id))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "get submission error")
+	}
+	return c.JSON(dto.Success(submission))
+}
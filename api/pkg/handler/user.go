@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"math"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/service"
+	"github.com/yokeTH/our-grader-backend/api/pkg/dto"
+)
+
+type UserHandler struct {
+	userService *service.UserService
+}
+
+func NewUserHandler(userService *service.UserService) *UserHandler {
+	return &UserHandler{userService: userService}
+}
+
+func (h *UserHandler) Me(c *fiber.Ctx) error {
+	user := c.Locals("user").(domain.User)
+	return c.JSON(dto.Success(user))
+}
+
+func (h *UserHandler) GetUsers(c *fiber.Ctx) error {
+	limit := math.Min(float64(c.QueryInt("limit", 10)), 50)
+	page := c.QueryInt("page", 1)
+	users, last, total, err := h.userService.GetUsers(int(limit), page)
+	if err != nil {
+		return apperror.InternalServerError(err, "get users error")
+	}
+	return c.JSON(dto.SuccessPagination(users, dto.Pagination{
+		CurrentPage: page,
+		LastPage:    last,
+		Total:       total,
+		Limit:       int(limit),
+	}))
+}
+
+func (h *UserHandler) GrantRole(c *fiber.Ctx) error {
+	by := c.Locals("user").(domain.User)
+	user, err := h.userService.GrantRole(by, c.Params("email"), domain.Role(strings.ToUpper(c.Params("role"))))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "grant role error")
+	}
+	return c.JSON(dto.Success(user))
+}
+
+func (h *UserHandler) RevokeRole(c *fiber.Ctx) error {
+	by := c.Locals("user").(domain.User)
+	user, err := h.userService.RevokeRole(by, c.Params("email"), domain.Role(strings.ToUpper(c.Params("role"))))
+	if err != nil {
+		if apperror.IsAppError(err) {
+			return err
+		}
+		return apperror.InternalServerError(err, "revoke role error")
+	}
+	return c.JSON(dto.Success(user))
+}
@@ -0,0 +1,271 @@
+package idtoken
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testAudience = "client.apps.googleusercontent.com"
+
+// fakeIssuer serves a key set over HTTP and signs tokens with its keys.
+type fakeIssuer struct {
+	t        *testing.T
+	keys     map[string]*rsa.PrivateKey
+	requests atomic.Int32
+	// block, when set, holds every key set request until it is closed
+	block chan struct{}
+	srv   *httptest.Server
+}
+
+func newFakeIssuer(t *testing.T, kids ...string) *fakeIssuer {
+	t.Helper()
+	f := &fakeIssuer{t: t, keys: make(map[string]*rsa.PrivateKey)}
+	for _, kid := range kids {
+		f.addKey(kid)
+	}
+	f.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.requests.Add(1)
+		if f.block != nil {
+			<-f.block
+		}
+		var set jwks
+		for kid, key := range f.keys {
+			set.Keys = append(set.Keys, jwk{
+				Kid: kid,
+				Kty: "RSA",
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		w.Header().Set("Cache-Control", "public, max-age=600")
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeIssuer) addKey(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.keys[kid] = key
+}
+
+func (f *fakeIssuer) sign(kid string, alg string, claims map[string]any) string {
+	f.t.Helper()
+	segment := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, f.keys[kid], crypto.SHA256, digest[:])
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims(now time.Time) map[string]any {
+	return map[string]any{
+		"iss":            "https://accounts.google.com",
+		"aud":            testAudience,
+		"sub":            "1234",
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"email":          "student@example.com",
+		"email_verified": "true",
+		"hd":             "example.com",
+	}
+}
+
+func TestVerify(t *testing.T) {
+	issuer := newFakeIssuer(t, "current", "other")
+	now := time.Unix(1_700_000_000, 0)
+	verifier := NewVerifier(NewJWKSSource(issuer.srv.URL), WithAudience(testAudience), WithClock(func() time.Time { return now }))
+
+	with := func(key string, value any) map[string]any {
+		claims := validClaims(now)
+		claims[key] = value
+		return claims
+	}
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr error
+	}{
+		{name: "valid", token: func() string { return issuer.sign("current", "RS256", validClaims(now)) }},
+		{name: "audience list", token: func() string {
+			return issuer.sign("current", "RS256", with("aud", []string{"someone-else", testAudience}))
+		}},
+		{name: "other key of the set", token: func() string { return issuer.sign("other", "RS256", validClaims(now)) }},
+		{name: "expired", token: func() string {
+			return issuer.sign("current", "RS256", with("exp", now.Add(-time.Minute).Unix()))
+		}, wantErr: ErrExpired},
+		{name: "expired within leeway", token: func() string {
+			return issuer.sign("current", "RS256", with("exp", now.Add(-10*time.Second).Unix()))
+		}},
+		{name: "missing expiry", token: func() string { return issuer.sign("current", "RS256", with("exp", 0)) }, wantErr: ErrExpired},
+		{name: "not yet valid", token: func() string {
+			return issuer.sign("current", "RS256", with("nbf", now.Add(time.Hour).Unix()))
+		}, wantErr: ErrNotYetValid},
+		{name: "other audience", token: func() string {
+			return issuer.sign("current", "RS256", with("aud", "someone-else"))
+		}, wantErr: ErrInvalidAudience},
+		{name: "other issuer", token: func() string {
+			return issuer.sign("current", "RS256", with("iss", "https://evil.example.com"))
+		}, wantErr: ErrInvalidIssuer},
+		{name: "unsupported algorithm", token: func() string {
+			return issuer.sign("current", "RS512", validClaims(now))
+		}, wantErr: ErrUnsupportedAlg},
+		{name: "unknown key", token: func() string {
+			token := issuer.sign("current", "RS256", validClaims(now))
+			header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"missing"}`))
+			return header + token[strings.Index(token, "."):]
+		}, wantErr: ErrUnknownKey},
+		{name: "tampered claims", token: func() string {
+			token := issuer.sign("current", "RS256", validClaims(now))
+			forged := issuer.sign("current", "RS256", with("email", "admin@example.com"))
+			return token[:strings.Index(token, ".")] + forged[strings.Index(forged, "."):strings.LastIndex(forged, ".")] + token[strings.LastIndex(token, "."):]
+		}, wantErr: ErrInvalidSignature},
+		{name: "malformed", token: func() string { return "not.a.token" }, wantErr: ErrMalformed},
+		{name: "two segments", token: func() string { return "a.b" }, wantErr: ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), tt.token())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (claims.Email != "student@example.com" || !bool(claims.EmailVerified) || claims.HostedDomain != "example.com") {
+				t.Errorf("unexpected claims %+v", claims)
+			}
+		})
+	}
+}
+
+func TestJWKSSourceCachesKeys(t *testing.T) {
+	issuer := newFakeIssuer(t, "a")
+	source := NewJWKSSource(issuer.srv.URL)
+
+	for range 3 {
+		if _, err := source.Key(context.Background(), "a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := issuer.requests.Load(); n != 1 {
+		t.Errorf("fetched %d times, want 1", n)
+	}
+}
+
+func TestJWKSSourceRotation(t *testing.T) {
+	issuer := newFakeIssuer(t, "a")
+	now := time.Unix(1_700_000_000, 0)
+	source := NewJWKSSource(issuer.srv.URL)
+	source.now = func() time.Time { return now }
+
+	if _, err := source.Key(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	issuer.addKey("b")
+
+	// Unknown keys are only fetched again after a while
+	if _, err := source.Key(context.Background(), "b"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("err = %v, want %v", err, ErrUnknownKey)
+	}
+	now = now.Add(minJWKSRefetch)
+	if _, err := source.Key(context.Background(), "b"); err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+	if n := issuer.requests.Load(); n != 2 {
+		t.Errorf("fetched %d times, want 2", n)
+	}
+}
+
+func TestJWKSSourceServesExpiredKeysWhileRefreshing(t *testing.T) {
+	issuer := newFakeIssuer(t, "a")
+	now := time.Unix(1_700_000_000, 0)
+	source := NewJWKSSource(issuer.srv.URL)
+	source.now = func() time.Time { return now }
+
+	if _, err := source.Key(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	issuer.block = make(chan struct{})
+	now = now.Add(time.Hour)
+	got := make(chan error)
+	go func() {
+		_, err := source.Key(context.Background(), "a")
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expired key waited for the refresh")
+	}
+
+	// A token with an unknown key joins the refresh in flight
+	issuer.addKey("b")
+	go func() {
+		_, err := source.Key(context.Background(), "b")
+		got <- err
+	}()
+	close(issuer.block)
+	if err := <-got; err != nil {
+		t.Fatalf("key of the refreshed set: %v", err)
+	}
+	if n := issuer.requests.Load(); n != 2 {
+		t.Errorf("fetched %d times, want 2", n)
+	}
+}
+
+func TestJWKSSourceFirstFetchFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := NewJWKSSource(srv.URL).Key(context.Background(), "a")
+	if err == nil || errors.Is(err, ErrUnknownKey) {
+		t.Errorf("err = %v, want the fetch error", err)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	tests := []struct {
+		cacheControl string
+		want         time.Duration
+	}{
+		{"public, max-age=19845, must-revalidate, no-transform", 19845 * time.Second},
+		{"max-age=0", defaultJWKSRefresh},
+		{"no-cache", defaultJWKSRefresh},
+		{"", defaultJWKSRefresh},
+	}
+	for _, tt := range tests {
+		if got := maxAge(tt.cacheControl); got != tt.want {
+			t.Errorf("maxAge(%q) = %v, want %v", tt.cacheControl, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package idtoken
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// GoogleCertsURL serves the keys Google signs its ID tokens with.
+const GoogleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+const defaultJWKSRefresh = time.Hour
+
+// Keys fetched for an unknown key ID are rate limited, an attacker could otherwise make us hammer the issuer.
+const minJWKSRefetch = time.Minute
+
+// KeySource looks up the public key a token was signed with.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// StaticSource is a fixed set of keys, e.g. of a local fake issuer in tests.
+type StaticSource map[string]*rsa.PublicKey
+
+func (s StaticSource) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	key, ok := s[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// JWKSSource fetches keys from a JSON Web Key Set URL and caches them.
+// The set is refreshed when it expires, as told by Cache-Control or after an hour,
+// and when a token names a key it hasn't seen, since issuers rotate keys.
+// Expired keys are served while the set is refreshed in the background; only a token
+// naming a key that isn't cached waits for the fetch. A failed refresh keeps using the keys it already has.
+type JWKSSource struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	expiresAt   time.Time
+	lastFetched time.Time
+	// fetching is closed once the fetch in flight is done, nil when there is none
+	fetching chan struct{}
+	fetchErr error
+}
+
+func NewJWKSSource(url string) *JWKSSource {
+	return &JWKSSource{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}
+}
+
+func (s *JWKSSource) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	now := s.now()
+	canFetch := s.keys == nil || now.Sub(s.lastFetched) >= minJWKSRefetch
+	if key, ok := s.keys[kid]; ok {
+		if now.After(s.expiresAt) && canFetch {
+			s.startFetch(now)
+		}
+		s.mu.Unlock()
+		return key, nil
+	}
+	if !canFetch && s.fetching == nil {
+		s.mu.Unlock()
+		return nil, ErrUnknownKey
+	}
+	done := s.startFetch(now)
+	s.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	if s.keys == nil && s.fetchErr != nil {
+		return nil, s.fetchErr
+	}
+	return nil, ErrUnknownKey
+}
+
+// startFetch refreshes the keys in the background unless that is already happening, and returns a channel
+// closed once it is done. It must be called with mu held.
+func (s *JWKSSource) startFetch(now time.Time) <-chan struct{} {
+	if s.fetching != nil {
+		return s.fetching
+	}
+	done := make(chan struct{})
+	s.fetching = done
+	s.lastFetched = now
+
+	go func() {
+		defer close(done)
+		// The fetch outlives the request that started it, the client timeout bounds it
+		keys, ttl, err := s.fetch(context.Background())
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetching = nil
+		s.fetchErr = err
+		if err == nil {
+			s.keys = keys
+			s.expiresAt = s.now().Add(ttl)
+		}
+	}()
+	return done
+}
+
+// fetch downloads the key set and tells how long it may be cached.
+func (s *JWKSSource) fetch(ctx context.Context) (map[string]*rsa.PublicKey, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, 0, fmt.Errorf("decode jwks: %w", err)
+	}
+	keys, err := set.rsaKeys()
+	if err != nil {
+		return nil, 0, err
+	}
+	return keys, maxAge(resp.Header.Get("Cache-Control")), nil
+}
+
+var maxAgePattern = regexp.MustCompile(`max-age=(\d+)`)
+
+func maxAge(cacheControl string) time.Duration {
+	m := maxAgePattern.FindStringSubmatch(cacheControl)
+	if m == nil {
+		return defaultJWKSRefresh
+	}
+	seconds, err := strconv.Atoi(m[1])
+	if err != nil || seconds <= 0 {
+		return defaultJWKSRefresh
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (set jwks) rsaKeys() (map[string]*rsa.PublicKey, error) {
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode modulus of key %s: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode exponent of key %s: %w", k.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("exponent of key %s is too large", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks has no RSA signing keys")
+	}
+	return keys, nil
+}
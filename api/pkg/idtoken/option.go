@@ -0,0 +1,33 @@
+package idtoken
+
+import "time"
+
+type VerifierOption func(*Verifier)
+
+// WithAudience sets the client IDs a token may be issued for. At least one is required.
+func WithAudience(audiences ...string) VerifierOption {
+	return func(v *Verifier) {
+		v.audiences = audiences
+	}
+}
+
+// WithIssuer replaces the accepted issuers, Google's by default.
+func WithIssuer(issuers ...string) VerifierOption {
+	return func(v *Verifier) {
+		v.issuers = issuers
+	}
+}
+
+// WithLeeway allows for clock skew between us and the issuer.
+func WithLeeway(leeway time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// WithClock replaces time.Now, e.g. to check expiry in tests.
+func WithClock(now func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
@@ -0,0 +1,201 @@
+package manifest
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is where the manifest lives, at the root of the project zip.
+const FileName = "problem.yaml"
+
+// Limits above these would let a simulation outlive the grader's own deadline.
+const (
+	MaxTimeSeconds = 50
+	MaxMemoryMB    = 4096
+)
+
+const (
+	defaultWorkDir = "cocotb"
+	// maxManifestSize keeps a hostile zip from making us read a huge manifest into memory
+	maxManifestSize = 1 << 20
+)
+
+var defaultCommand = []string{"make"}
+
+// Manifest describes a problem package, so the upload form doesn't have to repeat what the zip contains.
+//
+//	editable_files: [rtl/adder.v]
+//	languages: [verilog]
+//	testcases:
+//	  - name: test_adder.test_add
+//	    weight: 2
+//	    group: basic
+//	limits:
+//	  time_seconds: 20
+//	  memory_mb: 512
+//	simulator:
+//	  command: [make, SIM=icarus]
+//	  workdir: cocotb
+//	reference: solution
+type Manifest struct {
+	EditableFiles []string   `yaml:"editable_files"`
+	Languages     []string   `yaml:"languages"`
+	Testcases     []Testcase `yaml:"testcases"`
+	Limits        Limits     `yaml:"limits"`
+	Simulator     Simulator  `yaml:"simulator"`
+	// Reference is the directory holding a solution to every editable file, under the same relative paths.
+	// It is graded on upload and never given to student code.
+	Reference string `yaml:"reference"`
+}
+
+type Testcase struct {
+	// Name is the test key, e.g. "test_adder.test_add", or just the test function when that is unique
+	Name   string   `yaml:"name"`
+	Weight *float64 `yaml:"weight"`
+	Group  string   `yaml:"group"`
+}
+
+// Limits left at zero fall back to the grader's defaults.
+type Limits struct {
+	TimeSeconds float64 `yaml:"time_seconds"`
+	MemoryMB    uint    `yaml:"memory_mb"`
+}
+
+type Simulator struct {
+	// Command runs the tests and writes results.xml, it isn't passed through a shell
+	Command []string `yaml:"command"`
+	// WorkDir is the directory in the project the command runs in
+	WorkDir string `yaml:"workdir"`
+}
+
+// Parse reads a manifest, rejecting unknown fields so a typo doesn't silently fall back to a default.
+func Parse(data []byte) (*Manifest, error) {
+	var m Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse %s: %w", FileName, err)
+	}
+	if len(m.Simulator.Command) == 0 {
+		m.Simulator.Command = defaultCommand
+	}
+	if m.Simulator.WorkDir == "" {
+		m.Simulator.WorkDir = defaultWorkDir
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Load reads the manifest of a project zip. It returns nil without an error when the zip has none.
+func Load(r *zip.Reader) (*Manifest, error) {
+	for _, file := range r.File {
+		if file.Name != FileName {
+			continue
+		}
+		f, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		data, err := io.ReadAll(io.LimitReader(f, maxManifestSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxManifestSize {
+			return nil, fmt.Errorf("%s is larger than %d bytes", FileName, maxManifestSize)
+		}
+		return Parse(data)
+	}
+	return nil, nil
+}
+
+// LoadFile reads the manifest of an extracted project. It returns nil without an error when there is none.
+func LoadFile(name string) (*Manifest, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// check validates what can be checked without the rest of the package.
+func (m *Manifest) check() error {
+	for i, name := range m.EditableFiles {
+		if !isRelative(name) {
+			return fmt.Errorf("editable file '%s' must be a relative path inside the project", name)
+		}
+		if slices.Contains(m.EditableFiles[:i], name) {
+			return fmt.Errorf("editable file '%s' is listed twice", name)
+		}
+	}
+	for i, language := range m.Languages {
+		if strings.TrimSpace(language) == "" {
+			return errors.New("language names must not be empty")
+		}
+		if slices.Contains(m.Languages[:i], language) {
+			return fmt.Errorf("language '%s' is listed twice", language)
+		}
+	}
+	for i, tc := range m.Testcases {
+		if tc.Name == "" {
+			return fmt.Errorf("testcase %d has no name", i+1)
+		}
+		if tc.Weight != nil && *tc.Weight < 0 {
+			return fmt.Errorf("testcase '%s' has a negative weight", tc.Name)
+		}
+		if slices.ContainsFunc(m.Testcases[:i], func(other Testcase) bool { return other.Name == tc.Name }) {
+			return fmt.Errorf("testcase '%s' is listed twice", tc.Name)
+		}
+	}
+	if m.Limits.TimeSeconds < 0 || m.Limits.TimeSeconds > MaxTimeSeconds {
+		return fmt.Errorf("time limit must be between 0 and %d seconds", MaxTimeSeconds)
+	}
+	if m.Limits.MemoryMB > MaxMemoryMB {
+		return fmt.Errorf("memory limit must be at most %d MB", MaxMemoryMB)
+	}
+	if m.Reference != "" && (!isRelative(m.Reference) || path.Clean(m.Reference) == ".") {
+		return fmt.Errorf("reference '%s' must be a directory inside the project", m.Reference)
+	}
+	if !isRelative(m.Simulator.WorkDir) {
+		return fmt.Errorf("simulator workdir '%s' must be a relative path inside the project", m.Simulator.WorkDir)
+	}
+	return nil
+}
+
+// Validate checks that every file the manifest refers to is part of the project zip.
+func (m *Manifest) Validate(r *zip.Reader) error {
+	for _, name := range m.EditableFiles {
+		if !slices.ContainsFunc(r.File, func(f *zip.File) bool { return f.Name == name }) {
+			return fmt.Errorf("editable file '%s' is not in the project zip", name)
+		}
+	}
+	workDir := path.Clean(m.Simulator.WorkDir) + "/"
+	if workDir != "./" && !slices.ContainsFunc(r.File, func(f *zip.File) bool { return strings.HasPrefix(f.Name, workDir) }) {
+		return fmt.Errorf("simulator workdir '%s' is not in the project zip", m.Simulator.WorkDir)
+	}
+	return nil
+}
+
+// ReferenceFile is where the reference solution of an editable file is in the project.
+func (m *Manifest) ReferenceFile(name string) string {
+	return path.Join(m.Reference, name)
+}
+
+func isRelative(name string) bool {
+	clean := path.Clean(name)
+	return name != "" && !path.IsAbs(clean) && clean != ".." && !strings.HasPrefix(clean, "../")
+}
@@ -0,0 +1,174 @@
+package manifest
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	two := 2.0
+	tests := []struct {
+		name    string
+		yaml    string
+		want    *Manifest
+		wantErr string
+	}{
+		{
+			name: "full manifest",
+			yaml: `
+editable_files: [rtl/adder.v]
+languages: [verilog]
+testcases:
+  - name: test_adder.test_add
+    weight: 2
+    group: basic
+limits:
+  time_seconds: 20
+  memory_mb: 512
+simulator:
+  command: [make, SIM=icarus]
+  workdir: tests
+reference: solution
+`,
+			want: &Manifest{
+				EditableFiles: []string{"rtl/adder.v"},
+				Languages:     []string{"verilog"},
+				Testcases:     []Testcase{{Name: "test_adder.test_add", Weight: &two, Group: "basic"}},
+				Limits:        Limits{TimeSeconds: 20, MemoryMB: 512},
+				Simulator:     Simulator{Command: []string{"make", "SIM=icarus"}, WorkDir: "tests"},
+				Reference:     "solution",
+			},
+		},
+		{
+			name: "empty manifest uses the defaults",
+			yaml: "",
+			want: &Manifest{Simulator: Simulator{Command: []string{"make"}, WorkDir: "cocotb"}},
+		},
+		{name: "unknown field", yaml: "editable_file: [a.v]", wantErr: "field editable_file not found"},
+		{name: "absolute editable file", yaml: "editable_files: [/etc/passwd]", wantErr: "relative path"},
+		{name: "editable file outside the project", yaml: "editable_files: [rtl/../../a.v]", wantErr: "relative path"},
+		{name: "duplicate editable file", yaml: "editable_files: [a.v, a.v]", wantErr: "listed twice"},
+		{name: "empty language", yaml: "languages: [' ']", wantErr: "must not be empty"},
+		{name: "duplicate language", yaml: "languages: [verilog, verilog]", wantErr: "listed twice"},
+		{name: "testcase without name", yaml: "testcases: [{weight: 1}]", wantErr: "testcase 1 has no name"},
+		{name: "negative weight", yaml: "testcases: [{name: t.a, weight: -1}]", wantErr: "negative weight"},
+		{name: "duplicate testcase", yaml: "testcases: [{name: t.a}, {name: t.a}]", wantErr: "listed twice"},
+		{name: "time limit too long", yaml: "limits: {time_seconds: 51}", wantErr: "time limit"},
+		{name: "negative time limit", yaml: "limits: {time_seconds: -1}", wantErr: "time limit"},
+		{name: "memory limit too large", yaml: "limits: {memory_mb: 4097}", wantErr: "memory limit"},
+		{name: "reference at the root", yaml: "reference: .", wantErr: "reference"},
+		{name: "reference outside the project", yaml: "reference: ../solution", wantErr: "reference"},
+		{name: "workdir outside the project", yaml: "simulator: {workdir: ../tests}", wantErr: "workdir"},
+		{name: "not yaml", yaml: "editable_files: [", wantErr: "parse problem.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func zipOf(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestLoadAndValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantNil bool
+		wantErr string
+	}{
+		{
+			name: "valid package",
+			files: map[string]string{
+				FileName:          "editable_files: [rtl/adder.v]",
+				"rtl/adder.v":     "module adder; endmodule",
+				"cocotb/Makefile": "all:",
+			},
+		},
+		{name: "no manifest", files: map[string]string{"rtl/adder.v": ""}, wantNil: true},
+		{name: "manifest in a subdirectory is ignored", files: map[string]string{"sub/" + FileName: "bogus: 1"}, wantNil: true},
+		{
+			name:    "missing editable file",
+			files:   map[string]string{FileName: "editable_files: [rtl/adder.v]", "cocotb/Makefile": ""},
+			wantErr: "not in the project zip",
+		},
+		{
+			name:    "missing workdir",
+			files:   map[string]string{FileName: "simulator: {workdir: tests}", "cocotb/Makefile": ""},
+			wantErr: "workdir 'tests' is not in the project zip",
+		},
+		{
+			name:  "project root as workdir",
+			files: map[string]string{FileName: "simulator: {workdir: .}", "Makefile": ""},
+		},
+		{
+			name:    "manifest too large",
+			files:   map[string]string{FileName: "# " + strings.Repeat("x", maxManifestSize)},
+			wantErr: "larger than",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := zipOf(t, tt.files)
+			m, err := Load(r)
+			if err == nil && m != nil {
+				err = m.Validate(r)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (m == nil) != tt.wantNil {
+				t.Errorf("manifest = %+v, want nil %v", m, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestReferenceFile(t *testing.T) {
+	m := Manifest{Reference: "solution/"}
+	if got := m.ReferenceFile("rtl/adder.v"); got != "solution/rtl/adder.v" {
+		t.Errorf("ReferenceFile() = %q", got)
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+)
+
+// AccessPolicy decides which verified Google accounts may sign in.
+type AccessPolicy struct {
+	// HostedDomains are the Google Workspace domains whose accounts may sign in; empty allows any
+	HostedDomains []string
+	// AllowedEmails may sign in even from outside HostedDomains. With no HostedDomains, only they may sign in.
+	AllowedEmails []string
+	// BlockedEmails may never sign in
+	BlockedEmails        []string
+	RequireVerifiedEmail bool
+}
+
+// Check returns a forbidden error that says why the account was rejected, or nil.
+func (p AccessPolicy) Check(profile domain.Profile) error {
+	email := strings.ToLower(profile.Email)
+
+	if p.Blocked(email) {
+		return apperror.ForbiddenError(errors.New("email is blocked"), "This account is blocked")
+	}
+	if p.RequireVerifiedEmail && !profile.EmailVerified {
+		return apperror.ForbiddenError(errors.New("email is not verified"), "Email address must be verified")
+	}
+	if containsFold(p.AllowedEmails, email) {
+		return nil
+	}
+	if len(p.HostedDomains) > 0 {
+		if profile.HD == "" || !containsFold(p.HostedDomains, profile.HD) {
+			return apperror.ForbiddenError(errors.New("hosted domain is not allowed"), "Accounts from this domain are not allowed")
+		}
+		return nil
+	}
+	if len(p.AllowedEmails) > 0 {
+		return apperror.ForbiddenError(errors.New("email is not allowlisted"), "This account is not on the allowlist")
+	}
+	return nil
+}
+
+// CheckUser applies the policy to the owner of an access token, so that changes to it also stop their
+// tokens. The token was created after a verified sign in, which stored the hd claim on the user.
+func (p AccessPolicy) CheckUser(user domain.User) error {
+	return p.Check(domain.Profile{Email: user.Email, EmailVerified: true, HD: user.HostedDomain})
+}
+
+// Blocked reports whether the email is on the blocklist.
+func (p AccessPolicy) Blocked(email string) bool {
+	return containsFold(p.BlockedEmails, strings.ToLower(email))
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(strings.TrimSpace(v), s) })
+}
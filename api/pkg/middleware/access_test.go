@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+)
+
+func TestAccessPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  AccessPolicy
+		profile domain.Profile
+		allowed bool
+	}{
+		{name: "open policy", profile: domain.Profile{Email: "a@gmail.com"}, allowed: true},
+		{name: "blocked", policy: AccessPolicy{BlockedEmails: []string{"A@gmail.com"}}, profile: domain.Profile{Email: "a@gmail.com"}},
+		{name: "unverified", policy: AccessPolicy{RequireVerifiedEmail: true}, profile: domain.Profile{Email: "a@gmail.com"}},
+		{name: "verified", policy: AccessPolicy{RequireVerifiedEmail: true}, profile: domain.Profile{Email: "a@gmail.com", EmailVerified: true}, allowed: true},
+		{
+			name:    "hosted domain",
+			policy:  AccessPolicy{HostedDomains: []string{"uni.ac.th"}},
+			profile: domain.Profile{Email: "a@uni.ac.th", HD: "uni.ac.th"},
+			allowed: true,
+		},
+		{
+			name:    "other hosted domain",
+			policy:  AccessPolicy{HostedDomains: []string{"uni.ac.th"}},
+			profile: domain.Profile{Email: "a@other.ac.th", HD: "other.ac.th"},
+		},
+		{
+			name:    "consumer account with hosted domains",
+			policy:  AccessPolicy{HostedDomains: []string{"uni.ac.th"}},
+			profile: domain.Profile{Email: "a@gmail.com"},
+		},
+		{
+			name:    "allowlisted outside hosted domains",
+			policy:  AccessPolicy{HostedDomains: []string{"uni.ac.th"}, AllowedEmails: []string{" ta@gmail.com"}},
+			profile: domain.Profile{Email: "TA@gmail.com"},
+			allowed: true,
+		},
+		{name: "allowlist only", policy: AccessPolicy{AllowedEmails: []string{"ta@gmail.com"}}, profile: domain.Profile{Email: "a@gmail.com"}},
+		{
+			name:    "blocklist beats allowlist",
+			policy:  AccessPolicy{AllowedEmails: []string{"ta@gmail.com"}, BlockedEmails: []string{"ta@gmail.com"}},
+			profile: domain.Profile{Email: "ta@gmail.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.profile)
+			if (err == nil) != tt.allowed {
+				t.Errorf("Check() = %v, allowed %v", err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestAccessPolicyCheckUser(t *testing.T) {
+	policy := AccessPolicy{
+		HostedDomains:        []string{"uni.ac.th"},
+		AllowedEmails:        []string{"ta@gmail.com"},
+		BlockedEmails:        []string{"blocked@uni.ac.th"},
+		RequireVerifiedEmail: true,
+	}
+	tests := []struct {
+		name    string
+		user    domain.User
+		allowed bool
+	}{
+		{"hosted domain", domain.User{Email: "student@uni.ac.th", HostedDomain: "uni.ac.th"}, true},
+		{"hosted domain in another case", domain.User{Email: "Student@UNI.ac.th", HostedDomain: "UNI.ac.th"}, true},
+		{"allowlisted", domain.User{Email: "ta@gmail.com"}, true},
+		{"personal account", domain.User{Email: "student@gmail.com"}, false},
+		{"blocked", domain.User{Email: "blocked@uni.ac.th", HostedDomain: "uni.ac.th"}, false},
+		// A personal account on an address of the domain, e.g. made before the domain used Google Workspace
+		{"email domain without hd claim", domain.User{Email: "alumni@uni.ac.th"}, false},
+		{"other hosted domain", domain.User{Email: "student@uni.ac.th.evil.com", HostedDomain: "uni.ac.th.evil.com"}, false},
+	}
+	for _, tt := range tests {
+		err := policy.CheckUser(tt.user)
+		if (err == nil) != tt.allowed {
+			t.Errorf("%s: CheckUser(%+v) = %v, allowed %v", tt.name, tt.user, err, tt.allowed)
+		}
+	}
+}
@@ -1,22 +1,56 @@
 package middleware
 
 import (
-	"encoding/json"
 	"errors"
-	"net/http"
+	"fmt"
 	"strings"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/service"
+	"github.com/yokeTH/our-grader-backend/api/pkg/idtoken"
 )
 
+type AuthConfig struct {
+	// BootstrapAdmins are made admins on start up, so a fresh deployment has someone who can grant roles
+	BootstrapAdmins []string `env:"BOOTSTRAP_ADMINS" envSeparator:","`
+	// GoogleClientIDs are the OAuth client IDs ID tokens must be issued for
+	GoogleClientIDs      []string `env:"GOOGLE_CLIENT_IDS" envSeparator:","`
+	JWKSURL              string   `env:"JWKS_URL" envDefault:"https://www.googleapis.com/oauth2/v3/certs"`
+	HostedDomains        []string `env:"HOSTED_DOMAINS" envSeparator:","`
+	AllowedEmails        []string `env:"ALLOWED_EMAILS" envSeparator:","`
+	BlockedEmails        []string `env:"BLOCKED_EMAILS" envSeparator:","`
+	RequireVerifiedEmail bool     `env:"REQUIRE_VERIFIED_EMAIL" envDefault:"true"`
+}
+
+func (c AuthConfig) AccessPolicy() AccessPolicy {
+	return AccessPolicy{
+		HostedDomains:        c.HostedDomains,
+		AllowedEmails:        c.AllowedEmails,
+		BlockedEmails:        c.BlockedEmails,
+		RequireVerifiedEmail: c.RequireVerifiedEmail,
+	}
+}
+
+// NewGoogleVerifier verifies Google ID tokens against Google's cached signing keys.
+// Which accounts may sign in is up to the AccessPolicy, so rejections can say why.
+func NewGoogleVerifier(config AuthConfig) (*idtoken.Verifier, error) {
+	if len(config.GoogleClientIDs) == 0 {
+		return nil, errors.New("at least one google client id is required")
+	}
+	return idtoken.NewVerifier(idtoken.NewJWKSSource(config.JWKSURL), idtoken.WithAudience(config.GoogleClientIDs...)), nil
+}
+
 type AuthMiddleware struct {
+	verifier     *idtoken.Verifier
+	policy       AccessPolicy
+	userService  *service.UserService
+	tokenService *service.AccessTokenService
 }
 
-func NewAuthMiddleware() *AuthMiddleware {
-	return &AuthMiddleware{}
+func NewAuthMiddleware(verifier *idtoken.Verifier, policy AccessPolicy, userService *service.UserService, tokenService *service.AccessTokenService) *AuthMiddleware {
+	return &AuthMiddleware{verifier: verifier, policy: policy, userService: userService, tokenService: tokenService}
 }
 
 func (a *AuthMiddleware) Auth(ctx *fiber.Ctx) error {
@@ -32,42 +66,101 @@ func (a *AuthMiddleware) Auth(ctx *fiber.Ctx) error {
 
 	token := authHeader[7:]
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", "https://www.googleapis.com/oauth2/v3/userinfo", nil)
-	if err != nil {
-		return apperror.UnauthorizedError(err, "Failed to create request to Google OAuth")
+	if strings.HasPrefix(token, domain.AccessTokenPrefix) {
+		return a.accessToken(ctx, token)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := client.Do(req)
+	claims, err := a.verifier.Verify(ctx.Context(), token)
 	if err != nil {
-		return apperror.UnauthorizedError(err, "Failed to get profile from Google OAuth")
+		return apperror.UnauthorizedError(err, "Invalid ID token")
+	}
+
+	profile := domain.Profile{
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		FamilyName:    claims.FamilyName,
+		GivenName:     claims.GivenName,
+		HD:            claims.HostedDomain,
+		Picture:       claims.Picture,
+		Sub:           claims.Subject,
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return apperror.UnauthorizedError(errors.New("non-200 response from Google OAuth"), "Failed to get profile from Google OAuth")
+	if err := a.policy.Check(profile); err != nil {
+		return err
 	}
 
-	var profile domain.Profile
-	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
-		return apperror.UnauthorizedError(err, "Failed to decode profile from Google OAuth")
+	user, err := a.userService.SignIn(profile)
+	if err != nil {
+		return err
 	}
 
 	ctx.Locals("profile", profile)
+	ctx.Locals("user", user)
 	return ctx.Next()
 }
 
-func (a *AuthMiddleware) Owner(ctx *fiber.Ctx) error {
-	profile, ok := ctx.Locals("profile").(domain.Profile)
-	if !ok {
-		return apperror.UnauthorizedError(errors.New("profile not found in context"), "User profile is required")
+// accessToken signs in with a personal access token. Tokens with the read scope may make any GET request,
+// anything else has to be allowed by Scope on the route.
+func (a *AuthMiddleware) accessToken(ctx *fiber.Ctx, plain string) error {
+	token, err := a.tokenService.Authenticate(plain)
+	if err != nil {
+		return err
+	}
+	if err := a.policy.CheckUser(token.User); err != nil {
+		return err
 	}
+	if (ctx.Method() == fiber.MethodGet || ctx.Method() == fiber.MethodHead) && !token.HasScope(domain.ScopeRead) {
+		return apperror.ForbiddenError(errors.New("missing read scope"), "Access token doesn't have the read scope")
+	}
+
+	ctx.Locals("profile", domain.Profile{
+		Email:         token.User.Email,
+		EmailVerified: true,
+		Picture:       token.User.Picture,
+	})
+	ctx.Locals("user", token.User)
+	ctx.Locals("token", token)
+	return ctx.Next()
+}
 
-	if profile.Email != "6530162621@student.chula.ac.th" {
-		return apperror.ForbiddenError(errors.New("who are you?"), "who are you?")
+// Scope lets requests made with an access token through only when the token has the scope.
+// Requests signed in with Google are let through. Use it after Auth.
+func (a *AuthMiddleware) Scope(scope domain.Scope) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		token, ok := ctx.Locals("token").(domain.AccessToken)
+		if ok && !token.HasScope(scope) {
+			return apperror.ForbiddenError(fmt.Errorf("missing scope %s", scope), fmt.Sprintf("Access token doesn't have the %s scope", scope))
+		}
+		return ctx.Next()
 	}
+}
 
+// NoAccessToken only lets through requests signed in with Google, e.g. for managing the tokens themselves.
+func (a *AuthMiddleware) NoAccessToken(ctx *fiber.Ctx) error {
+	if _, ok := ctx.Locals("token").(domain.AccessToken); ok {
+		return apperror.ForbiddenError(errors.New("access token used"), "Sign in with Google to do this")
+	}
 	return ctx.Next()
 }
+
+// Require lets the request through only when the user has every given permission. Use it after Auth.
+// Access tokens never carry permissions, staff have to sign in with Google.
+func (a *AuthMiddleware) Require(permissions ...domain.Permission) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		user, ok := ctx.Locals("user").(domain.User)
+		if !ok {
+			return apperror.UnauthorizedError(errors.New("user not found in context"), "User profile is required")
+		}
+		if _, ok := ctx.Locals("token").(domain.AccessToken); ok {
+			return apperror.ForbiddenError(errors.New("access token used"), "Sign in with Google to do this")
+		}
+
+		for _, permission := range permissions {
+			if !user.Can(permission) {
+				return apperror.ForbiddenError(fmt.Errorf("missing permission %s", permission), "You don't have permission to do this")
+			}
+		}
+
+		return ctx.Next()
+	}
+}
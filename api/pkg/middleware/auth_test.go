@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yokeTH/our-grader-backend/api/pkg/apperror"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/service"
+	"gorm.io/gorm"
+)
+
+// tokenRepo is an in-memory AccessTokenRepository.
+type tokenRepo map[string]domain.AccessToken
+
+func (r tokenRepo) Create(token *domain.AccessToken) error {
+	r[token.Hash] = *token
+	return nil
+}
+
+func (r tokenRepo) GetByHash(hash string) (domain.AccessToken, error) {
+	token, ok := r[hash]
+	if !ok {
+		return token, gorm.ErrRecordNotFound
+	}
+	return token, nil
+}
+
+func (r tokenRepo) GetByUserID(userID uint) ([]domain.AccessToken, error) { return nil, nil }
+
+func (r tokenRepo) Delete(userID uint, id uint) (int64, error) { return 0, nil }
+
+func (r tokenRepo) TouchLastUsed(id uint, at time.Time) error { return nil }
+
+func TestAuthAccessToken(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	student := domain.User{Email: "student@uni.ac.th", HostedDomain: "uni.ac.th"}
+	repo := tokenRepo{}
+	for plain, token := range map[string]domain.AccessToken{
+		"ogp_student":   {User: student, Scopes: "read,submit"},
+		"ogp_submit":    {User: student, Scopes: "submit"},
+		"ogp_outsider":  {User: domain.User{Email: "someone@gmail.com"}, Scopes: "read,submit"},
+		"ogp_personal":  {User: domain.User{Email: "alumni@uni.ac.th"}, Scopes: "read,submit"},
+		"ogp_blocked":   {User: domain.User{Email: "blocked@uni.ac.th", HostedDomain: "uni.ac.th"}, Scopes: "read,submit"},
+		"ogp_allowlist": {User: domain.User{Email: "ta@gmail.com"}, Scopes: "read"},
+		"ogp_expired":   {User: student, Scopes: "read", ExpiresAt: &expired},
+	} {
+		token.Hash = domain.HashAccessToken(plain)
+		repo.Create(&token)
+	}
+	auth := NewAuthMiddleware(nil, AccessPolicy{
+		HostedDomains:        []string{"uni.ac.th"},
+		AllowedEmails:        []string{"ta@gmail.com"},
+		BlockedEmails:        []string{"blocked@uni.ac.th"},
+		RequireVerifiedEmail: true,
+	}, nil, service.NewAccessTokenService(repo))
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.ErrorHandler})
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/", auth.Auth, ok)
+	app.Post("/submit", auth.Auth, auth.Scope(domain.ScopeSubmit), ok)
+	app.Post("/tokens", auth.Auth, auth.NoAccessToken, ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{name: "valid token", method: "GET", path: "/", token: "ogp_student", want: 200},
+		{name: "unknown token", method: "GET", path: "/", token: "ogp_unknown", want: 401},
+		{name: "expired token", method: "GET", path: "/", token: "ogp_expired", want: 401},
+		{name: "owner outside the hosted domains", method: "GET", path: "/", token: "ogp_outsider", want: 403},
+		{name: "owner signed in without the hd claim", method: "GET", path: "/", token: "ogp_personal", want: 403},
+		{name: "blocked owner", method: "GET", path: "/", token: "ogp_blocked", want: 403},
+		{name: "allowlisted owner", method: "GET", path: "/", token: "ogp_allowlist", want: 200},
+		{name: "GET without read scope", method: "GET", path: "/", token: "ogp_submit", want: 403},
+		{name: "submit scope", method: "POST", path: "/submit", token: "ogp_submit", want: 200},
+		{name: "missing submit scope", method: "POST", path: "/submit", token: "ogp_allowlist", want: 403},
+		{name: "route closed to tokens", method: "POST", path: "/tokens", token: "ogp_student", want: 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+)
+
+type AccessTokenRepository struct {
+	db *database.Database
+}
+
+func NewAccessTokenRepository(db *database.Database) *AccessTokenRepository {
+	return &AccessTokenRepository{db: db}
+}
+
+func (r *AccessTokenRepository) Create(token *domain.AccessToken) error {
+	if err := r.db.Omit("User").Create(token).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *AccessTokenRepository) GetByHash(hash string) (domain.AccessToken, error) {
+	var token domain.AccessToken
+	if err := r.db.Preload("User.Roles").Where("hash = ?", hash).First(&token).Error; err != nil {
+		return token, err
+	}
+	return token, nil
+}
+
+func (r *AccessTokenRepository) GetByUserID(userID uint) ([]domain.AccessToken, error) {
+	var tokens []domain.AccessToken
+	if err := r.db.Where("user_id = ?", userID).Order("id ASC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Delete revokes a token of the user, it reports no rows for a token of someone else.
+func (r *AccessTokenRepository) Delete(userID uint, id uint) (int64, error) {
+	result := r.db.Where("user_id = ? AND id = ?", userID, id).Delete(&domain.AccessToken{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *AccessTokenRepository) TouchLastUsed(id uint, at time.Time) error {
+	if err := r.db.Model(&domain.AccessToken{}).Where("id = ?", id).Update("last_used_at", at).Error; err != nil {
+		return err
+	}
+	return nil
+}
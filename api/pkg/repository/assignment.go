@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AssignmentRepository struct {
+	db *database.Database
+}
+
+func NewAssignmentRepository(db *database.Database) *AssignmentRepository {
+	return &AssignmentRepository{db: db}
+}
+
+func (r *AssignmentRepository) Create(assignment *domain.Assignment) error {
+	if err := r.db.Omit("Problems.*").Create(assignment).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *AssignmentRepository) GetByID(id uint) (domain.Assignment, error) {
+	var assignment domain.Assignment
+	if err := r.db.Preload("Problems").Preload("Extensions.User").Where("id = ?", id).First(&assignment).Error; err != nil {
+		return assignment, err
+	}
+	return assignment, nil
+}
+
+func (r *AssignmentRepository) GetByCourseID(courseID uint) ([]domain.Assignment, error) {
+	var assignments []domain.Assignment
+	if err := r.db.Preload("Problems").Where("course_id = ?", courseID).Order("due_at ASC").Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// GetByProblemID returns the assignments the problem is part of, with their extensions.
+func (r *AssignmentRepository) GetByProblemID(problemID uint) ([]domain.Assignment, error) {
+	var assignments []domain.Assignment
+	if err := r.db.Preload("Extensions").
+		Where("id IN (?)", r.db.Table("assignment_problems").Select("assignment_id").Where("problem_id = ?", problemID)).
+		Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// Update saves the fields of the assignment and replaces its problems.
+func (r *AssignmentRepository) Update(assignment *domain.Assignment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Assignment{}).Where("id = ?", assignment.ID).Updates(map[string]any{
+			"name":         assignment.Name,
+			"open_at":      assignment.OpenAt,
+			"due_at":       assignment.DueAt,
+			"close_at":     assignment.CloseAt,
+			"late_policy":  assignment.LatePolicy,
+			"late_penalty": assignment.LatePenalty,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(assignment).Omit("Problems.*").Association("Problems").Replace(assignment.Problems)
+	})
+}
+
+func (r *AssignmentRepository) Delete(id uint) error {
+	if err := r.db.Delete(&domain.Assignment{}, id).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpsertExtension grants the extension or moves the deadlines of an existing one.
+func (r *AssignmentRepository) UpsertExtension(extension *domain.AssignmentExtension) error {
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "assignment_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"due_at", "close_at", "granted_by", "updated_at"}),
+	}).Omit("User").Create(extension).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteExtension removes the extension for good, so granting one again later starts from a clean row.
+func (r *AssignmentRepository) DeleteExtension(assignmentID uint, userID uint) (int64, error) {
+	result := r.db.Unscoped().Where("assignment_id = ? AND user_id = ?", assignmentID, userID).Delete(&domain.AssignmentExtension{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ContestRepository struct {
+	db *database.Database
+}
+
+func NewContestRepository(db *database.Database) *ContestRepository {
+	return &ContestRepository{db: db}
+}
+
+func (r *ContestRepository) Create(contest *domain.Contest) error {
+	if err := r.db.Omit("Problems.*").Create(contest).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *ContestRepository) GetByID(id uint) (domain.Contest, error) {
+	var contest domain.Contest
+	if err := r.db.Preload("Problems", func(db *gorm.DB) *gorm.DB {
+		return db.Order("problems.id ASC")
+	}).Preload("Participants.User").Where("id = ?", id).First(&contest).Error; err != nil {
+		return contest, err
+	}
+	return contest, nil
+}
+
+// GetContests lists the global contests and those of the given courses.
+func (r *ContestRepository) GetContests(courseIDs []uint) ([]domain.Contest, error) {
+	var contests []domain.Contest
+	query := r.db.Where("course_id IS NULL")
+	if len(courseIDs) > 0 {
+		query = r.db.Where("course_id IS NULL OR course_id IN ?", courseIDs)
+	}
+	if err := query.Order("start_at DESC").Find(&contests).Error; err != nil {
+		return nil, err
+	}
+	return contests, nil
+}
+
+// GetByProblemID returns the contests the problem is part of, with their participants.
+func (r *ContestRepository) GetByProblemID(problemID uint) ([]domain.Contest, error) {
+	var contests []domain.Contest
+	if err := r.db.Preload("Participants").
+		Where("id IN (?)", r.db.Table("contest_problems").Select("contest_id").Where("problem_id = ?", problemID)).
+		Find(&contests).Error; err != nil {
+		return nil, err
+	}
+	return contests, nil
+}
+
+// GetAll lists every contest, for global staff.
+func (r *ContestRepository) GetAll() ([]domain.Contest, error) {
+	var contests []domain.Contest
+	if err := r.db.Order("start_at DESC").Find(&contests).Error; err != nil {
+		return nil, err
+	}
+	return contests, nil
+}
+
+// Update saves the fields of the contest and replaces its problems.
+func (r *ContestRepository) Update(contest *domain.Contest) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Contest{}).Where("id = ?", contest.ID).Updates(map[string]any{
+			"name":            contest.Name,
+			"style":           contest.Style,
+			"start_at":        contest.StartAt,
+			"end_at":          contest.EndAt,
+			"freeze_minutes":  contest.FreezeMinutes,
+			"penalty_minutes": contest.PenaltyMinutes,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(contest).Omit("Problems.*").Association("Problems").Replace(contest.Problems)
+	})
+}
+
+func (r *ContestRepository) Delete(id uint) error {
+	if err := r.db.Delete(&domain.Contest{}, id).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// AddParticipant registers the user unless they already are. It reports whether the user was added.
+func (r *ContestRepository) AddParticipant(participant *domain.ContestParticipant) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Omit("User").Create(participant)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *ContestRepository) RemoveParticipant(contestID uint, userID uint) (int64, error) {
+	result := r.db.Unscoped().Where("contest_id = ? AND user_id = ?", contestID, userID).Delete(&domain.ContestParticipant{})
+	return result.RowsAffected, result.Error
+}
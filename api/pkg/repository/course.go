@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"gorm.io/gorm/clause"
+)
+
+type CourseRepository struct {
+	db *database.Database
+}
+
+func NewCourseRepository(db *database.Database) *CourseRepository {
+	return &CourseRepository{db: db}
+}
+
+// Create saves the course together with its first members.
+func (r *CourseRepository) Create(course *domain.Course) error {
+	if err := r.db.Create(course).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *CourseRepository) GetByID(id uint) (domain.Course, error) {
+	var course domain.Course
+	if err := r.db.Where("id = ?", id).First(&course).Error; err != nil {
+		return course, err
+	}
+	return course, nil
+}
+
+func (r *CourseRepository) GetByInviteCode(code string) (domain.Course, error) {
+	var course domain.Course
+	if err := r.db.Where("invite_code = ?", code).First(&course).Error; err != nil {
+		return course, err
+	}
+	return course, nil
+}
+
+func (r *CourseRepository) GetByUserID(userID uint) ([]domain.Course, error) {
+	var courses []domain.Course
+	if err := r.db.
+		Where("id IN (?)", r.db.Model(&domain.CourseMember{}).Select("course_id").Where("user_id = ?", userID)).
+		Order("id ASC").
+		Find(&courses).Error; err != nil {
+		return nil, err
+	}
+	return courses, nil
+}
+
+func (r *CourseRepository) UpdateInviteCode(id uint, code string) error {
+	if err := r.db.Model(&domain.Course{}).Where("id = ?", id).Update("invite_code", code).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *CourseRepository) GetMembers(courseID uint) ([]domain.CourseMember, error) {
+	var members []domain.CourseMember
+	if err := r.db.Preload("User").Where("course_id = ?", courseID).Order("id ASC").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *CourseRepository) GetMembership(courseID uint, userID uint) (domain.CourseMember, error) {
+	var member domain.CourseMember
+	if err := r.db.Where("course_id = ? AND user_id = ?", courseID, userID).First(&member).Error; err != nil {
+		return member, err
+	}
+	return member, nil
+}
+
+func (r *CourseRepository) GetMemberships(userID uint) ([]domain.CourseMember, error) {
+	var members []domain.CourseMember
+	if err := r.db.Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddMember enrolls the user unless they already are a member, keeping the role they have.
+// It reports whether the user was added.
+func (r *CourseRepository) AddMember(member *domain.CourseMember) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Omit("Course", "User").Create(member)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpsertMember enrolls the user or changes the role of an existing member.
+func (r *CourseRepository) UpsertMember(member *domain.CourseMember) error {
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "course_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Omit("Course", "User").Create(member).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoveMember drops the membership for good, so enrolling again later starts from a clean row.
+func (r *CourseRepository) RemoveMember(courseID uint, userID uint) (int64, error) {
+	result := r.db.Unscoped().Where("course_id = ? AND user_id = ?", courseID, userID).Delete(&domain.CourseMember{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errLeaseExpired = errors.New("worker stopped responding while grading")
+
+type GradingJobRepository struct {
+	db *database.Database
+}
+
+func NewGradingJobRepository(db *database.Database) *GradingJobRepository {
+	return &GradingJobRepository{db: db}
+}
+
+// Release lets workers claim a pending job right away instead of at its scheduled time.
+func (r *GradingJobRepository) Release(id uint) error {
+	if err := r.db.Model(&domain.GradingJob{}).
+		Where("id = ? AND status = ?", id, domain.GradingJobPending).
+		Update("run_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// EnqueueUnlessPending adds the job unless its submission already has one waiting,
+// which will see the latest state of the submission anyway. It reports whether the job was added.
+func (r *GradingJobRepository) EnqueueUnlessPending(job *domain.GradingJob) (bool, error) {
+	added := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockSubmissionJobs(tx, job.SubmissionID); err != nil {
+			return err
+		}
+
+		var pending int64
+		if err := tx.Model(&domain.GradingJob{}).
+			Where("submission_id = ? AND status = ?", job.SubmissionID, domain.GradingJobPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return nil
+		}
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		added = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return added, nil
+}
+
+// lockSubmissionJobs serializes changes to the jobs of one submission until the transaction ends,
+// so checking for a pending or running job and acting on the answer can't race with another caller.
+func lockSubmissionJobs(tx *gorm.DB, submissionID uint) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", int64(submissionID)).Error
+}
+
+// hasRunningJob reports whether a worker is grading the submission.
+func hasRunningJob(tx *gorm.DB, submissionID uint) (bool, error) {
+	var running int64
+	if err := tx.Model(&domain.GradingJob{}).
+		Where("submission_id = ? AND status = ?", submissionID, domain.GradingJobRunning).
+		Count(&running).Error; err != nil {
+		return false, err
+	}
+	return running > 0, nil
+}
+
+// Claim locks the oldest runnable job and marks it as running.
+// Rows locked by other workers are skipped, so several workers can poll the same table.
+// A submission is never graded by two workers at once, they would share its working directory.
+// It returns nil without an error when there is nothing to do.
+func (r *GradingJobRepository) Claim(now time.Time) (*domain.GradingJob, error) {
+	var job domain.GradingJob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}).
+			Where("status = ?", domain.GradingJobPending).
+			Where("run_at <= ?", now).
+			Where("NOT EXISTS (SELECT 1 FROM grading_jobs running WHERE running.submission_id = grading_jobs.submission_id AND running.status = ? AND running.deleted_at IS NULL)", domain.GradingJobRunning).
+			Order("run_at ASC").
+			First(&job).Error; err != nil {
+			return err
+		}
+
+		// Another worker may have started a job of the same submission since the query above,
+		// look again once no one else can
+		if err := lockSubmissionJobs(tx, job.SubmissionID); err != nil {
+			return err
+		}
+		running, err := hasRunningJob(tx, job.SubmissionID)
+		if err != nil {
+			return err
+		}
+		if running {
+			return gorm.ErrRecordNotFound
+		}
+
+		// Postgres keeps microseconds, Complete and Fail compare against the stored value
+		lockedAt := now.Truncate(time.Microsecond)
+		job.Status = domain.GradingJobRunning
+		job.Attempts++
+		job.LockedAt = &lockedAt
+		return tx.Model(&domain.GradingJob{}).Where("id = ?", job.ID).Updates(map[string]any{
+			"status":    job.Status,
+			"attempts":  job.Attempts,
+			"locked_at": job.LockedAt,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Complete marks the job as done. It reports false without changing anything
+// when the caller no longer holds the job, because its lease expired and it was put back in the queue.
+func (r *GradingJobRepository) Complete(job *domain.GradingJob) (bool, error) {
+	result := held(r.db.Model(&domain.GradingJob{}), job).Updates(map[string]any{
+		"status":     domain.GradingJobDone,
+		"locked_at":  nil,
+		"last_error": "",
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// held limits tx to the given job while it is still running under the lock it was claimed with.
+func held(tx *gorm.DB, job *domain.GradingJob) *gorm.DB {
+	return tx.Where("id = ? AND status = ? AND locked_at = ?", job.ID, domain.GradingJobRunning, job.LockedAt)
+}
+
+func (r *GradingJobRepository) UpdateStage(id uint, stage string) error {
+	if err := r.db.Model(&domain.GradingJob{}).Where("id = ?", id).Update("stage", stage).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// Fail records a failed attempt. The job goes back to the queue at retryAt,
+// or is marked as failed for good once it has used up its attempts.
+// Like Complete, it reports false when the caller no longer holds the job.
+func (r *GradingJobRepository) Fail(job *domain.GradingJob, cause error, retryAt time.Time) (bool, error) {
+	status := domain.GradingJobPending
+	if job.Attempts >= job.MaxAttempts {
+		status = domain.GradingJobFailed
+	}
+
+	result := held(r.db.Model(&domain.GradingJob{}), job).Updates(map[string]any{
+		"status":     status,
+		"run_at":     retryAt,
+		"locked_at":  nil,
+		"last_error": cause.Error(),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	job.Status = status
+	job.RunAt = retryAt
+	job.LockedAt = nil
+	job.LastError = cause.Error()
+	return true, nil
+}
+
+// RequeueStale puts back jobs whose worker has held them since before the given time,
+// which happens when a grader crashes or is restarted in the middle of a run.
+// Jobs that have used up their attempts are marked as failed instead and returned,
+// so a job that keeps taking the grader down is not retried forever.
+func (r *GradingJobRepository) RequeueStale(lockedBefore time.Time) (int64, []domain.GradingJob, error) {
+	var requeued int64
+	var failed []domain.GradingJob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&failed).
+			Clauses(clause.Returning{}).
+			Where("status = ?", domain.GradingJobRunning).
+			Where("locked_at < ?", lockedBefore).
+			Where("attempts >= max_attempts").
+			Updates(map[string]any{
+				"status":     domain.GradingJobFailed,
+				"locked_at":  nil,
+				"last_error": errLeaseExpired.Error(),
+			}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&domain.GradingJob{}).
+			Where("status = ?", domain.GradingJobRunning).
+			Where("locked_at < ?", lockedBefore).
+			Updates(map[string]any{
+				"status":    domain.GradingJobPending,
+				"run_at":    time.Now(),
+				"locked_at": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		requeued = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return requeued, failed, nil
+}
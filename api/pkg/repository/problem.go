@@ -1,8 +1,12 @@
 package repository
 
 import (
+	"errors"
+	"time"
+
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
 	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"gorm.io/gorm"
 )
 
 type ProblemRepository struct {
@@ -31,17 +35,71 @@ func (r *ProblemRepository) GetProblems(limit int, page int) ([]domain.Problem,
 	return problems, lastPage, total, nil
 }
 
+// GetVisibleProblems lists the problems a user who isn't global staff can see at the given time.
+func (r *ProblemRepository) GetVisibleProblems(now time.Time, enrollment domain.Enrollment, limit int, page int) ([]domain.Problem, int, int, error) {
+	var problems []domain.Problem
+	query := r.visible(r.db.Preload("EditableFile").Preload("AllowLanguage"), now, enrollment)
+	lastPage, total, err := r.db.Paginate(&problems, query, limit, page, "id ASC")
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return problems, lastPage, total, nil
+}
+
+// GetVisibleProblemByID returns the problem if the user can see it at the given time, gorm.ErrRecordNotFound otherwise.
+func (r *ProblemRepository) GetVisibleProblemByID(id uint, now time.Time, enrollment domain.Enrollment) (domain.Problem, error) {
+	var problem domain.Problem
+	query := r.db.Preload("EditableFile").Preload("AllowLanguage").Preload("Testcases").Preload("Versions").Where("id = ?", id)
+	if err := r.visible(query, now, enrollment).First(&problem).Error; err != nil {
+		return problem, err
+	}
+	return problem, nil
+}
+
+// visible keeps the problems that Enrollment.CanSee would accept, leaving out those of contests that haven't
+// started unless the user teaches the course of the contest.
+func (r *ProblemRepository) visible(query *gorm.DB, now time.Time, enrollment domain.Enrollment) *gorm.DB {
+	published := r.db.Where("visibility = ? OR (visibility = ? AND publish_at <= ?)", domain.VisibilityPublished, domain.VisibilityScheduled, now)
+	member := r.db.Where("course_id IS NULL")
+	if len(enrollment.CourseIDs) > 0 {
+		member = member.Or("course_id IN ?", enrollment.CourseIDs)
+	}
+	upcoming := r.db.Table("contest_problems").Select("contest_problems.problem_id").
+		Joins("JOIN contests ON contests.id = contest_problems.contest_id AND contests.deleted_at IS NULL").
+		Where("contests.start_at > ?", now)
+	if len(enrollment.StaffCourseIDs) > 0 {
+		upcoming = upcoming.Where("contests.course_id IS NULL OR contests.course_id NOT IN ?", enrollment.StaffCourseIDs)
+	}
+	visible := r.db.Where(member).Where(published).Where("id NOT IN (?)", upcoming)
+	if len(enrollment.StaffCourseIDs) > 0 {
+		visible = visible.Or("course_id IN ?", enrollment.StaffCourseIDs)
+	}
+	return query.Where(visible)
+}
+
 func (r *ProblemRepository) GetProblemByID(id uint) (domain.Problem, error) {
-	var problem *domain.Problem
-	if err := r.db.Preload("EditableFile").Where("id = ?", id).First(&problem).Error; err != nil {
-		return *problem, err
+	var problem domain.Problem
+	if err := r.db.Preload("EditableFile").Preload("AllowLanguage").Preload("Testcases").Preload("Versions").Where("id = ?", id).First(&problem).Error; err != nil {
+		return problem, err
 	}
-	return *problem, nil
+	return problem, nil
+}
+
+// GetWithoutTestcases lists the problems that have no testcase definitions, which only
+// problems created before definitions were stored can lack.
+func (r *ProblemRepository) GetWithoutTestcases() ([]domain.Problem, error) {
+	var problems []domain.Problem
+	if err := r.db.Where("NOT EXISTS (SELECT 1 FROM problem_testcases WHERE problem_testcases.problem_id = problems.id AND problem_testcases.deleted_at IS NULL)").
+		Order("id ASC").
+		Find(&problems).Error; err != nil {
+		return nil, err
+	}
+	return problems, nil
 }
 
 func (r *ProblemRepository) UpdateProblem(id uint, updateProblem domain.Problem) (domain.Problem, error) {
 	var problem domain.Problem
-	if err := r.db.Model(&domain.Problem{}).Preload("EditableFile").Preload("AllowLanguage").Where("id = ?", id).Updates(updateProblem).First(&problem).Error; err != nil {
+	if err := r.db.Model(&domain.Problem{}).Preload("EditableFile").Preload("AllowLanguage").Preload("Testcases").Where("id = ?", id).Updates(updateProblem).First(&problem).Error; err != nil {
 		return problem, err
 	}
 	return problem, nil
@@ -53,3 +111,235 @@ func (r *ProblemRepository) DeleteProblem(id uint) error {
 	}
 	return nil
 }
+
+// UpdateScoring sets the scoring policy of a problem together with the weights and groups of its testcases.
+func (r *ProblemRepository) UpdateScoring(id uint, policy domain.ScoringPolicy, testcases []domain.ProblemTestcase) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Problem{}).Where("id = ?", id).Update("scoring_policy", policy).Error; err != nil {
+			return err
+		}
+		for _, testcase := range testcases {
+			result := tx.Model(&domain.ProblemTestcase{}).
+				Where("id = ? AND problem_id = ?", testcase.ID, id).
+				Updates(map[string]any{"weight": testcase.Weight, "group": testcase.Group})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+
+		// Only the current version is scored with the new weights, older ones keep theirs
+		var current domain.ProblemVersion
+		err := tx.Joins("JOIN problems ON problems.id = problem_versions.problem_id AND problems.current_version = problem_versions.version").
+			Where("problem_versions.problem_id = ?", id).
+			First(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return snapshotScoring(tx, current.ID)
+	})
+}
+
+func (r *ProblemRepository) UpdateFields(id uint, fields map[string]any) error {
+	if err := r.db.Model(&domain.Problem{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// Update writes every part of a problem update in one transaction, so a failure leaves the problem as it was.
+func (r *ProblemRepository) Update(id uint, update domain.ProblemUpdate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if update.Templates != nil {
+			if err := replaceTemplates(tx, id, update.Templates); err != nil {
+				return err
+			}
+		}
+		if update.Testcases != nil {
+			definitions, err := replaceTestcases(tx, id, update.Testcases)
+			if err != nil {
+				return err
+			}
+			if update.Version != nil {
+				update.Version.Testcases = definitions
+			}
+		}
+		if update.Version != nil {
+			if err := tx.Create(update.Version).Error; err != nil {
+				return err
+			}
+			if err := snapshotScoring(tx, update.Version.ID); err != nil {
+				return err
+			}
+		}
+		if update.Languages != nil {
+			problem := domain.Problem{}
+			problem.ID = id
+			if err := tx.Model(&problem).Association("AllowLanguage").Replace(update.Languages); err != nil {
+				return err
+			}
+		}
+		if len(update.Fields) > 0 {
+			if err := tx.Model(&domain.Problem{}).Where("id = ?", id).Updates(update.Fields).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReplaceTestcases swaps the testcase definitions of a problem after its project changed.
+// Definitions that are still there keep their ID and take the new weight and group; the others are soft deleted.
+func (r *ProblemRepository) ReplaceTestcases(id uint, testcases []domain.ProblemTestcase) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		_, err := replaceTestcases(tx, id, testcases)
+		return err
+	})
+}
+
+// replaceTestcases does the work of ReplaceTestcases and returns the definitions that are left.
+func replaceTestcases(tx *gorm.DB, id uint, testcases []domain.ProblemTestcase) ([]domain.ProblemTestcase, error) {
+	// Deleted definitions are looked at too, the key of a test that comes back is still taken
+	var existing []domain.ProblemTestcase
+	if err := tx.Unscoped().Where("problem_id = ?", id).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]domain.ProblemTestcase, len(existing))
+	for _, t := range existing {
+		byKey[t.Key] = t
+	}
+
+	definitions := make([]domain.ProblemTestcase, 0, len(testcases))
+	keep := make([]uint, 0, len(testcases))
+	for _, t := range testcases {
+		if old, ok := byKey[t.Key]; ok {
+			// The new project decides the weight and group, the definition only keeps its ID
+			old.Weight = t.Weight
+			old.Group = t.Group
+			old.DeletedAt = gorm.DeletedAt{}
+			if err := tx.Unscoped().Model(&old).Select("weight", "group", "deleted_at").Updates(&old).Error; err != nil {
+				return nil, err
+			}
+			definitions = append(definitions, old)
+			keep = append(keep, old.ID)
+			continue
+		}
+		t.ProblemID = id
+		if err := tx.Create(&t).Error; err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, t)
+		keep = append(keep, t.ID)
+	}
+
+	if err := tx.Where("problem_id = ? AND id NOT IN ?", id, keep).Delete(&domain.ProblemTestcase{}).Error; err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+func (r *ProblemRepository) CreateVersion(version *domain.ProblemVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return snapshotScoring(tx, version.ID)
+	})
+}
+
+// GetVersion returns a version of a problem with its testcase definitions, including deleted ones,
+// weighted the way they were in that version.
+func (r *ProblemRepository) GetVersion(problemID uint, version uint) (domain.ProblemVersion, error) {
+	var problemVersion domain.ProblemVersion
+	if err := r.db.Preload("Testcases", unscoped).Where("problem_id = ? AND version = ?", problemID, version).First(&problemVersion).Error; err != nil {
+		return problemVersion, err
+	}
+	if err := applyScoring(r.db.DB, &problemVersion); err != nil {
+		return problemVersion, err
+	}
+	return problemVersion, nil
+}
+
+// snapshotScoring keeps the current weight and group of every test of a version on its links,
+// definitions are shared between versions and may be weighted differently later.
+func snapshotScoring(tx *gorm.DB, versionID uint) error {
+	return tx.Exec(`UPDATE problem_version_testcases SET weight = problem_testcases.weight, "group" = problem_testcases."group"
+		FROM problem_testcases
+		WHERE problem_testcases.id = problem_version_testcases.problem_testcase_id AND problem_version_testcases.problem_version_id = ?`, versionID).Error
+}
+
+// applyScoring puts the weights and groups a version kept on its loaded testcases.
+func applyScoring(tx *gorm.DB, version *domain.ProblemVersion) error {
+	var links []domain.ProblemVersionTestcase
+	if err := tx.Where("problem_version_id = ?", version.ID).Find(&links).Error; err != nil {
+		return err
+	}
+	version.ApplyScoring(links)
+	return nil
+}
+
+// GetVersionsWithoutTestcases lists the versions uploaded before versions kept their testcase definitions.
+func (r *ProblemRepository) GetVersionsWithoutTestcases() ([]domain.ProblemVersion, error) {
+	var versions []domain.ProblemVersion
+	if err := r.db.Where("NOT EXISTS (SELECT 1 FROM problem_version_testcases WHERE problem_version_testcases.problem_version_id = problem_versions.id)").
+		Order("id ASC").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// SetVersionTestcases links a version to the definitions of the tests in its zip, matched by key.
+// A test the problem no longer has gets a deleted definition, the current ones are left as they are.
+// The version keeps the weights the definitions have now, the ones it was uploaded with are not known.
+func (r *ProblemRepository) SetVersionTestcases(version *domain.ProblemVersion, testcases []domain.ProblemTestcase) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing []domain.ProblemTestcase
+		if err := tx.Unscoped().Where("problem_id = ?", version.ProblemID).Find(&existing).Error; err != nil {
+			return err
+		}
+		byKey := make(map[string]domain.ProblemTestcase, len(existing))
+		for _, t := range existing {
+			byKey[t.Key] = t
+		}
+
+		definitions := make([]domain.ProblemTestcase, 0, len(testcases))
+		for _, t := range testcases {
+			if old, ok := byKey[t.Key]; ok {
+				definitions = append(definitions, old)
+				continue
+			}
+			t.ProblemID = version.ProblemID
+			if err := tx.Create(&t).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&t).Error; err != nil {
+				return err
+			}
+			definitions = append(definitions, t)
+		}
+		if err := tx.Model(version).Association("Testcases").Replace(definitions); err != nil {
+			return err
+		}
+		return snapshotScoring(tx, version.ID)
+	})
+}
+
+// UpdateValidationByReference records how a reference run went. Runs that have since been replaced by
+// a newer upload match no problem and change nothing.
+func (r *ProblemRepository) UpdateValidationByReference(submissionID uint, status domain.ValidationStatus) error {
+	if err := r.db.Model(&domain.Problem{}).Where("reference_submission_id = ?", submissionID).Update("validation_status", status).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// unscoped lets a preload include soft deleted rows.
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"github.com/yokeTH/our-grader-backend/api/pkg/mock"
+	"gorm.io/gorm"
+)
+
+// setupDB returns a database with the problem tables, removed again when the test ends.
+func setupDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := mock.SetupMockDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mock.CleanupMockDB)
+	if err := db.AutoMigrate(
+		&domain.Language{},
+		&domain.TemplateFile{},
+		&domain.Problem{},
+		&domain.ProblemTestcase{},
+		&domain.ProblemVersion{},
+		&domain.ProblemVersionTestcase{},
+		&domain.Contest{},
+		&domain.ContestParticipant{},
+	); err != nil {
+		t.Fatal(err)
+	}
+	return &database.Database{DB: db}
+}
+
+func TestProblemUpdateReuploadTakesNewWeights(t *testing.T) {
+	repo := NewProblemRepository(setupDB(t))
+
+	problem := domain.Problem{Name: "adder", CurrentVersion: 1, Testcases: []domain.ProblemTestcase{
+		{Key: "t.a", Name: "a", Weight: 1},
+		{Key: "t.b", Name: "b", Weight: 1},
+	}}
+	if err := repo.CreateProblem(&problem); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateVersion(&domain.ProblemVersion{ProblemID: problem.ID, Version: 1, Testcases: problem.Testcases}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new project weights t.a differently, drops t.b and adds t.c
+	err := repo.Update(problem.ID, domain.ProblemUpdate{
+		Fields:  map[string]any{"current_version": 2},
+		Version: &domain.ProblemVersion{ProblemID: problem.ID, Version: 2},
+		Testcases: []domain.ProblemTestcase{
+			{Key: "t.a", Name: "a", Weight: 3, Group: "basic"},
+			{Key: "t.c", Name: "c", Weight: 2},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := repo.GetProblemByID(problem.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weights := func(testcases []domain.ProblemTestcase) map[string]domain.ProblemTestcase {
+		byKey := make(map[string]domain.ProblemTestcase, len(testcases))
+		for _, tc := range testcases {
+			byKey[tc.Key] = tc
+		}
+		return byKey
+	}
+
+	current := weights(updated.Testcases)
+	if len(current) != 2 {
+		t.Fatalf("problem has testcases %+v, want t.a and t.c", updated.Testcases)
+	}
+	if a := current["t.a"]; a.ID != problem.Testcases[0].ID || a.Weight != 3 || a.Group != "basic" {
+		t.Errorf("t.a = ID %d weight %v group %q, want ID %d weight 3 group basic", a.ID, a.Weight, a.Group, problem.Testcases[0].ID)
+	}
+	if c := current["t.c"]; c.Weight != 2 {
+		t.Errorf("t.c weight = %v, want 2", c.Weight)
+	}
+
+	second, err := repo.GetVersion(problem.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := weights(second.Testcases)["t.a"]; a.Weight != 3 || a.Group != "basic" {
+		t.Errorf("version 2 t.a = weight %v group %q, want weight 3 group basic", a.Weight, a.Group)
+	}
+
+	// The first version is still scored the way it was uploaded
+	first, err := repo.GetVersion(problem.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := weights(first.Testcases)
+	if a := old["t.a"]; a.Weight != 1 || a.Group != "" {
+		t.Errorf("version 1 t.a = weight %v group %q, want weight 1 without a group", a.Weight, a.Group)
+	}
+	if _, ok := old["t.b"]; !ok {
+		t.Errorf("version 1 lost t.b: %+v", first.Testcases)
+	}
+}
+
+func TestVisibleProblemsHideUpcomingContests(t *testing.T) {
+	db := setupDB(t)
+	repo := NewProblemRepository(db)
+
+	courseID := uint(7)
+	start := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	problems := make([]domain.Problem, 3)
+	for i := range problems {
+		problems[i] = domain.Problem{Name: fmt.Sprintf("p%d", i+1), Visibility: domain.VisibilityPublished}
+		if err := repo.CreateProblem(&problems[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	contests := []domain.Contest{
+		{Name: "upcoming", CourseID: &courseID, StartAt: start, EndAt: start.Add(time.Hour), Problems: problems[:1]},
+		{Name: "running", StartAt: start.Add(-time.Hour), EndAt: start.Add(time.Hour), Problems: problems[1:2]},
+	}
+	if err := db.Create(&contests).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	student := domain.Enrollment{CourseIDs: []uint{courseID}}
+	staff := domain.Enrollment{CourseIDs: []uint{courseID}, StaffCourseIDs: []uint{courseID}}
+	tests := []struct {
+		name       string
+		now        time.Time
+		enrollment domain.Enrollment
+		want       []string
+	}{
+		{name: "student before the start", now: start.Add(-time.Minute), enrollment: student, want: []string{"p2", "p3"}},
+		{name: "staff of the contest course", now: start.Add(-time.Minute), enrollment: staff, want: []string{"p1", "p2", "p3"}},
+		{name: "student once it started", now: start, enrollment: student, want: []string{"p1", "p2", "p3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listed, _, _, err := repo.GetVisibleProblems(tt.now, tt.enrollment, 10, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, p := range listed {
+				names = append(names, p.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("listed %v, want %v", names, tt.want)
+			}
+
+			_, err = repo.GetVisibleProblemByID(problems[0].ID, tt.now, tt.enrollment)
+			if visible := slices.Contains(tt.want, "p1"); visible != (err == nil) {
+				t.Errorf("GetVisibleProblemByID(p1) = %v, want visible %v", err, visible)
+			}
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Errorf("GetVisibleProblemByID(p1) = %v, want gorm.ErrRecordNotFound", err)
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
 	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"gorm.io/gorm"
 )
 
 type SubmissionRepository struct {
@@ -13,11 +16,15 @@ func NewSubmissionRepository(db *database.Database) *SubmissionRepository {
 	return &SubmissionRepository{db: db}
 }
 
-func (r *SubmissionRepository) Create(s *domain.Submission) error {
-	if err := r.db.Create(s).Error; err != nil {
-		return err
-	}
-	return nil
+// Create inserts a submission together with the job that grades it, so a submission is never left without one.
+func (r *SubmissionRepository) Create(s *domain.Submission, job *domain.GradingJob) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(s).Error; err != nil {
+			return err
+		}
+		job.SubmissionID = s.ID
+		return tx.Create(job).Error
+	})
 }
 
 func (r *SubmissionRepository) GetSubmissionsByID(id uint) (domain.Submission, error) {
@@ -27,11 +34,20 @@ func (r *SubmissionRepository) GetSubmissionsByID(id uint) (domain.Submission, e
 		Preload("SubmissionFile.TemplateFile").
 		Preload("Language").
 		Preload("Problem").
+		Preload("Problem.Testcases").
+		Preload("ProblemVersion").
+		Preload("ProblemVersion.Testcases", unscoped).
 		Preload("Testcases").
+		Preload("Diagnostics").
 		Where("id = ?", id).
 		First(&submissions).Error; err != nil {
 		return submissions, err
 	}
+	if submissions.ProblemVersion != nil {
+		if err := applyScoring(r.db.DB, submissions.ProblemVersion); err != nil {
+			return submissions, err
+		}
+	}
 	return submissions, nil
 }
 
@@ -41,8 +57,10 @@ func (r *SubmissionRepository) GetSubmissionsByUserIDAndProblemID(email string,
 		Preload("Language").
 		Preload("Problem").
 		Preload("Testcases").
+		Preload("Diagnostics").
 		Where("submission_by = ?", email).
-		Where("problem_id = ?", pid)
+		Where("problem_id = ?", pid).
+		Where("reference = ?", false)
 	lastPage, total, err := r.db.Paginate(&submissions, query, limit, page, "id DESC")
 	if err != nil {
 		return nil, 0, 0, err
@@ -56,3 +74,215 @@ func (r *SubmissionRepository) Update(data *domain.Submission) error {
 	}
 	return nil
 }
+
+func (r *SubmissionRepository) UpdateStatus(id uint, status domain.SubmissionStatus) error {
+	if err := r.db.Model(&domain.Submission{}).Where("id = ?", id).Update("status", status).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *SubmissionRepository) UpdateResult(id uint, status domain.SubmissionStatus, verdict domain.Verdict) error {
+	if err := r.db.Model(&domain.Submission{}).Where("id = ?", id).Updates(map[string]any{
+		"status":  status,
+		"verdict": verdict,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReplaceDiagnostics swaps the compiler diagnostics of a submission, so a regrade doesn't keep stale ones.
+func (r *SubmissionRepository) ReplaceDiagnostics(id uint, diagnostics []domain.Diagnostic) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("submission_id = ?", id).Delete(&domain.Diagnostic{}).Error; err != nil {
+			return err
+		}
+		if len(diagnostics) == 0 {
+			return nil
+		}
+		for i := range diagnostics {
+			diagnostics[i].SubmissionID = id
+		}
+		return tx.Create(&diagnostics).Error
+	})
+}
+
+func (r *SubmissionRepository) UpdateScore(id uint, score float64, maxScore float64) error {
+	if err := r.db.Model(&domain.Submission{}).Where("id = ?", id).Updates(map[string]any{
+		"score":     score,
+		"max_score": maxScore,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetBestScores returns the best graded score of a user for each of the problems they submitted to.
+func (r *SubmissionRepository) GetBestScores(email string, problemIDs []uint) (map[uint]float64, error) {
+	var rows []struct {
+		ProblemID uint
+		Score     float64
+	}
+	if err := r.db.Model(&domain.Submission{}).
+		Select("problem_id, MAX(score) AS score").
+		Where("submission_by = ?", email).
+		Where("problem_id IN ?", problemIDs).
+		Where("status = ?", domain.SubmissionGraded).
+		Where("reference = ?", false).
+		Group("problem_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	scores := make(map[uint]float64, len(rows))
+	for _, row := range rows {
+		scores[row.ProblemID] = row.Score
+	}
+	return scores, nil
+}
+
+// GetIDsByProblemID lists the submissions of a problem, leaving out reference runs.
+func (r *SubmissionRepository) GetIDsByProblemID(problemID uint) ([]uint, error) {
+	var ids []uint
+	if err := r.db.Model(&domain.Submission{}).Where("problem_id = ? AND reference = ?", problemID, false).Order("id ASC").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ResetForRegrade points a submission at another problem version and clears its results.
+// The testcase rows are recreated from the definitions of that version.
+// It reports false and leaves the submission alone while a worker is grading it,
+// the worker would overwrite the reset with the results of the old version.
+func (r *SubmissionRepository) ResetForRegrade(id uint, problemVersionID uint, definitions []domain.ProblemTestcase) (bool, error) {
+	reset := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Claim takes the same lock, so no worker starts on the submission until the reset is done
+		if err := lockSubmissionJobs(tx, id); err != nil {
+			return err
+		}
+		running, err := hasRunningJob(tx, id)
+		if err != nil || running {
+			return err
+		}
+
+		if err := tx.Model(&domain.Submission{}).Where("id = ?", id).Updates(map[string]any{
+			"problem_version_id": problemVersionID,
+			"status":             domain.SubmissionQueued,
+			"verdict":            domain.VerdictPending,
+			"score":              0,
+			"max_score":          0,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("submission_id = ?", id).Delete(&domain.Testcase{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("submission_id = ?", id).Delete(&domain.Diagnostic{}).Error; err != nil {
+			return err
+		}
+		reset = true
+		if len(definitions) == 0 {
+			return nil
+		}
+
+		testcases := make([]domain.Testcase, len(definitions))
+		for i, definition := range definitions {
+			testcases[i] = domain.Testcase{Name: definition.Key, ProblemTestcaseID: definition.ID, SubmissionID: id}
+		}
+		return tx.Create(&testcases).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return reset, nil
+}
+
+// GetByProblemIDs returns the submissions to the problems, oldest first, leaving out reference runs.
+func (r *SubmissionRepository) GetByProblemIDs(problemIDs []uint) ([]domain.Submission, error) {
+	var submissions []domain.Submission
+	if err := r.db.
+		Select("id", "created_at", "submission_by", "problem_id", "score", "max_score", "status", "verdict").
+		Where("problem_id IN ?", problemIDs).
+		Where("reference = ?", false).
+		Order("id ASC").
+		Find(&submissions).Error; err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+// GetBetween returns the submissions to the problems made in the time range, with their testcase results,
+// oldest first. Reference runs are left out.
+func (r *SubmissionRepository) GetBetween(problemIDs []uint, from time.Time, to time.Time) ([]domain.Submission, error) {
+	var submissions []domain.Submission
+	if err := r.db.
+		Select("id", "created_at", "submission_by", "problem_id", "score", "max_score", "status", "verdict").
+		Preload("Testcases", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "submission_id", "result")
+		}).
+		Where("problem_id IN ?", problemIDs).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Where("reference = ?", false).
+		Order("created_at ASC, id ASC").
+		Find(&submissions).Error; err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+// Search returns up to limit submissions matching the filter, in the order it asks for.
+func (r *SubmissionRepository) Search(filter domain.SubmissionFilter, limit int) ([]domain.Submission, error) {
+	query := r.db.Preload("Problem", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "name", "course_id")
+	}).Where("reference = ?", false)
+
+	if filter.ProblemID != 0 {
+		query = query.Where("problem_id = ?", filter.ProblemID)
+	}
+	if filter.SubmissionBy != "" {
+		query = query.Where("submission_by = ?", filter.SubmissionBy)
+	}
+	if filter.Verdict != "" {
+		query = query.Where("verdict = ?", filter.Verdict)
+	}
+	if filter.LanguageName != "" {
+		query = query.Where("language_name = ?", filter.LanguageName)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.MinScore != nil {
+		query = query.Where("score >= ?", *filter.MinScore)
+	}
+	if filter.MaxScore != nil {
+		query = query.Where("score <= ?", *filter.MaxScore)
+	}
+
+	// Keyset pagination: continue strictly after the cursor in the sort order
+	cmp, dir := "<", "DESC"
+	if filter.Ascending {
+		cmp, dir = ">", "ASC"
+	}
+	if filter.SortByScore {
+		if filter.After != nil {
+			query = query.Where("score "+cmp+" ? OR (score = ? AND id "+cmp+" ?)", filter.After.Score, filter.After.Score, filter.After.ID)
+		}
+		query = query.Order("score " + dir).Order("id " + dir)
+	} else {
+		if filter.After != nil {
+			query = query.Where("id "+cmp+" ?", filter.After.ID)
+		}
+		query = query.Order("id " + dir)
+	}
+
+	var submissions []domain.Submission
+	if err := query.Limit(limit).Find(&submissions).Error; err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
 	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"gorm.io/gorm"
 )
 
 type TemplateFileRepository struct {
@@ -26,3 +27,15 @@ func (r *TemplateFileRepository) CreateMany(template []*domain.TemplateFile) err
 	}
 	return nil
 }
+
+// replaceTemplates swaps the template files of a problem.
+// The old rows are soft deleted because past submission files still refer to them.
+func replaceTemplates(tx *gorm.DB, problemID uint, templates []*domain.TemplateFile) error {
+	if err := tx.Where("problem_id = ?", problemID).Delete(&domain.TemplateFile{}).Error; err != nil {
+		return err
+	}
+	if len(templates) == 0 {
+		return nil
+	}
+	return tx.Create(templates).Error
+}
@@ -52,7 +52,10 @@ func (r *TestcaseRepository) GetTestcasesBySubmissionID(submissionID uint) ([]do
 }
 
 func (r *TestcaseRepository) UpdateTestcase(testcase *domain.Testcase) error {
-	err := r.db.Model(&domain.Testcase{}).Where("id = ?", testcase.ID).Updates(testcase).Error
+	// Select the result columns so a regrade can clear a message left by the previous run
+	err := r.db.Model(&domain.Testcase{}).Where("id = ?", testcase.ID).
+		Select("result", "wall_time_sec", "sim_time_ns", "message").
+		Updates(testcase).Error
 	if err != nil {
 		return err
 	}
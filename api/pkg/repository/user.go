@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"gorm.io/gorm/clause"
+)
+
+type UserRepository struct {
+	db *database.Database
+}
+
+func NewUserRepository(db *database.Database) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// Upsert creates the user on first sign in and keeps the name and picture up to date afterwards.
+func (r *UserRepository) Upsert(user *domain.User) error {
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "picture", "hosted_domain", "updated_at"}),
+	}).Omit("Roles").Create(user).Error; err != nil {
+		return err
+	}
+	return r.db.Preload("Roles").Where("email = ?", user.Email).First(user).Error
+}
+
+// FindOrCreateByEmail returns the user with the email, creating an empty one for someone who hasn't signed in yet.
+func (r *UserRepository) FindOrCreateByEmail(email string) (domain.User, error) {
+	user := domain.User{Email: email}
+	if err := r.db.Preload("Roles").Where(domain.User{Email: email}).FirstOrCreate(&user).Error; err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// GetByEmail returns the user with the email, gorm.ErrRecordNotFound if nobody has it.
+func (r *UserRepository) GetByEmail(email string) (domain.User, error) {
+	var user domain.User
+	if err := r.db.Preload("Roles").Where("email = ?", email).First(&user).Error; err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+func (r *UserRepository) GetUsers(limit int, page int) ([]domain.User, int, int, error) {
+	var users []domain.User
+	query := r.db.Preload("Roles")
+	lastPage, total, err := r.db.Paginate(&users, query, limit, page, "id ASC")
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return users, lastPage, total, nil
+}
+
+func (r *UserRepository) GrantRole(userID uint, role domain.Role, grantedBy string) error {
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&domain.UserRole{
+		UserID:    userID,
+		Role:      role,
+		GrantedBy: grantedBy,
+	}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// RevokeRole removes the role for good, so granting it again later starts from a clean row.
+func (r *UserRepository) RevokeRole(userID uint, role domain.Role) (int64, error) {
+	result := r.db.Unscoped().Where("user_id = ? AND role = ?", userID, role).Delete(&domain.UserRole{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *UserRepository) CountByRole(role domain.Role) (int64, error) {
+	var count int64
+	if err := r.db.Model(&domain.UserRole{}).Where("role = ?", role).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
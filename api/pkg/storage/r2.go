@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type R2Config struct {
@@ -89,6 +90,42 @@ func (s *R2Storage) DeleteFile(ctx context.Context, key string) error {
 	return err
 }
 
+// DeleteFolder deletes every object whose key starts with prefix.
+func (s *R2Storage) DeleteFolder(ctx context.Context, prefix string) error {
+	paginator := s3.NewListObjectsV2Paginator(s.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.BucketName),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		if len(page.Contents) == 0 {
+			continue
+		}
+
+		// A page holds at most 1000 keys, which is also the limit of a single DeleteObjects call
+		objects := make([]types.ObjectIdentifier, len(page.Contents))
+		for i, obj := range page.Contents {
+			objects[i] = types.ObjectIdentifier{Key: obj.Key}
+		}
+		out, err := s.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.BucketName),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.Errors) > 0 {
+			return fmt.Errorf("delete %s: %s", aws.ToString(out.Errors[0].Key), aws.ToString(out.Errors[0].Message))
+		}
+	}
+
+	return nil
+}
+
 func (s *R2Storage) GetFile(ctx context.Context, key string) (io.ReadCloser, error) {
 	obj, err := s.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.BucketName),
@@ -20,4 +20,5 @@ type IStorage interface {
 	GetPublicUrl(key string) string
 	GetFile(ctx context.Context, key string) (io.ReadCloser, error)
 	DeleteFile(ctx context.Context, key string) error
+	DeleteFolder(ctx context.Context, prefix string) error
 }
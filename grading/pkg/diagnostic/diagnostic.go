@@ -0,0 +1,125 @@
+package diagnostic
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxDiagnostics = 50
+const maxMessageLength = 500
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type Diagnostic struct {
+	File     string
+	Line     int
+	Severity Severity
+	Message  string
+}
+
+var (
+	// iverilog: "../hdl/adder.v:12: syntax error" or "../hdl/adder.v:5: error: Unknown module type: foo"
+	iverilogPattern = regexp.MustCompile(`^(\S+?\.s?vh?):(\d+):\s*(?:(error|warning|sorry):\s*)?(.*)$`)
+	// verilator: "%Error: ../hdl/adder.v:12:5: syntax error, unexpected ')'"
+	verilatorPattern = regexp.MustCompile(`^%(Error|Warning)(?:-\w+)?:\s*(\S+?\.s?vh?):(\d+):(?:\d+:)?\s*(.*)$`)
+)
+
+// Parse extracts compiler diagnostics from the build output.
+// Files are reported by the name of the matching template file, so students see
+// the names they edited instead of paths inside the grader.
+func Parse(output string, templateFiles []string) []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	seen := make(map[Diagnostic]bool)
+
+	for _, line := range strings.Split(output, "\n") {
+		if len(diagnostics) >= maxDiagnostics {
+			break
+		}
+
+		d, ok := parseLine(strings.TrimSpace(line))
+		if !ok {
+			continue
+		}
+		d.File = matchTemplate(d.File, templateFiles)
+		d.Message = truncate(d.Message, maxMessageLength)
+
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		diagnostics = append(diagnostics, d)
+	}
+
+	return diagnostics
+}
+
+// HasErrors reports whether any of the diagnostics is an error rather than a warning.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func parseLine(line string) (Diagnostic, bool) {
+	if m := verilatorPattern.FindStringSubmatch(line); m != nil {
+		lineNo, _ := strconv.Atoi(m[3])
+		severity := SeverityError
+		if m[1] == "Warning" {
+			severity = SeverityWarning
+		}
+		return Diagnostic{File: m[2], Line: lineNo, Severity: severity, Message: m[4]}, true
+	}
+
+	if m := iverilogPattern.FindStringSubmatch(line); m != nil {
+		lineNo, _ := strconv.Atoi(m[2])
+		severity := SeverityError
+		message := m[4]
+		switch m[3] {
+		case "warning":
+			severity = SeverityWarning
+		case "":
+			// Messages such as "syntax error" come without a severity prefix
+			if strings.Contains(message, "warning") {
+				severity = SeverityWarning
+			}
+		}
+		return Diagnostic{File: m[1], Line: lineNo, Severity: severity, Message: message}, true
+	}
+
+	return Diagnostic{}, false
+}
+
+// truncate shortens s to at most n bytes without cutting a character in half.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// matchTemplate maps a path printed by the compiler to the template file it refers to.
+// Other files, such as the testbench, are reduced to their base name.
+func matchTemplate(path string, templateFiles []string) string {
+	path = filepath.ToSlash(filepath.Clean(path))
+	for _, name := range templateFiles {
+		name = filepath.ToSlash(filepath.Clean(name))
+		if path == name || strings.HasSuffix(path, "/"+name) {
+			return name
+		}
+	}
+	return filepath.Base(path)
+}
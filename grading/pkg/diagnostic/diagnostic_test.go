@@ -0,0 +1,107 @@
+package diagnostic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParse(t *testing.T) {
+	templates := []string{"hdl/adder.v", "hdl/alu.sv"}
+
+	tests := []struct {
+		name   string
+		output string
+		want   []Diagnostic
+	}{
+		{
+			name:   "iverilog syntax error without severity",
+			output: "../hdl/adder.v:12: syntax error\n../hdl/adder.v:12: error: Invalid module instantiation\n",
+			want: []Diagnostic{
+				{File: "hdl/adder.v", Line: 12, Severity: SeverityError, Message: "syntax error"},
+				{File: "hdl/adder.v", Line: 12, Severity: SeverityError, Message: "Invalid module instantiation"},
+			},
+		},
+		{
+			name:   "iverilog warning",
+			output: "../hdl/adder.v:7: warning: Port 2 (b) of adder expects 8 bits, got 4.\n",
+			want: []Diagnostic{
+				{File: "hdl/adder.v", Line: 7, Severity: SeverityWarning, Message: "Port 2 (b) of adder expects 8 bits, got 4."},
+			},
+		},
+		{
+			name:   "iverilog sorry is an error",
+			output: "../hdl/alu.sv:30: sorry: constant selects in always_* processes are not currently supported (all bits will be included).\n",
+			want: []Diagnostic{
+				{File: "hdl/alu.sv", Line: 30, Severity: SeverityError, Message: "constant selects in always_* processes are not currently supported (all bits will be included)."},
+			},
+		},
+		{
+			name:   "iverilog testbench path",
+			output: "/tmp/grader/42/project/cocotb/tb_top.v:5: error: Unknown module type: adder\n2 error(s) during elaboration.\n",
+			want: []Diagnostic{
+				{File: "tb_top.v", Line: 5, Severity: SeverityError, Message: "Unknown module type: adder"},
+			},
+		},
+		{
+			name:   "verilator error with column",
+			output: "%Error: ../hdl/adder.v:12:5: syntax error, unexpected ')'\n%Error: Exiting due to 1 error(s)\n",
+			want: []Diagnostic{
+				{File: "hdl/adder.v", Line: 12, Severity: SeverityError, Message: "syntax error, unexpected ')'"},
+			},
+		},
+		{
+			name:   "verilator lint warning",
+			output: "%Warning-WIDTH: ../hdl/adder.v:8:14: Operator ASSIGNW expects 8 bits on the Assign RHS, but Assign RHS's ADD generates 9 bits.\n",
+			want: []Diagnostic{
+				{File: "hdl/adder.v", Line: 8, Severity: SeverityWarning, Message: "Operator ASSIGNW expects 8 bits on the Assign RHS, but Assign RHS's ADD generates 9 bits."},
+			},
+		},
+		{
+			name:   "duplicates and unrelated lines",
+			output: "make[1]: Entering directory '/project/cocotb'\n  ../hdl/adder.v:3: syntax error\n../hdl/adder.v:3: syntax error\nI give up.\n",
+			want: []Diagnostic{
+				{File: "hdl/adder.v", Line: 3, Severity: SeverityError, Message: "syntax error"},
+			},
+		},
+		{
+			name:   "nothing to report",
+			output: "cocotb.regression running test_adder.adder_basic_test (1/1)\n",
+			want:   []Diagnostic{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.output, templates)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTruncatesOnCharacterBoundary(t *testing.T) {
+	message := strings.Repeat("a", maxMessageLength-1) + "é"
+	got := Parse("../hdl/adder.v:1: error: "+message, nil)
+	if len(got) != 1 {
+		t.Fatalf("Parse() returned %d diagnostics, want 1", len(got))
+	}
+	if want := strings.Repeat("a", maxMessageLength-1); got[0].Message != want {
+		t.Errorf("message has %d bytes, want %d", len(got[0].Message), len(want))
+	}
+	if !utf8.ValidString(got[0].Message) {
+		t.Error("message is not valid UTF-8")
+	}
+}
+
+func TestHasErrors(t *testing.T) {
+	warning := Diagnostic{Severity: SeverityWarning}
+	if HasErrors([]Diagnostic{warning}) {
+		t.Error("HasErrors() = true for warnings only")
+	}
+	if !HasErrors([]Diagnostic{warning, {Severity: SeverityError}}) {
+		t.Error("HasErrors() = false with an error")
+	}
+}
@@ -0,0 +1,72 @@
+package event
+
+import (
+	"sync"
+
+	"github.com/yokeTH/our-grader-backend/proto/verilog"
+)
+
+const subscriberBuffer = 64
+
+// Broker fans grading events out to everyone watching a submission.
+// It remembers the latest stage of each running submission so a new watcher
+// immediately learns where a grading run currently is.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[uint32]map[chan *verilog.GradingEvent]struct{}
+	stages map[uint32]*verilog.GradingEvent
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subs:   make(map[uint32]map[chan *verilog.GradingEvent]struct{}),
+		stages: make(map[uint32]*verilog.GradingEvent),
+	}
+}
+
+// Publish delivers ev to the current subscribers of its submission.
+// Slow subscribers miss events instead of blocking the grader.
+func (b *Broker) Publish(ev *verilog.GradingEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch ev.Event.(type) {
+	case *verilog.GradingEvent_Stage:
+		b.stages[ev.SubmissionID] = ev
+	case *verilog.GradingEvent_Summary:
+		delete(b.stages, ev.SubmissionID)
+	}
+
+	for ch := range b.subs[ev.SubmissionID] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel with the events of the given submission and a function to stop receiving them.
+func (b *Broker) Subscribe(submissionID uint32) (<-chan *verilog.GradingEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan *verilog.GradingEvent, subscriberBuffer)
+	if b.subs[submissionID] == nil {
+		b.subs[submissionID] = make(map[chan *verilog.GradingEvent]struct{})
+	}
+	b.subs[submissionID][ch] = struct{}{}
+
+	if ev, ok := b.stages[submissionID]; ok {
+		ch <- ev
+	}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subs[submissionID], ch)
+		if len(b.subs[submissionID]) == 0 {
+			delete(b.subs, submissionID)
+		}
+	}
+}
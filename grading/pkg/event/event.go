@@ -0,0 +1,37 @@
+package event
+
+import (
+	"github.com/yokeTH/our-grader-backend/proto/verilog"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Emitter receives the events produced while grading a submission.
+type Emitter func(ev *verilog.GradingEvent)
+
+func Stage(submissionID uint, stage verilog.Stage) *verilog.GradingEvent {
+	return &verilog.GradingEvent{
+		SubmissionID: uint32(submissionID),
+		Time:         timestamppb.Now(),
+		Event:        &verilog.GradingEvent_Stage{Stage: &verilog.StageChanged{Stage: stage}},
+	}
+}
+
+func Testcase(submissionID uint, classname string, name string, passed bool) *verilog.GradingEvent {
+	return &verilog.GradingEvent{
+		SubmissionID: uint32(submissionID),
+		Time:         timestamppb.Now(),
+		Event: &verilog.GradingEvent_Testcase{Testcase: &verilog.TestcaseFinished{
+			Classname: classname,
+			Name:      name,
+			Passed:    passed,
+		}},
+	}
+}
+
+func Summary(submissionID uint, summary *verilog.GradingSummary) *verilog.GradingEvent {
+	return &verilog.GradingEvent{
+		SubmissionID: uint32(submissionID),
+		Time:         timestamppb.Now(),
+		Event:        &verilog.GradingEvent_Summary{Summary: summary},
+	}
+}
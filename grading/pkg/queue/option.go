@@ -0,0 +1,40 @@
+package queue
+
+import "time"
+
+type WorkerOption func(*Worker)
+
+// WithConcurrency sets how many jobs are processed at the same time
+func WithConcurrency(n int) WorkerOption {
+	return func(w *Worker) {
+		w.concurrency = n
+	}
+}
+
+// WithPollInterval sets how long an idle worker waits before polling again
+func WithPollInterval(d time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.pollInterval = d
+	}
+}
+
+// WithLeaseTimeout sets how long a running job may be held before it is handed to another worker
+func WithLeaseTimeout(d time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.leaseTimeout = d
+	}
+}
+
+// WithRetryBackoff sets the base delay between attempts
+func WithRetryBackoff(d time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.retryBackoff = d
+	}
+}
+
+// WithFailureHandler sets the callback for jobs that have used up their attempts
+func WithFailureHandler(h FailureHandler) WorkerOption {
+	return func(w *Worker) {
+		w.onFailure = h
+	}
+}
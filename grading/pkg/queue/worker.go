@@ -0,0 +1,172 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
+	"github.com/yokeTH/our-grader-backend/api/pkg/core/port"
+)
+
+// Handler processes a single claimed job. Returning an error schedules a retry.
+type Handler func(ctx context.Context, job domain.GradingJob) error
+
+// FailureHandler is called once a job has failed its last attempt.
+type FailureHandler func(ctx context.Context, job domain.GradingJob, err error)
+
+const defaultConcurrency = 1
+const defaultPollInterval = 2 * time.Second
+const defaultLeaseTimeout = 5 * time.Minute
+const defaultRetryBackoff = 30 * time.Second
+
+type Worker struct {
+	repo         port.GradingJobRepository
+	handler      Handler
+	onFailure    FailureHandler
+	concurrency  int
+	pollInterval time.Duration
+	leaseTimeout time.Duration
+	retryBackoff time.Duration
+}
+
+// NewWorker creates a worker that pulls jobs from repo and passes them to handler.
+//
+// Default values are:
+//   - Concurrency: 1
+//   - PollInterval: 2s
+//   - LeaseTimeout: 5m
+//   - RetryBackoff: 30s, multiplied by the number of attempts so far
+func NewWorker(repo port.GradingJobRepository, handler Handler, opts ...WorkerOption) *Worker {
+	w := &Worker{
+		repo:         repo,
+		handler:      handler,
+		concurrency:  defaultConcurrency,
+		pollInterval: defaultPollInterval,
+		leaseTimeout: defaultLeaseTimeout,
+		retryBackoff: defaultRetryBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Start runs the worker until ctx is cancelled.
+func (w *Worker) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.requeueStale(ctx)
+	}()
+
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := w.repo.Claim(time.Now())
+		if err != nil {
+			log.Printf("queue: claim job failed: %v", err)
+		}
+		if err != nil || job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.pollInterval):
+			}
+			continue
+		}
+
+		w.process(ctx, *job)
+	}
+}
+
+func (w *Worker) process(ctx context.Context, job domain.GradingJob) {
+	err := w.run(ctx, job)
+	if err == nil {
+		held, err := w.repo.Complete(&job)
+		if err != nil {
+			log.Printf("queue: complete job %d failed: %v", job.ID, err)
+		} else if !held {
+			log.Printf("queue: job %d was requeued before it completed", job.ID)
+		}
+		return
+	}
+
+	log.Printf("queue: job %d for submission %d failed on attempt %d/%d: %v", job.ID, job.SubmissionID, job.Attempts, job.MaxAttempts, err)
+
+	retryAt := time.Now().Add(time.Duration(job.Attempts) * w.retryBackoff)
+	held, failErr := w.repo.Fail(&job, err, retryAt)
+	if failErr != nil {
+		log.Printf("queue: record failure of job %d failed: %v", job.ID, failErr)
+		return
+	}
+	if !held {
+		log.Printf("queue: job %d was requeued before it failed", job.ID)
+		return
+	}
+
+	if job.Status == domain.GradingJobFailed {
+		w.fail(ctx, job, err)
+	}
+}
+
+func (w *Worker) fail(ctx context.Context, job domain.GradingJob, err error) {
+	if w.onFailure != nil {
+		w.onFailure(ctx, job, err)
+	}
+}
+
+// run calls the handler and turns a panic into an error so one bad job can't take the worker down.
+func (w *Worker) run(ctx context.Context, job domain.GradingJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return w.handler(ctx, job)
+}
+
+func (w *Worker) requeueStale(ctx context.Context) {
+	ticker := time.NewTicker(w.leaseTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		n, failed, err := w.repo.RequeueStale(time.Now().Add(-w.leaseTimeout))
+		if err != nil {
+			log.Printf("queue: requeue stale jobs failed: %v", err)
+		} else if n > 0 {
+			log.Printf("queue: requeued %d stale jobs", n)
+		}
+		for _, job := range failed {
+			log.Printf("queue: job %d for submission %d timed out on its last attempt", job.ID, job.SubmissionID)
+			w.fail(ctx, job, errors.New(job.LastError))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
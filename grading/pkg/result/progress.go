@@ -0,0 +1,94 @@
+package result
+
+import (
+	"bytes"
+	_ "embed"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+type ProgressKind int
+
+const (
+	ProgressTestStarted ProgressKind = iota
+	ProgressTestPassed
+	ProgressTestFailed
+)
+
+//go:embed sitecustomize.py
+var progressHook []byte
+
+// InstallProgressHook writes the Python module that reports cocotb progress to dir.
+// Simulations that have dir on their PYTHONPATH write their regression log to the sandbox progress pipe.
+func InstallProgressHook(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "sitecustomize.py"), progressHook, 0o644)
+}
+
+// Progress is a single test event read from the cocotb regression log.
+type Progress struct {
+	Kind      ProgressKind
+	Classname string
+	Name      string
+}
+
+const maxLineLength = 64 * 1024
+
+// cocotb logs "running test_adder.adder_basic_test (1/2)" before a test
+// and "test_adder.adder_basic_test passed" (or failed) after it.
+var (
+	runningPattern  = regexp.MustCompile(`cocotb\.regression\s+running\s+(\S+)\.(\w+)`)
+	finishedPattern = regexp.MustCompile(`cocotb\.regression\s+(\S+)\.(\w+)\s+(passed|failed)`)
+)
+
+func ParseProgressLine(line string) (Progress, bool) {
+	if m := runningPattern.FindStringSubmatch(line); m != nil {
+		return Progress{Kind: ProgressTestStarted, Classname: m[1], Name: m[2]}, true
+	}
+	if m := finishedPattern.FindStringSubmatch(line); m != nil {
+		kind := ProgressTestPassed
+		if m[3] == "failed" {
+			kind = ProgressTestFailed
+		}
+		return Progress{Kind: kind, Classname: m[1], Name: m[2]}, true
+	}
+	return Progress{}, false
+}
+
+// ProgressWriter is an io.Writer that reads the regression log written by the progress hook
+// line by line and reports every cocotb test event it recognises.
+type ProgressWriter struct {
+	mu     sync.Mutex
+	buf    []byte
+	report func(Progress)
+}
+
+func NewProgressWriter(report func(Progress)) *ProgressWriter {
+	return &ProgressWriter{report: report}
+}
+
+func (w *ProgressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if progress, ok := ParseProgressLine(string(w.buf[:i])); ok {
+			w.report(progress)
+		}
+		w.buf = w.buf[i+1:]
+	}
+	// A line this long is not a cocotb log line, don't keep buffering it
+	if len(w.buf) > maxLineLength {
+		w.buf = w.buf[:0]
+	}
+	return len(p), nil
+}
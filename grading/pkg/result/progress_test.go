@@ -0,0 +1,33 @@
+package result
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProgressWriter(t *testing.T) {
+	var got []Progress
+	w := NewProgressWriter(func(p Progress) { got = append(got, p) })
+
+	// Lines may arrive split across writes
+	for _, chunk := range []string{
+		"cocotb.regression running test_adder.adder_basic_test (1/2)\n",
+		"cocotb.regression test_adder.adder_basic",
+		"_test passed\ncocotb.regression some other message\n",
+		"cocotb.regression test_adder.adder_random_test failed\n",
+		"cocotb.regression test_adder.adder_overflow_test passed",
+	} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []Progress{
+		{Kind: ProgressTestStarted, Classname: "test_adder", Name: "adder_basic_test"},
+		{Kind: ProgressTestPassed, Classname: "test_adder", Name: "adder_basic_test"},
+		{Kind: ProgressTestFailed, Classname: "test_adder", Name: "adder_random_test"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -2,6 +2,7 @@ package result
 
 import (
 	"encoding/xml"
+	"strings"
 )
 
 type Testsuites struct {
@@ -34,9 +35,50 @@ type Testcase struct {
 	SimTimeNs float64  `xml:"sim_time_ns,attr"`
 	RatioTime float64  `xml:"ratio_time,attr"`
 	Failure   *Failure `xml:"failure,omitempty"`
+	Error     *Failure `xml:"error,omitempty"`
+	Skipped   *Skipped `xml:"skipped,omitempty"`
+	SystemOut string   `xml:"system-out,omitempty"`
+	SystemErr string   `xml:"system-err,omitempty"`
 }
 
+// Failure is either a <failure> (a failed assertion) or an <error> (the test crashed).
 type Failure struct {
-	XMLName xml.Name `xml:"failure"`
-	Message string   `xml:"message,attr"`
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type Skipped struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// Details describes why a test did not pass, or returns an empty string when it did.
+// What the test printed follows the failure, so truncating the text drops the output first.
+// The text comes straight from the simulation; sanitize it before showing it to anyone.
+func (t *Testcase) Details() string {
+	var parts []string
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			parts = append(parts, s)
+		}
+	}
+
+	switch {
+	case t.Error != nil:
+		add(t.Error.Message)
+		add(t.Error.Body)
+	case t.Failure != nil:
+		add(t.Failure.Message)
+		add(t.Failure.Body)
+	case t.Skipped != nil:
+		add(t.Skipped.Message)
+		add(t.Skipped.Body)
+	default:
+		return ""
+	}
+	add(t.SystemOut)
+	add(t.SystemErr)
+
+	return strings.Join(parts, "\n")
 }
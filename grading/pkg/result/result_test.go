@@ -0,0 +1,72 @@
+package result
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTestcaseDetails(t *testing.T) {
+	tests := []struct {
+		name     string
+		testcase Testcase
+		want     string
+	}{
+		{
+			name:     "passed",
+			testcase: Testcase{SystemOut: "all good"},
+			want:     "",
+		},
+		{
+			name:     "failure",
+			testcase: Testcase{Failure: &Failure{Message: "assert 1 == 2", Body: "  traceback  "}},
+			want:     "assert 1 == 2\ntraceback",
+		},
+		{
+			name:     "error wins over failure",
+			testcase: Testcase{Error: &Failure{Message: "crashed"}, Failure: &Failure{Message: "assert"}},
+			want:     "crashed",
+		},
+		{
+			name:     "skipped",
+			testcase: Testcase{Skipped: &Skipped{Message: "not supported"}},
+			want:     "not supported",
+		},
+		{
+			name: "output after the failure",
+			testcase: Testcase{
+				Failure:   &Failure{Message: "assert"},
+				SystemOut: "dut.out = 3",
+				SystemErr: "warning",
+			},
+			want: "assert\ndut.out = 3\nwarning",
+		},
+		{
+			name:     "blank parts are left out",
+			testcase: Testcase{Failure: &Failure{Message: " ", Body: "body"}, SystemOut: "\n"},
+			want:     "body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.testcase.Details(); got != tt.want {
+				t.Errorf("Details() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeDetailsKeepsFailure(t *testing.T) {
+	testcase := Testcase{
+		Failure:   &Failure{Message: "assert \x1b[31mfailed\x1b[0m in /tmp/verilog/12/prj/test.py"},
+		SystemOut: strings.Repeat("x", MaxMessageLength),
+	}
+
+	got := Sanitize(testcase.Details())
+	if !strings.HasPrefix(got, "assert failed in test.py\nxxx") {
+		t.Errorf("Sanitize(Details()) starts with %q", got[:40])
+	}
+	if len(got) > MaxMessageLength+len("…") {
+		t.Errorf("Sanitize(Details()) is %d bytes, want at most %d", len(got), MaxMessageLength)
+	}
+}
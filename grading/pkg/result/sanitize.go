@@ -0,0 +1,41 @@
+package result
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxMessageLength bounds a stored testcase message; a runaway test can log megabytes.
+const MaxMessageLength = 2000
+
+var (
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+	// Paths inside the grader and the sandbox tell students nothing and leak how grading is set up
+	graderPathPattern = regexp.MustCompile(`(?:/tmp/sandbox-root-\w+|/tmp/verilog/\d+/prj)/?`)
+)
+
+// Sanitize makes simulation output safe to store and show: valid UTF-8, no terminal escapes
+// or control characters, no grader paths, and at most MaxMessageLength bytes.
+func Sanitize(s string) string {
+	s = strings.ToValidUTF8(s, "")
+	s = ansiPattern.ReplaceAllString(s, "")
+	s = graderPathPattern.ReplaceAllString(s, "")
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' || !unicode.IsControl(r) {
+			return r
+		}
+		return -1
+	}, s)
+	s = strings.TrimSpace(s)
+
+	if len(s) <= MaxMessageLength {
+		return s
+	}
+	cut := MaxMessageLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
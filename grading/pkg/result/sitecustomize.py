@@ -0,0 +1,43 @@
+"""Reports cocotb test progress to the grader.
+
+The grader puts this module on the PYTHONPATH of a simulation, so Python loads it on startup.
+It copies the log records of the cocotb regression manager to the file descriptor named in
+SANDBOX_PROGRESS_FD. The simulator loads Python before the design runs, and the hook moves the
+descriptor to a copy that isn't inherited, so processes the design starts can't write there.
+"""
+
+import logging
+import os
+
+
+class _ProgressHandler(logging.Handler):
+    def __init__(self, stream):
+        super().__init__(logging.INFO)
+        self._stream = stream
+
+    def emit(self, record):
+        try:
+            message = record.getMessage().replace("\n", " ")
+            self._stream.write(f"{record.name} {message}\n")
+            self._stream.flush()
+        except Exception:
+            self.handleError(record)
+
+
+def _install():
+    fd = os.environ.pop("SANDBOX_PROGRESS_FD", None)
+    if not fd:
+        return
+    try:
+        # os.dup returns a descriptor that isn't inherited, e.g. by a shell started through $system
+        private = os.dup(int(fd))
+        os.close(int(fd))
+    except (OSError, ValueError):
+        # Helpers started by the build, e.g. cocotb-config, may not have the descriptor
+        return
+    stream = os.fdopen(private, "w")
+    # cocotb replaces the handlers of the root logger, not those of its own loggers
+    logging.getLogger("cocotb.regression").addHandler(_ProgressHandler(stream))
+
+
+_install()
@@ -0,0 +1,90 @@
+package sandbox
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// initArg is the first argument of a re-executed grader binary that should act as the sandbox init process.
+const initArg = "__sandbox_init__"
+
+// ProgressFDEnv names the environment variable that tells the command which file descriptor writes to Config.Progress.
+const ProgressFDEnv = "SANDBOX_PROGRESS_FD"
+
+var ErrUnsupported = errors.New("sandbox is only supported on linux")
+
+type Status string
+
+const (
+	StatusExited         Status = "EXITED"
+	StatusTimeLimit      Status = "TIME_LIMIT"
+	StatusMemoryLimit    Status = "MEMORY_LIMIT"
+	StatusProcessLimit   Status = "PROCESS_LIMIT"
+	StatusKilledBySignal Status = "KILLED"
+)
+
+type Limits struct {
+	// CPU is the number of cores the run may use, e.g. 1.5
+	CPU      float64
+	MemoryMB uint
+	Pids     uint
+	WallTime time.Duration
+}
+
+type Config struct {
+	// Dir is copied into a private tmpfs that becomes the only writable place in the sandbox
+	Dir string
+	// WorkDir is the directory inside Dir the command runs in
+	WorkDir string
+	Command []string
+	// Collect lists files, relative to WorkDir, that are copied to OutDir after the run
+	Collect []string
+	OutDir  string
+	// Hide lists directories that are covered by an empty read-only tmpfs once Dir has been copied,
+	// e.g. the parent of Dir where other runs keep their files
+	Hide []string
+	// Env is added to the minimal environment of the command
+	Env    []string
+	Limits Limits
+	Stdout io.Writer
+	// Progress, if set, receives what the command writes to the file descriptor named in ProgressFDEnv.
+	// Unlike Stdout, it only gets output from processes that still hold that descriptor.
+	Progress io.Writer
+}
+
+type Result struct {
+	Status   Status
+	ExitCode int
+	WallTime time.Duration
+	// PeakMemoryBytes is the highest memory usage of the whole process tree
+	PeakMemoryBytes uint64
+}
+
+// Killed reports whether the sandbox stopped the run because it hit a limit.
+func (r *Result) Killed() bool {
+	return r.Status == StatusTimeLimit || r.Status == StatusMemoryLimit || r.Status == StatusProcessLimit
+}
+
+// IsInit reports whether this process was started by Run to set up a sandbox.
+// Call it first thing in main and hand over to Init when it returns true.
+func IsInit() bool {
+	return len(os.Args) > 2 && os.Args[1] == initArg
+}
+
+// spec is what the parent passes to the init process.
+type spec struct {
+	Root    string   `json:"root"`
+	Dir     string   `json:"dir"`
+	WorkDir string   `json:"work_dir"`
+	Command []string `json:"command"`
+	Collect []string `json:"collect"`
+	OutDir  string   `json:"out_dir"`
+	Hide    []string `json:"hide"`
+	Env     []string `json:"env"`
+	// Progress tells init that the progress pipe is open as progressFD
+	Progress bool `json:"progress"`
+	Uid      int  `json:"uid"`
+	Gid      int  `json:"gid"`
+}
@@ -0,0 +1,579 @@
+package sandbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	nobodyID      = 65534
+	tmpfsSizeMB   = 256
+	cpuPeriod     = 100000
+	maxCollectMB  = 16
+	initFailCode  = 125
+	maxStatusLen  = 4096
+	defaultPATH   = "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	defaultLocale = "LANG=C.UTF-8"
+)
+
+// Descriptors Run passes to init, and where the command finds the progress pipe, right after stdio
+const (
+	statusFD          = 3
+	progressFD        = 4
+	commandProgressFD = 3
+)
+
+type Sandbox struct {
+	cgroupRoot string
+	counter    atomic.Uint64
+}
+
+// New prepares cgroupRoot, a cgroup v2 directory the grader is allowed to manage, for running sandboxes.
+// The grader moves itself into a leaf cgroup so the cpu, memory and pids controllers
+// can be enabled for the sandboxes created next to it.
+func New(cgroupRoot string) (*Sandbox, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 is not mounted at %s: %w", cgroupRoot, err)
+	}
+
+	self := filepath.Join(cgroupRoot, "grader")
+	if err := os.MkdirAll(self, 0o755); err != nil {
+		return nil, fmt.Errorf("create grader cgroup: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(self, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+		return nil, fmt.Errorf("move grader into its cgroup: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte("+cpu +memory +pids"), 0); err != nil {
+		return nil, fmt.Errorf("enable cgroup controllers: %w", err)
+	}
+
+	return &Sandbox{cgroupRoot: cgroupRoot}, nil
+}
+
+// Run executes cfg.Command in new mount, PID, network, IPC and UTS namespaces as an unprivileged user,
+// with an empty environment, a read-only root filesystem and the limits enforced by a fresh cgroup.
+// Hitting a limit is not an error; it is reported through Result.Status.
+func (s *Sandbox) Run(ctx context.Context, cfg Config) (*Result, error) {
+	cgroup := filepath.Join(s.cgroupRoot, fmt.Sprintf("sandbox-%d", s.counter.Add(1)))
+	if err := os.Mkdir(cgroup, 0o755); err != nil {
+		return nil, fmt.Errorf("create sandbox cgroup: %w", err)
+	}
+	defer removeCgroup(cgroup)
+
+	if err := writeLimits(cgroup, cfg.Limits); err != nil {
+		return nil, err
+	}
+
+	cgroupDir, err := os.Open(cgroup)
+	if err != nil {
+		return nil, fmt.Errorf("open sandbox cgroup: %w", err)
+	}
+	defer cgroupDir.Close()
+
+	root, err := os.MkdirTemp("", "sandbox-root-")
+	if err != nil {
+		return nil, fmt.Errorf("create sandbox root: %w", err)
+	}
+	defer os.RemoveAll(root)
+
+	// Only root may write here, so the command itself can't plant files that look collected
+	if err := os.MkdirAll(cfg.OutDir, 0o700); err != nil {
+		return nil, fmt.Errorf("create sandbox output directory: %w", err)
+	}
+
+	data, err := json.Marshal(spec{
+		Root:     root,
+		Dir:      cfg.Dir,
+		WorkDir:  cfg.WorkDir,
+		Command:  cfg.Command,
+		Collect:  cfg.Collect,
+		OutDir:   cfg.OutDir,
+		Hide:     cfg.Hide,
+		Env:      append([]string{defaultPATH, defaultLocale}, cfg.Env...),
+		Progress: cfg.Progress != nil,
+		Uid:      nobodyID,
+		Gid:      nobodyID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("find grader executable: %w", err)
+	}
+
+	// Init reports its own failures on a pipe the command can't reach,
+	// so nothing the command prints or exits with can pass for one
+	statusR, statusW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("create sandbox status pipe: %w", err)
+	}
+	defer statusR.Close()
+
+	cmd := exec.Command(self, initArg, string(data))
+	cmd.Env = []string{}
+	cmd.Stdout = cfg.Stdout
+	cmd.Stderr = cfg.Stdout
+	cmd.ExtraFiles = []*os.File{statusW}
+
+	// Progress gets a pipe of its own, which the command writes to through an inherited descriptor.
+	// Every process the command starts inherits it too, it is up to the command to close it
+	// before running anything that must not report progress.
+	var progressW *os.File
+	var progressDone chan struct{}
+	if cfg.Progress != nil {
+		var progressR *os.File
+		progressR, progressW, err = os.Pipe()
+		if err != nil {
+			statusW.Close()
+			return nil, fmt.Errorf("create sandbox progress pipe: %w", err)
+		}
+		defer progressR.Close()
+		cmd.ExtraFiles = append(cmd.ExtraFiles, progressW)
+
+		progressDone = make(chan struct{})
+		go func() {
+			defer close(progressDone)
+			_, _ = io.Copy(cfg.Progress, progressR)
+		}()
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UseCgroupFD: true,
+		CgroupFD:    int(cgroupDir.Fd()),
+		Pdeathsig:   syscall.SIGKILL,
+	}
+
+	start := time.Now()
+	err = cmd.Start()
+	// Only the sandbox may hold the write ends, so reading them ends when it is gone
+	statusW.Close()
+	if progressW != nil {
+		progressW.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("start sandbox: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var timeout <-chan time.Time
+	if cfg.Limits.WallTime > 0 {
+		timer := time.NewTimer(cfg.Limits.WallTime)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	timedOut := false
+	var waitErr error
+	select {
+	case waitErr = <-done:
+	case <-timeout:
+		timedOut = true
+		kill(cgroup, cmd)
+		waitErr = <-done
+	case <-ctx.Done():
+		kill(cgroup, cmd)
+		<-done
+		return nil, ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	if waitErr != nil && !errors.As(waitErr, &exitErr) {
+		return nil, fmt.Errorf("wait for sandbox: %w", waitErr)
+	}
+	if progressDone != nil {
+		<-progressDone
+	}
+
+	result := &Result{
+		Status:          StatusExited,
+		ExitCode:        cmd.ProcessState.ExitCode(),
+		WallTime:        time.Since(start),
+		PeakMemoryBytes: peakMemory(cgroup, cmd.ProcessState),
+	}
+	initErr, err := io.ReadAll(io.LimitReader(statusR, maxStatusLen))
+	if err != nil {
+		return nil, fmt.Errorf("read sandbox status: %w", err)
+	}
+	if len(initErr) > 0 {
+		return nil, fmt.Errorf("sandbox init failed: %s", initErr)
+	}
+
+	switch {
+	case timedOut:
+		result.Status = StatusTimeLimit
+	case readEventCount(cgroup, "memory.events", "oom_kill") > 0:
+		result.Status = StatusMemoryLimit
+	case result.ExitCode != 0 && readEventCount(cgroup, "pids.events", "max") > 0:
+		result.Status = StatusProcessLimit
+	case result.ExitCode < 0:
+		result.Status = StatusKilledBySignal
+	}
+
+	return result, nil
+}
+
+// Init sets up the sandbox from inside the new namespaces, runs the command and exits with its exit code.
+// A failure of the setup itself is written to the status pipe instead. It never returns.
+func Init() {
+	status := os.NewFile(statusFD, "sandbox-status")
+	// The command must not inherit the status pipe, the progress pipe is handed over explicitly
+	syscall.CloseOnExec(statusFD)
+	syscall.CloseOnExec(progressFD)
+
+	var sp spec
+	if err := json.Unmarshal([]byte(os.Args[2]), &sp); err != nil {
+		initFailed(status, fmt.Errorf("invalid spec: %w", err))
+	}
+
+	code, err := runInit(sp)
+	if err != nil {
+		initFailed(status, err)
+	}
+	os.Exit(code)
+}
+
+func initFailed(status *os.File, err error) {
+	if _, writeErr := io.WriteString(status, err.Error()); writeErr != nil {
+		fmt.Fprintln(os.Stderr, "sandbox:", err)
+	}
+	os.Exit(initFailCode)
+}
+
+func runInit(sp spec) (int, error) {
+	if len(sp.Command) == 0 {
+		return 0, errors.New("no command")
+	}
+
+	// Keep every mount change inside this namespace
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return 0, fmt.Errorf("make mounts private: %w", err)
+	}
+
+	// The output directory stays writable, everything that is already mounted becomes read-only
+	if err := unix.Mount(sp.OutDir, sp.OutDir, "", unix.MS_BIND, ""); err != nil {
+		return 0, fmt.Errorf("bind output directory: %w", err)
+	}
+	if err := remountReadOnly(sp.OutDir); err != nil {
+		return 0, err
+	}
+	// Opened now because the output directory may lie inside one of the hidden directories
+	outDir, err := os.Open(sp.OutDir)
+	if err != nil {
+		return 0, fmt.Errorf("open output directory: %w", err)
+	}
+	defer outDir.Close()
+
+	if err := unix.Mount("tmpfs", sp.Root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, fmt.Sprintf("size=%dm,mode=0755", tmpfsSizeMB)); err != nil {
+		return 0, fmt.Errorf("mount workdir: %w", err)
+	}
+	if err := copyTree(sp.Dir, sp.Root, sp.Uid, sp.Gid); err != nil {
+		return 0, fmt.Errorf("copy project into sandbox: %w", err)
+	}
+
+	tmpDir := filepath.Join(sp.Root, ".tmp")
+	if err := os.Mkdir(tmpDir, 0o1777); err != nil {
+		return 0, fmt.Errorf("create tmp directory: %w", err)
+	}
+	if err := os.Chown(tmpDir, sp.Uid, sp.Gid); err != nil {
+		return 0, fmt.Errorf("chown tmp directory: %w", err)
+	}
+
+	for _, dir := range sp.Hide {
+		if err := unix.Mount("tmpfs", dir, "tmpfs", unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "size=4k,mode=0755"); err != nil {
+			return 0, fmt.Errorf("hide %s: %w", dir, err)
+		}
+	}
+
+	// A fresh /proc only shows the processes of this PID namespace
+	if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return 0, fmt.Errorf("mount proc: %w", err)
+	}
+	if err := unix.Sethostname([]byte("sandbox")); err != nil {
+		return 0, fmt.Errorf("set hostname: %w", err)
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return 0, fmt.Errorf("set no_new_privs: %w", err)
+	}
+
+	// exec.Command looks the binary up in our own PATH, which starts out empty
+	for _, kv := range sp.Env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			os.Setenv(k, v)
+		}
+	}
+
+	cmd := exec.Command(sp.Command[0], sp.Command[1:]...)
+	cmd.Dir = filepath.Join(sp.Root, sp.WorkDir)
+	cmd.Env = append(sp.Env, "HOME="+sp.Root, "TMPDIR="+tmpDir)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if sp.Progress {
+		progress := os.NewFile(progressFD, "sandbox-progress")
+		defer progress.Close()
+		cmd.ExtraFiles = []*os.File{progress}
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", ProgressFDEnv, commandProgressFD))
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: uint32(sp.Uid), Gid: uint32(sp.Gid)},
+		Setpgid:    true,
+	}
+
+	code := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return 0, fmt.Errorf("run command: %w", err)
+		}
+		code = exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			code = 128 + int(status.Signal())
+		}
+	}
+
+	for _, name := range sp.Collect {
+		if err := copyOut(filepath.Join(cmd.Dir, name), outDir, filepath.Base(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "sandbox: collect %s: %v\n", name, err)
+		}
+	}
+
+	return code, nil
+}
+
+func writeLimits(cgroup string, limits Limits) error {
+	files := map[string]string{}
+	if limits.MemoryMB > 0 {
+		files["memory.max"] = strconv.FormatUint(uint64(limits.MemoryMB)*1024*1024, 10)
+		files["memory.swap.max"] = "0"
+	}
+	if limits.Pids > 0 {
+		files["pids.max"] = strconv.FormatUint(uint64(limits.Pids), 10)
+	}
+	if limits.CPU > 0 {
+		files["cpu.max"] = fmt.Sprintf("%d %d", int(limits.CPU*cpuPeriod), cpuPeriod)
+	}
+
+	for name, value := range files {
+		err := os.WriteFile(filepath.Join(cgroup, name), []byte(value), 0)
+		// memory.swap.max is missing when the kernel has no swap accounting, which is fine
+		if err != nil && !(name == "memory.swap.max" && errors.Is(err, fs.ErrNotExist)) {
+			return fmt.Errorf("set %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func kill(cgroup string, cmd *exec.Cmd) {
+	if err := os.WriteFile(filepath.Join(cgroup, "cgroup.kill"), []byte("1"), 0); err != nil {
+		// Older kernels have no cgroup.kill; killing the init process takes the whole PID namespace down
+		_ = cmd.Process.Kill()
+	}
+}
+
+func removeCgroup(cgroup string) {
+	// The cgroup can only be removed once the kernel has finished reaping its processes
+	for range 50 {
+		if err := os.Remove(cgroup); err == nil || errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	fmt.Println("sandbox: failed to remove cgroup", cgroup)
+}
+
+// readEventCount returns a counter from a cgroup events file such as memory.events.
+func readEventCount(cgroup string, file string, key string) uint64 {
+	data, err := os.ReadFile(filepath.Join(cgroup, file))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			n, _ := strconv.ParseUint(fields[1], 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// peakMemory reads memory.peak of the sandbox cgroup. Kernels older than 5.19 don't have it,
+// then the max RSS reported by wait4 is used; it covers the process tree but only its largest process.
+func peakMemory(cgroup string, state *os.ProcessState) uint64 {
+	if data, err := os.ReadFile(filepath.Join(cgroup, "memory.peak")); err == nil {
+		if n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			return n
+		}
+	}
+	if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		// ru_maxrss is in kilobytes
+		return uint64(usage.Maxrss) * 1024
+	}
+	return 0
+}
+
+// remountReadOnly makes every mount in this namespace read-only, keeping its other flags.
+func remountReadOnly(keep string) error {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return fmt.Errorf("read mounts: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		target := unescapeMountPath(fields[4])
+		if target == keep {
+			continue
+		}
+
+		flags := uintptr(unix.MS_REMOUNT | unix.MS_BIND | unix.MS_RDONLY)
+		for _, opt := range strings.Split(fields[5], ",") {
+			switch opt {
+			case "nosuid":
+				flags |= unix.MS_NOSUID
+			case "nodev":
+				flags |= unix.MS_NODEV
+			case "noexec":
+				flags |= unix.MS_NOEXEC
+			case "noatime":
+				flags |= unix.MS_NOATIME
+			case "nodiratime":
+				flags |= unix.MS_NODIRATIME
+			case "relatime":
+				flags |= unix.MS_RELATIME
+			}
+		}
+
+		// A mount left writable would be writable by the command, so any failure aborts the run
+		if err := unix.Mount("", target, "", flags, ""); err != nil {
+			return fmt.Errorf("remount %s read-only: %w", target, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes (\040 for a space) used in /proc/self/mountinfo.
+func unescapeMountPath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if n, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+func copyTree(src string, dst string, uid int, gid int) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()|0o700); err != nil {
+				return err
+			}
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case d.Type().IsRegular():
+			if err := copyFile(path, target, info.Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+		return os.Lchown(target, uid, gid)
+	})
+}
+
+func copyFile(src string, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// copyOut copies a file produced by the command out of the sandbox into dir.
+// The command controls the source, so symlinks and anything but regular files are refused.
+func copyOut(src string, dir *os.File, name string) error {
+	in, err := os.OpenFile(src, os.O_RDONLY|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", src)
+	}
+
+	fd, err := unix.Openat(int(dir.Fd()), name, unix.O_CREAT|unix.O_WRONLY|unix.O_TRUNC|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return err
+	}
+	out := os.NewFile(uintptr(fd), name)
+	defer out.Close()
+
+	_, err = io.Copy(out, io.LimitReader(in, maxCollectMB*1024*1024))
+	return err
+}
@@ -0,0 +1,60 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnescapeMountPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "plain", path: "/tmp/verilog", want: "/tmp/verilog"},
+		{name: "space", path: `/mnt/my\040disk`, want: "/mnt/my disk"},
+		{name: "tab and newline", path: `/a\011b\012c`, want: "/a\tb\nc"},
+		{name: "backslash", path: `/a\134b`, want: `/a\b`},
+		{name: "not octal", path: `/a\09x`, want: `/a\09x`},
+		{name: "escape at the end", path: `/a\040`, want: "/a "},
+		{name: "cut off escape", path: `/a\04`, want: `/a\04`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unescapeMountPath(tt.path); got != tt.want {
+				t.Errorf("unescapeMountPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadEventCount(t *testing.T) {
+	cgroup := t.TempDir()
+	events := "low 0\nhigh 3\nmax 7\noom 2\noom_kill 1\n"
+	if err := os.WriteFile(filepath.Join(cgroup, "memory.events"), []byte(events), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		file string
+		key  string
+		want uint64
+	}{
+		{name: "counter", file: "memory.events", key: "oom_kill", want: 1},
+		{name: "prefix of another key", file: "memory.events", key: "oom", want: 2},
+		{name: "zero", file: "memory.events", key: "low", want: 0},
+		{name: "missing key", file: "memory.events", key: "oom_group_kill", want: 0},
+		{name: "missing file", file: "pids.events", key: "max", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readEventCount(cgroup, tt.file, tt.key); got != tt.want {
+				t.Errorf("readEventCount(%s, %s) = %d, want %d", tt.file, tt.key, got, tt.want)
+			}
+		})
+	}
+}
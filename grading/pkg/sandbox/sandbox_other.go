@@ -0,0 +1,24 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+type Sandbox struct{}
+
+func New(cgroupRoot string) (*Sandbox, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Sandbox) Run(ctx context.Context, cfg Config) (*Result, error) {
+	return nil, ErrUnsupported
+}
+
+func Init() {
+	fmt.Fprintln(os.Stderr, "sandbox:", ErrUnsupported)
+	os.Exit(1)
+}
@@ -1,23 +1,32 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"os"
-	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/yokeTH/our-grader-backend/api/pkg/config"
 	"github.com/yokeTH/our-grader-backend/api/pkg/core/domain"
 	"github.com/yokeTH/our-grader-backend/api/pkg/database"
+	"github.com/yokeTH/our-grader-backend/api/pkg/manifest"
 	"github.com/yokeTH/our-grader-backend/api/pkg/repository"
 	"github.com/yokeTH/our-grader-backend/api/pkg/storage"
+	"github.com/yokeTH/our-grader-backend/grading/pkg/diagnostic"
+	"github.com/yokeTH/our-grader-backend/grading/pkg/event"
+	"github.com/yokeTH/our-grader-backend/grading/pkg/queue"
 	"github.com/yokeTH/our-grader-backend/grading/pkg/result"
+	"github.com/yokeTH/our-grader-backend/grading/pkg/sandbox"
 	"github.com/yokeTH/our-grader-backend/grading/pkg/unzip"
 	"github.com/yokeTH/our-grader-backend/proto/verilog"
 	"google.golang.org/grpc"
@@ -29,95 +38,200 @@ const (
 	port             = ":50051"
 	executionTimeout = 1 * time.Minute
 	requestTimeout   = 1 * time.Minute
+	workerCount      = 2
+	cgroupRoot       = "/sys/fs/cgroup"
+	workRoot         = "/tmp/verilog"
+	pythonHookDir    = "/var/lib/grader/python"
 )
 
+// simulationLimits keep the wall time below executionTimeout so a hanging design
+// is reported as a time limit verdict instead of failing the whole job.
+var simulationLimits = sandbox.Limits{
+	CPU:      1,
+	MemoryMB: 1024,
+	Pids:     256,
+	WallTime: 40 * time.Second,
+}
+
+// simulationConfig applies the limits and simulator of a problem manifest over the defaults.
+func simulationConfig(m *manifest.Manifest) (sandbox.Limits, []string, string) {
+	limits := simulationLimits
+	if m == nil {
+		return limits, []string{"make"}, "cocotb"
+	}
+	if m.Limits.TimeSeconds > 0 {
+		limits.WallTime = time.Duration(m.Limits.TimeSeconds * float64(time.Second))
+	}
+	if m.Limits.MemoryMB > 0 {
+		limits.MemoryMB = m.Limits.MemoryMB
+	}
+	return limits, m.Simulator.Command, m.Simulator.WorkDir
+}
+
+var limitVerdicts = map[sandbox.Status]domain.Verdict{
+	sandbox.StatusTimeLimit:    domain.VerdictTimeLimitExceeded,
+	sandbox.StatusMemoryLimit:  domain.VerdictMemoryLimitExceeded,
+	sandbox.StatusProcessLimit: domain.VerdictProcessLimitExceeded,
+}
+
 type server struct {
 	verilog.UnimplementedSomeServiceServer
+	store          storage.IStorage
+	sandbox        *sandbox.Sandbox
+	broker         *event.Broker
+	jobRepo        *repository.GradingJobRepository
+	submissionRepo *repository.SubmissionRepository
+	testcaseRepo   *repository.TestcaseRepository
+	problemRepo    *repository.ProblemRepository
 }
 
-func (s *server) Run(ctx context.Context, in *verilog.VerilogRequest) (*verilog.VerilogResponse, error) {
+func (s *server) Grade(in *verilog.GradeRequest, stream verilog.SomeService_GradeServer) error {
 	// Create a new context with timeout
-	ctx, cancel := context.WithTimeout(ctx, executionTimeout)
+	ctx, cancel := context.WithTimeout(stream.Context(), executionTimeout)
 	defer cancel()
 
-	// Create a channel to track if context is done before function completes
-	done := make(chan struct{})
-	var resp *verilog.VerilogResponse
-	var execErr error
+	// Testcase results are stored concurrently and a stream must not be written from two goroutines at once
+	var mu sync.Mutex
+	emit := func(ev *verilog.GradingEvent) {
+		mu.Lock()
+		defer mu.Unlock()
 
-	go func() {
-		resp, execErr = s.processVerilog(ctx, in)
-		close(done)
-	}()
+		s.broker.Publish(ev)
+		if err := stream.Send(ev); err != nil {
+			fmt.Println("stream.Send failed:", err.Error())
+		}
+	}
 
-	// Wait for either completion or context cancellation
-	select {
-	case <-ctx.Done():
-		return nil, status.Errorf(codes.DeadlineExceeded, "request timed out after %v", executionTimeout)
-	case <-done:
-		return resp, execErr
+	if err := s.grade(ctx, uint(in.SubmissionID), emit); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return status.Errorf(codes.DeadlineExceeded, "request timed out after %v", executionTimeout)
+		}
+		return status.Error(codes.Internal, err.Error())
 	}
+	return nil
 }
 
-func (s *server) processVerilog(ctx context.Context, in *verilog.VerilogRequest) (*verilog.VerilogResponse, error) {
-	config := config.Load()
-	store, err := storage.NewR2Storage(config.R2)
-	if err != nil {
-		fmt.Println("storage.NewR2Storage failed:", err.Error())
-		return &verilog.VerilogResponse{Msg: err.Error()}, nil
+func (s *server) Watch(in *verilog.WatchRequest, stream verilog.SomeService_WatchServer) error {
+	events, unsubscribe := s.broker.Subscribe(in.SubmissionID)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case ev := <-events:
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+			if ev.GetSummary() != nil {
+				return nil
+			}
+		}
 	}
+}
 
-	// Check if context is cancelled
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
+// handleJob grades the submission of a job claimed from the queue.
+func (s *server) handleJob(ctx context.Context, job domain.GradingJob) error {
+	ctx, cancel := context.WithTimeout(ctx, executionTimeout)
+	defer cancel()
+
+	emit := func(ev *verilog.GradingEvent) {
+		s.broker.Publish(ev)
+		// Keep the stage on the job row so a stuck submission can be spotted from the database
+		if stage := ev.GetStage(); stage != nil {
+			if err := s.jobRepo.UpdateStage(job.ID, stage.Stage.String()); err != nil {
+				fmt.Println("jobRepo.UpdateStage failed:", err.Error())
+			}
+		}
 	}
 
-	db, err := database.NewPostgresDB(config.PSQL)
-	if err != nil {
-		fmt.Println("database.NewPostgresDB failed:", err.Error())
-		log.Fatalf("failed to connect database: %v", err)
+	return s.grade(ctx, job.SubmissionID, emit)
+}
+
+// handleJobFailure marks the submission as failed once the queue gives up on it,
+// so it does not look like it is still waiting to be graded.
+func (s *server) handleJobFailure(ctx context.Context, job domain.GradingJob, err error) {
+	if updateErr := s.submissionRepo.UpdateResult(job.SubmissionID, domain.SubmissionFailed, domain.VerdictSystemError); updateErr != nil {
+		fmt.Println("submissionRepo.UpdateResult failed:", updateErr.Error())
 	}
+	if updateErr := s.problemRepo.UpdateValidationByReference(job.SubmissionID, domain.ValidationFailed); updateErr != nil {
+		fmt.Println("problemRepo.UpdateValidationByReference failed:", updateErr.Error())
+	}
+}
 
-	submissionRepo := repository.NewSubmissionRepository(db)
+// grade runs processVerilog and always finishes the event stream with a summary.
+func (s *server) grade(ctx context.Context, submissionID uint, emit event.Emitter) error {
+	summary, err := s.processVerilog(ctx, submissionID, emit)
+	if summary == nil {
+		summary = &verilog.GradingSummary{}
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	emit(event.Stage(submissionID, verilog.Stage_STAGE_FINISHED))
+	emit(event.Summary(submissionID, summary))
+	return err
+}
 
-	submission, err := submissionRepo.GetSubmissionsByID(uint(in.SubmissionID))
+func (s *server) processVerilog(ctx context.Context, submissionID uint, emit event.Emitter) (*verilog.GradingSummary, error) {
+	submission, err := s.submissionRepo.GetSubmissionsByID(submissionID)
 	if err != nil {
 		fmt.Println("submissionRepo.GetSubmissionsByID failed:", err.Error())
-		return &verilog.VerilogResponse{Msg: err.Error()}, nil
+		return nil, err
+	}
+
+	// Without definitions there is nothing to check the results against, and every run would pass
+	if len(submission.Testcases) == 0 {
+		return s.failNoTestcases(submission)
+	}
+
+	if err := s.submissionRepo.UpdateStatus(submission.ID, domain.SubmissionRunning); err != nil {
+		fmt.Println("submissionRepo.UpdateStatus failed:", err.Error())
+		return nil, err
 	}
+	submission.Status = domain.SubmissionRunning
+
+	emit(event.Stage(submission.ID, verilog.Stage_STAGE_FETCHING_PROJECT))
 
 	// Check if context is cancelled
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
-	body, err := store.GetFile(ctx, submission.Problem.ProjectZipFile)
+	body, err := s.store.GetFile(ctx, submission.ProjectZipFile())
 	if err != nil {
 		fmt.Println("store.GetFile failed:", err.Error())
-		return &verilog.VerilogResponse{Msg: err.Error()}, nil
+		return nil, err
 	}
 	defer body.Close()
 
-	basePath := fmt.Sprintf("/tmp/verilog/%d", in.SubmissionID)
+	basePath := fmt.Sprintf("%s/%d", workRoot, submission.ID)
+
+	// A retried job must not see files left behind by the previous attempt
+	if err := os.RemoveAll(basePath); err != nil {
+		fmt.Println("os.RemoveAll failed:", err.Error())
+		return nil, err
+	}
+	defer os.RemoveAll(basePath)
 
 	zipPath := fmt.Sprintf("%s/%s", basePath, "zip.zip")
 	zipDir := zipPath[:len(zipPath)-len("/zip.zip")]
 	if err := os.MkdirAll(zipDir, os.ModePerm); err != nil {
 		fmt.Println("os.MkdirAll failed:", err.Error())
-		return &verilog.VerilogResponse{Msg: err.Error()}, nil
+		return nil, err
 	}
 
 	outputFile, err := os.Create(zipPath)
 	if err != nil {
 		fmt.Println("os.Create failed:", err.Error())
-		return &verilog.VerilogResponse{Msg: err.Error()}, nil
+		return nil, err
 	}
 	defer outputFile.Close()
 
 	_, err = io.Copy(outputFile, body)
 	if err != nil {
 		fmt.Println("io.Copy failed:", err.Error())
-		return &verilog.VerilogResponse{Msg: err.Error()}, nil
+		return nil, err
 	}
 
 	// Check if context is cancelled
@@ -128,12 +242,17 @@ func (s *server) processVerilog(ctx context.Context, in *verilog.VerilogRequest)
 	unzipDir := fmt.Sprintf("%s/prj", basePath)
 	if err := os.MkdirAll(unzipDir, os.ModePerm); err != nil {
 		fmt.Println("os.MkdirAll failed:", err.Error())
-		return &verilog.VerilogResponse{Msg: err.Error()}, nil
+		return nil, err
 	}
 
 	if err := unzip.UnzipFile(zipPath, unzipDir); err != nil {
 		fmt.Println("unzip.UnzipFile failed:", err.Error())
-		return &verilog.VerilogResponse{Msg: err.Error()}, nil
+		return nil, err
+	}
+	// The zip still holds the reference solution, which is removed from the project below
+	if err := os.Remove(zipPath); err != nil {
+		fmt.Println("os.Remove failed:", err.Error())
+		return nil, err
 	}
 
 	for _, file := range submission.SubmissionFile {
@@ -143,10 +262,10 @@ func (s *server) processVerilog(ctx context.Context, in *verilog.VerilogRequest)
 		}
 
 		fileKey := fmt.Sprintf("submissions/%d/%d", submission.ID, file.TemplateFileID)
-		fileContent, err := store.GetFile(ctx, fileKey)
+		fileContent, err := s.store.GetFile(ctx, fileKey)
 		if err != nil {
 			fmt.Println("store.GetFile failed:", err.Error())
-			return &verilog.VerilogResponse{Msg: err.Error()}, nil
+			return nil, err
 		}
 		defer fileContent.Close()
 
@@ -154,59 +273,129 @@ func (s *server) processVerilog(ctx context.Context, in *verilog.VerilogRequest)
 		localFile, err := os.Create(localFilePath)
 		if err != nil {
 			fmt.Println("os.Create failed:", err.Error())
-			return &verilog.VerilogResponse{Msg: err.Error()}, nil
+			return nil, err
 		}
 		defer localFile.Close()
 
 		_, err = io.Copy(localFile, fileContent)
 		if err != nil {
 			fmt.Println("io.Copy failed:", err.Error())
-			return &verilog.VerilogResponse{Msg: err.Error()}, nil
+			return nil, err
 		}
 	}
 
-	// Run simulation with context
-	simDir := fmt.Sprintf("%s/%s", unzipDir, "cocotb")
+	// The manifest is part of the graded version, so limits always match the project they were written for
+	m, err := manifest.LoadFile(filepath.Join(unzipDir, manifest.FileName))
+	if err != nil {
+		fmt.Println("manifest.LoadFile failed:", err.Error())
+		return nil, err
+	}
+	limits, command, workDir := simulationConfig(m)
+	if m != nil && m.Reference != "" {
+		// Student code could otherwise read the solution while it runs
+		if err := os.RemoveAll(filepath.Join(unzipDir, m.Reference)); err != nil {
+			fmt.Println("os.RemoveAll failed:", err.Error())
+			return nil, err
+		}
+	}
 
-	// Use CommandContext to respect context timeouts
-	cmd := exec.CommandContext(ctx, "make")
-	cmd.Dir = simDir
-	stdOut, err := cmd.CombinedOutput()
+	emit(event.Stage(submission.ID, verilog.Stage_STAGE_COMPILING))
 
-	// Check if the error was due to context cancellation
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
+	// Report every test as soon as cocotb logs it; results.xml is only written at the very end.
+	// The log comes through its own pipe, so a design printing look-alike lines to stdout can't fake a result.
+	reported := make(map[string]bool)
+	testsStarted := false
+	progress := result.NewProgressWriter(func(p result.Progress) {
+		if !testsStarted {
+			testsStarted = true
+			emit(event.Stage(submission.ID, verilog.Stage_STAGE_RUNNING_TESTS))
+		}
+		if p.Kind == result.ProgressTestStarted {
+			return
+		}
+		reported[p.Classname+"."+p.Name] = true
+		emit(event.Testcase(submission.ID, p.Classname, p.Name, p.Kind == result.ProgressTestPassed))
+	})
+
+	// Student code must not see the grader's environment, network or files,
+	// nor the projects of other submissions graded at the same time
+	var stdOut bytes.Buffer
+	outDir := fmt.Sprintf("%s/out", basePath)
+	run, err := s.sandbox.Run(ctx, sandbox.Config{
+		Dir:      unzipDir,
+		WorkDir:  workDir,
+		Command:  command,
+		Collect:  []string{"results.xml"},
+		OutDir:   outDir,
+		Hide:     []string{workRoot},
+		Env:      []string{"PYTHONPATH=" + pythonHookDir},
+		Limits:   limits,
+		Stdout:   &stdOut,
+		Progress: progress,
+	})
+	if err != nil {
+		fmt.Println("sandbox.Run failed:", err.Error())
+		return nil, err
 	}
 
-	// First handle the command execution error if there is one
-	// Note: This doesn't necessarily mean the simulation failed as the exit code
-	// might be non-zero but we still want to capture the output
-	if err != nil {
-		fmt.Printf("make command execution error: %v\n", err)
-		// We continue processing to capture the output regardless
+	// A non-zero exit code doesn't necessarily mean the simulation failed,
+	// the results are read from results.xml regardless
+	if run.ExitCode != 0 {
+		fmt.Printf("make exited with %d (%s)\n", run.ExitCode, run.Status)
 	}
 
+	emit(event.Stage(submission.ID, verilog.Stage_STAGE_UPLOADING_RESULTS))
+
 	fileKey := fmt.Sprintf("submissions/%d/stdOut.txt", submission.ID)
-	if err := store.UploadFile(ctx, fileKey, "text/plain", strings.NewReader(string(stdOut))); err != nil {
+	if err := s.store.UploadFile(ctx, fileKey, "text/plain", strings.NewReader(stdOut.String())); err != nil {
 		fmt.Println("store.UploadFile failed:", err.Error())
-		return &verilog.VerilogResponse{Msg: err.Error()}, nil
+		return nil, err
 	}
 	submission.StdoutObjectKey = fileKey
-	if err := submissionRepo.Update(&submission); err != nil {
+	submission.MemoryUsageMB = uint((run.PeakMemoryBytes + 1<<20 - 1) >> 20)
+	submission.RunTimeMs = uint(run.WallTime.Milliseconds())
+	if err := s.submissionRepo.Update(&submission); err != nil {
 		fmt.Println("submissionRepo.Update failed:", err.Error())
-		return &verilog.VerilogResponse{Msg: err.Error()}, nil
+		return nil, err
 	}
 
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var firstErr error
+	var graded []domain.Testcase
+	summary := &verilog.GradingSummary{
+		Total:         uint32(len(submission.Testcases)),
+		MemoryUsageMB: uint32(submission.MemoryUsageMB),
+		RunTimeMs:     uint32(submission.RunTimeMs),
+	}
 
-	testcaseRepo := repository.NewTestcaseRepository(db)
-
-	resultPath := fmt.Sprintf("%s/%s", simDir, "results.xml")
+	resultPath := filepath.Join(outDir, "results.xml")
 	simResult, err := result.GetResult(resultPath)
-	if err != nil {
-		fmt.Println("result.GetResult failed:", err.Error())
+
+	// The build failed if the compiler reported an error and no test started. The progress stream
+	// alone can't tell, a simulation that stops before its first test, e.g. on a missing top level,
+	// or a design that closes the pipe, would look the same as a build that never finished.
+	var diagnostics []domain.Diagnostic
+	parsed := diagnostic.Parse(stdOut.String(), templateFileNames(submission))
+	compileFailed := err != nil && !run.Killed() && !testsStarted && diagnostic.HasErrors(parsed)
+	if compileFailed {
+		diagnostics = toDiagnostics(parsed)
+	}
+	if err := s.submissionRepo.ReplaceDiagnostics(submission.ID, diagnostics); err != nil {
+		fmt.Println("submissionRepo.ReplaceDiagnostics failed:", err.Error())
+		return nil, err
+	}
+
+	if run.Killed() || err != nil {
+		verdict, testcaseResult := domain.VerdictCompileError, domain.TestResultCompile
+		switch {
+		case run.Killed():
+			verdict, testcaseResult = limitVerdicts[run.Status], domain.TestResultFail
+		case !compileFailed:
+			// The design built but the simulation crashed before cocotb wrote its results
+			fmt.Println("result.GetResult failed:", err.Error())
+			verdict, testcaseResult = domain.VerdictRuntimeError, domain.TestResultFail
+		}
 
 		for i := range submission.Testcases {
 			// Check if context is cancelled
@@ -214,8 +403,11 @@ func (s *server) processVerilog(ctx context.Context, in *verilog.VerilogRequest)
 				return nil, ctx.Err()
 			}
 
-			submission.Testcases[i].Result = domain.TestResultCompile
-			updateErr := testcaseRepo.UpdateTestcase(&submission.Testcases[i])
+			submission.Testcases[i].Result = testcaseResult
+			submission.Testcases[i].WallTimeSec = 0
+			submission.Testcases[i].SimTimeNs = 0
+			submission.Testcases[i].Message = ""
+			updateErr := s.testcaseRepo.UpdateTestcase(&submission.Testcases[i])
 
 			if updateErr != nil {
 				fmt.Println("testcaseRepo.UpdateTestcase failed:", updateErr.Error())
@@ -227,52 +419,103 @@ func (s *server) processVerilog(ctx context.Context, in *verilog.VerilogRequest)
 			}
 		}
 
-		return &verilog.VerilogResponse{Msg: err.Error()}, nil
+		if firstErr != nil {
+			return nil, firstErr
+		}
+
+		// This is a verdict, not a reason to retry
+		summary.Failed = summary.Total
+		return s.finish(submission, submission.Testcases, verdict, summary)
+	}
+
+	// Results are keyed like the problem's testcase definitions, whichever testsuite they came from
+	simTestcases := make(map[string]result.Testcase)
+	for _, testsuite := range simResult.Testsuite {
+		for _, testcase := range testsuite.Testcase {
+			simTestcases[testcase.Classname+"."+testcase.Name] = testcase
+		}
+	}
+	for key := range simTestcases {
+		if !slices.ContainsFunc(submission.Testcases, func(t domain.Testcase) bool { return t.Name == key }) {
+			fmt.Printf("submission %d: result for unknown testcase %s ignored\n", submission.ID, key)
+		}
 	}
 
 	// Create a context-aware goroutine pool
 	processDone := make(chan struct{})
 
 	go func() {
-		for _, testsuite := range simResult.Testsuite {
-			for i, testcase := range testsuite.Testcase {
-				// Check if context is cancelled
+		for i := range submission.Testcases {
+			// Check if context is cancelled
+			if ctx.Err() != nil {
+				wg.Wait()
+				close(processDone)
+				return
+			}
+
+			wg.Add(1)
+			go func(testcaseResult domain.Testcase) {
+				defer wg.Done()
+
+				// Check context cancellation within goroutine
 				if ctx.Err() != nil {
-					wg.Wait()
-					close(processDone)
 					return
 				}
 
-				wg.Add(1)
-				go func(testcase result.Testcase, index int) {
-					defer wg.Done()
+				testcase, ok := simTestcases[testcaseResult.Name]
+				if !ok {
+					testcase.Classname, testcase.Name, _ = strings.Cut(testcaseResult.Name, ".")
+				}
 
-					// Check context cancellation within goroutine
-					if ctx.Err() != nil {
-						return
-					}
+				var outcome domain.TestcaseResult
+				switch {
+				case !ok:
+					// A test that never reported, e.g. because the simulation stopped early
+					outcome = domain.TestResultFail
+					testcaseResult.Message = "test did not run"
+				case testcase.Error != nil:
+					outcome = domain.TestResultError
+				case testcase.Failure != nil:
+					outcome = domain.TestResultFail
+				case testcase.Skipped != nil:
+					outcome = domain.TestResultSkipped
+				default:
+					outcome = domain.TestResultPass
+				}
+				if ok {
+					testcaseResult.Message = result.Sanitize(testcase.Details())
+				}
 
-					var result domain.TestcaseResult
-					if testcase.Failure != nil {
-						result = domain.TestResultFail
-					} else {
-						result = domain.TestResultPass
-					}
+				mu.Lock()
+				if outcome == domain.TestResultPass {
+					summary.Passed++
+				} else {
+					summary.Failed++
+				}
+				mu.Unlock()
+
+				if !reported[testcaseResult.Name] {
+					emit(event.Testcase(submission.ID, testcase.Classname, testcase.Name, outcome == domain.TestResultPass))
+				}
 
-					testcaseResult := submission.Testcases[index]
-					testcaseResult.Result = result
-					updateErr := testcaseRepo.UpdateTestcase(&testcaseResult)
-
-					if updateErr != nil {
-						fmt.Println("testcaseRepo.UpdateTestcase failed:", updateErr.Error())
-						mu.Lock()
-						if firstErr == nil {
-							firstErr = updateErr
-						}
-						mu.Unlock()
+				testcaseResult.Result = outcome
+				testcaseResult.WallTimeSec = testcase.Time
+				testcaseResult.SimTimeNs = testcase.SimTimeNs
+				updateErr := s.testcaseRepo.UpdateTestcase(&testcaseResult)
+
+				mu.Lock()
+				graded = append(graded, testcaseResult)
+				mu.Unlock()
+
+				if updateErr != nil {
+					fmt.Println("testcaseRepo.UpdateTestcase failed:", updateErr.Error())
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = updateErr
 					}
-				}(testcase, i)
-			}
+					mu.Unlock()
+				}
+			}(submission.Testcases[i])
 		}
 
 		wg.Wait()
@@ -288,13 +531,138 @@ func (s *server) processVerilog(ctx context.Context, in *verilog.VerilogRequest)
 	}
 
 	if firstErr != nil {
-		return &verilog.VerilogResponse{Msg: firstErr.Error()}, nil
+		return nil, firstErr
+	}
+
+	verdict := domain.VerdictWrongAnswer
+	if summary.Total > 0 && summary.Passed == summary.Total {
+		verdict = domain.VerdictAccepted
 	}
 
-	return &verilog.VerilogResponse{Msg: "Success"}, nil
+	return s.finish(submission, graded, verdict, summary)
+}
+
+// finish scores the graded testcases and stores the verdict.
+// The score is stored first so that a graded submission is never seen without it.
+func (s *server) finish(submission domain.Submission, testcases []domain.Testcase, verdict domain.Verdict, summary *verilog.GradingSummary) (*verilog.GradingSummary, error) {
+	// A regrade may use an older version, whose tests can differ from the current ones
+	problem := submission.Problem
+	if submission.ProblemVersion != nil && len(submission.ProblemVersion.Testcases) > 0 {
+		problem.Testcases = submission.ProblemVersion.Testcases
+	}
+	score, maxScore := problem.Score(testcases)
+	if err := s.submissionRepo.UpdateScore(submission.ID, score, maxScore); err != nil {
+		fmt.Println("submissionRepo.UpdateScore failed:", err.Error())
+		return nil, err
+	}
+
+	// A problem only takes submissions once its reference solution passed every test
+	if submission.Reference {
+		status := domain.ValidationFailed
+		if verdict == domain.VerdictAccepted {
+			status = domain.ValidationPassed
+		}
+		if err := s.problemRepo.UpdateValidationByReference(submission.ID, status); err != nil {
+			fmt.Println("problemRepo.UpdateValidationByReference failed:", err.Error())
+			return nil, err
+		}
+	}
+
+	summary.Score = score
+	summary.MaxScore = maxScore
+	summary.Status = string(domain.SubmissionGraded)
+	summary.Verdict = string(verdict)
+	return summary, s.submissionRepo.UpdateResult(submission.ID, domain.SubmissionGraded, verdict)
+}
+
+// failNoTestcases ends the run of a submission that has no testcases as a system error.
+// Retrying wouldn't help; the problem needs its definitions backfilled and the submission regraded.
+func (s *server) failNoTestcases(submission domain.Submission) (*verilog.GradingSummary, error) {
+	if err := s.submissionRepo.UpdateResult(submission.ID, domain.SubmissionFailed, domain.VerdictSystemError); err != nil {
+		fmt.Println("submissionRepo.UpdateResult failed:", err.Error())
+		return nil, err
+	}
+	if submission.Reference {
+		if err := s.problemRepo.UpdateValidationByReference(submission.ID, domain.ValidationFailed); err != nil {
+			fmt.Println("problemRepo.UpdateValidationByReference failed:", err.Error())
+			return nil, err
+		}
+	}
+	return &verilog.GradingSummary{
+		Status:  string(domain.SubmissionFailed),
+		Verdict: string(domain.VerdictSystemError),
+		Error:   "submission has no testcases",
+	}, nil
+}
+
+func templateFileNames(submission domain.Submission) []string {
+	names := make([]string, 0, len(submission.SubmissionFile))
+	for _, file := range submission.SubmissionFile {
+		names = append(names, file.TemplateFile.Name)
+	}
+	return names
+}
+
+func toDiagnostics(parsed []diagnostic.Diagnostic) []domain.Diagnostic {
+	diagnostics := make([]domain.Diagnostic, 0, len(parsed))
+	for _, d := range parsed {
+		diagnostics = append(diagnostics, domain.Diagnostic{
+			File:     d.File,
+			Line:     d.Line,
+			Severity: string(d.Severity),
+			Message:  d.Message,
+		})
+	}
+	return diagnostics
 }
 
 func main() {
+	// The grader re-executes itself to set up the simulation sandbox
+	if sandbox.IsInit() {
+		sandbox.Init()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config := config.Load()
+
+	store, err := storage.NewR2Storage(config.R2)
+	if err != nil {
+		log.Fatalf("failed to create storage: %v", err)
+	}
+
+	db, err := database.NewPostgresDB(config.PSQL)
+	if err != nil {
+		log.Fatalf("failed to connect database: %v", err)
+	}
+
+	box, err := sandbox.New(cgroupRoot)
+	if err != nil {
+		log.Fatalf("failed to set up sandbox: %v", err)
+	}
+	// Simulations read the hook from the read-only root filesystem, they can't change it
+	if err := result.InstallProgressHook(pythonHookDir); err != nil {
+		log.Fatalf("failed to install progress hook: %v", err)
+	}
+
+	srv := &server{
+		store:          store,
+		sandbox:        box,
+		broker:         event.NewBroker(),
+		jobRepo:        repository.NewGradingJobRepository(db),
+		submissionRepo: repository.NewSubmissionRepository(db),
+		testcaseRepo:   repository.NewTestcaseRepository(db),
+		problemRepo:    repository.NewProblemRepository(db),
+	}
+
+	worker := queue.NewWorker(
+		srv.jobRepo,
+		srv.handleJob,
+		queue.WithConcurrency(workerCount),
+		queue.WithFailureHandler(srv.handleJobFailure),
+	)
+
 	lis, err := net.Listen("tcp", port)
 	if err != nil {
 		fmt.Println("net.Listen failed:", err.Error())
@@ -307,10 +675,18 @@ func main() {
 
 	// Create gRPC server with the configured options
 	grpcServer := grpc.NewServer(serverOptions...)
-	verilog.RegisterSomeServiceServer(grpcServer, &server{})
+	verilog.RegisterSomeServiceServer(grpcServer, srv)
 
-	if err := grpcServer.Serve(lis); err != nil {
-		fmt.Println("grpcServer.Serve failed:", err.Error())
-		log.Fatalf("failed to serve: %v", err)
-	}
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			fmt.Println("grpcServer.Serve failed:", err.Error())
+			log.Fatalf("failed to serve: %v", err)
+		}
+	}()
+
+	// Blocks until a shutdown signal, letting in-flight jobs finish
+	worker.Start(ctx)
+
+	log.Println("shutting down grader...")
+	grpcServer.GracefulStop()
 }
@@ -9,6 +9,7 @@ package verilog
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,27 +22,85 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-type VerilogRequest struct {
+type Stage int32
+
+const (
+	Stage_STAGE_UNSPECIFIED       Stage = 0
+	Stage_STAGE_FETCHING_PROJECT  Stage = 1
+	Stage_STAGE_COMPILING         Stage = 2
+	Stage_STAGE_RUNNING_TESTS     Stage = 3
+	Stage_STAGE_UPLOADING_RESULTS Stage = 4
+	Stage_STAGE_FINISHED          Stage = 5
+)
+
+// Enum value maps for Stage.
+var (
+	Stage_name = map[int32]string{
+		0: "STAGE_UNSPECIFIED",
+		1: "STAGE_FETCHING_PROJECT",
+		2: "STAGE_COMPILING",
+		3: "STAGE_RUNNING_TESTS",
+		4: "STAGE_UPLOADING_RESULTS",
+		5: "STAGE_FINISHED",
+	}
+	Stage_value = map[string]int32{
+		"STAGE_UNSPECIFIED":       0,
+		"STAGE_FETCHING_PROJECT":  1,
+		"STAGE_COMPILING":         2,
+		"STAGE_RUNNING_TESTS":     3,
+		"STAGE_UPLOADING_RESULTS": 4,
+		"STAGE_FINISHED":          5,
+	}
+)
+
+func (x Stage) Enum() *Stage {
+	p := new(Stage)
+	*p = x
+	return p
+}
+
+func (x Stage) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Stage) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_verilog_proto_enumTypes[0].Descriptor()
+}
+
+func (Stage) Type() protoreflect.EnumType {
+	return &file_proto_verilog_proto_enumTypes[0]
+}
+
+func (x Stage) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Stage.Descriptor instead.
+func (Stage) EnumDescriptor() ([]byte, []int) {
+	return file_proto_verilog_proto_rawDescGZIP(), []int{0}
+}
+
+type GradeRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SubmissionID  uint32                 `protobuf:"varint,1,opt,name=submissionID,proto3" json:"submissionID,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *VerilogRequest) Reset() {
-	*x = VerilogRequest{}
+func (x *GradeRequest) Reset() {
+	*x = GradeRequest{}
 	mi := &file_proto_verilog_proto_msgTypes[0]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *VerilogRequest) String() string {
+func (x *GradeRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*VerilogRequest) ProtoMessage() {}
+func (*GradeRequest) ProtoMessage() {}
 
-func (x *VerilogRequest) ProtoReflect() protoreflect.Message {
+func (x *GradeRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_verilog_proto_msgTypes[0]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -53,39 +112,39 @@ func (x *VerilogRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use VerilogRequest.ProtoReflect.Descriptor instead.
-func (*VerilogRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use GradeRequest.ProtoReflect.Descriptor instead.
+func (*GradeRequest) Descriptor() ([]byte, []int) {
 	return file_proto_verilog_proto_rawDescGZIP(), []int{0}
 }
 
-func (x *VerilogRequest) GetSubmissionID() uint32 {
+func (x *GradeRequest) GetSubmissionID() uint32 {
 	if x != nil {
 		return x.SubmissionID
 	}
 	return 0
 }
 
-type VerilogResponse struct {
+type WatchRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Msg           string                 `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"`
+	SubmissionID  uint32                 `protobuf:"varint,1,opt,name=submissionID,proto3" json:"submissionID,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *VerilogResponse) Reset() {
-	*x = VerilogResponse{}
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
 	mi := &file_proto_verilog_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *VerilogResponse) String() string {
+func (x *WatchRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*VerilogResponse) ProtoMessage() {}
+func (*WatchRequest) ProtoMessage() {}
 
-func (x *VerilogResponse) ProtoReflect() protoreflect.Message {
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_verilog_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -97,35 +156,428 @@ func (x *VerilogResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use VerilogResponse.ProtoReflect.Descriptor instead.
-func (*VerilogResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
 	return file_proto_verilog_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *VerilogResponse) GetMsg() string {
+func (x *WatchRequest) GetSubmissionID() uint32 {
+	if x != nil {
+		return x.SubmissionID
+	}
+	return 0
+}
+
+type StageChanged struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stage         Stage                  `protobuf:"varint,1,opt,name=stage,proto3,enum=proto.Stage" json:"stage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StageChanged) Reset() {
+	*x = StageChanged{}
+	mi := &file_proto_verilog_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StageChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StageChanged) ProtoMessage() {}
+
+func (x *StageChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verilog_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StageChanged.ProtoReflect.Descriptor instead.
+func (*StageChanged) Descriptor() ([]byte, []int) {
+	return file_proto_verilog_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StageChanged) GetStage() Stage {
+	if x != nil {
+		return x.Stage
+	}
+	return Stage_STAGE_UNSPECIFIED
+}
+
+type TestcaseFinished struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Classname     string                 `protobuf:"bytes,1,opt,name=classname,proto3" json:"classname,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Passed        bool                   `protobuf:"varint,3,opt,name=passed,proto3" json:"passed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestcaseFinished) Reset() {
+	*x = TestcaseFinished{}
+	mi := &file_proto_verilog_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestcaseFinished) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestcaseFinished) ProtoMessage() {}
+
+func (x *TestcaseFinished) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verilog_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestcaseFinished.ProtoReflect.Descriptor instead.
+func (*TestcaseFinished) Descriptor() ([]byte, []int) {
+	return file_proto_verilog_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TestcaseFinished) GetClassname() string {
+	if x != nil {
+		return x.Classname
+	}
+	return ""
+}
+
+func (x *TestcaseFinished) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TestcaseFinished) GetPassed() bool {
+	if x != nil {
+		return x.Passed
+	}
+	return false
+}
+
+type GradingSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Passed        uint32                 `protobuf:"varint,1,opt,name=passed,proto3" json:"passed,omitempty"`
+	Failed        uint32                 `protobuf:"varint,2,opt,name=failed,proto3" json:"failed,omitempty"`
+	Total         uint32                 `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Verdict       string                 `protobuf:"bytes,6,opt,name=verdict,proto3" json:"verdict,omitempty"`
+	MemoryUsageMB uint32                 `protobuf:"varint,7,opt,name=memoryUsageMB,proto3" json:"memoryUsageMB,omitempty"`
+	RunTimeMs     uint32                 `protobuf:"varint,8,opt,name=runTimeMs,proto3" json:"runTimeMs,omitempty"`
+	Score         float64                `protobuf:"fixed64,9,opt,name=score,proto3" json:"score,omitempty"`
+	MaxScore      float64                `protobuf:"fixed64,10,opt,name=maxScore,proto3" json:"maxScore,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GradingSummary) Reset() {
+	*x = GradingSummary{}
+	mi := &file_proto_verilog_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GradingSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GradingSummary) ProtoMessage() {}
+
+func (x *GradingSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verilog_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GradingSummary.ProtoReflect.Descriptor instead.
+func (*GradingSummary) Descriptor() ([]byte, []int) {
+	return file_proto_verilog_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GradingSummary) GetPassed() uint32 {
+	if x != nil {
+		return x.Passed
+	}
+	return 0
+}
+
+func (x *GradingSummary) GetFailed() uint32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *GradingSummary) GetTotal() uint32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *GradingSummary) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *GradingSummary) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *GradingSummary) GetVerdict() string {
 	if x != nil {
-		return x.Msg
+		return x.Verdict
 	}
 	return ""
 }
 
+func (x *GradingSummary) GetMemoryUsageMB() uint32 {
+	if x != nil {
+		return x.MemoryUsageMB
+	}
+	return 0
+}
+
+func (x *GradingSummary) GetRunTimeMs() uint32 {
+	if x != nil {
+		return x.RunTimeMs
+	}
+	return 0
+}
+
+func (x *GradingSummary) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *GradingSummary) GetMaxScore() float64 {
+	if x != nil {
+		return x.MaxScore
+	}
+	return 0
+}
+
+type GradingEvent struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	SubmissionID uint32                 `protobuf:"varint,1,opt,name=submissionID,proto3" json:"submissionID,omitempty"`
+	Time         *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*GradingEvent_Stage
+	//	*GradingEvent_Testcase
+	//	*GradingEvent_Summary
+	Event         isGradingEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GradingEvent) Reset() {
+	*x = GradingEvent{}
+	mi := &file_proto_verilog_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GradingEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GradingEvent) ProtoMessage() {}
+
+func (x *GradingEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_verilog_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GradingEvent.ProtoReflect.Descriptor instead.
+func (*GradingEvent) Descriptor() ([]byte, []int) {
+	return file_proto_verilog_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GradingEvent) GetSubmissionID() uint32 {
+	if x != nil {
+		return x.SubmissionID
+	}
+	return 0
+}
+
+func (x *GradingEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *GradingEvent) GetEvent() isGradingEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *GradingEvent) GetStage() *StageChanged {
+	if x != nil {
+		if x, ok := x.Event.(*GradingEvent_Stage); ok {
+			return x.Stage
+		}
+	}
+	return nil
+}
+
+func (x *GradingEvent) GetTestcase() *TestcaseFinished {
+	if x != nil {
+		if x, ok := x.Event.(*GradingEvent_Testcase); ok {
+			return x.Testcase
+		}
+	}
+	return nil
+}
+
+func (x *GradingEvent) GetSummary() *GradingSummary {
+	if x != nil {
+		if x, ok := x.Event.(*GradingEvent_Summary); ok {
+			return x.Summary
+		}
+	}
+	return nil
+}
+
+type isGradingEvent_Event interface {
+	isGradingEvent_Event()
+}
+
+type GradingEvent_Stage struct {
+	Stage *StageChanged `protobuf:"bytes,3,opt,name=stage,proto3,oneof"`
+}
+
+type GradingEvent_Testcase struct {
+	Testcase *TestcaseFinished `protobuf:"bytes,4,opt,name=testcase,proto3,oneof"`
+}
+
+type GradingEvent_Summary struct {
+	Summary *GradingSummary `protobuf:"bytes,5,opt,name=summary,proto3,oneof"`
+}
+
+func (*GradingEvent_Stage) isGradingEvent_Event() {}
+
+func (*GradingEvent_Testcase) isGradingEvent_Event() {}
+
+func (*GradingEvent_Summary) isGradingEvent_Event() {}
+
 var File_proto_verilog_proto protoreflect.FileDescriptor
 
 var file_proto_verilog_proto_rawDesc = string([]byte{
 	0x0a, 0x13, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x65, 0x72, 0x69, 0x6c, 0x6f, 0x67, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x34, 0x0a, 0x0e,
-	0x56, 0x65, 0x72, 0x69, 0x6c, 0x6f, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22,
-	0x0a, 0x0c, 0x73, 0x75, 0x62, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x44, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x73, 0x75, 0x62, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e,
-	0x49, 0x44, 0x22, 0x23, 0x0a, 0x0f, 0x56, 0x65, 0x72, 0x69, 0x6c, 0x6f, 0x67, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x32, 0x43, 0x0a, 0x0b, 0x53, 0x6f, 0x6d, 0x65, 0x53,
-	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x34, 0x0a, 0x03, 0x52, 0x75, 0x6e, 0x12, 0x15, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x6c, 0x6f, 0x67, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x56, 0x65, 0x72,
-	0x69, 0x6c, 0x6f, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x0f, 0x5a, 0x0d,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x65, 0x72, 0x69, 0x6c, 0x6f, 0x67, 0x62, 0x06, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x32, 0x0a,
+	0x0c, 0x47, 0x72, 0x61, 0x64, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a,
+	0x0c, 0x73, 0x75, 0x62, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x44, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x0c, 0x73, 0x75, 0x62, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49,
+	0x44, 0x22, 0x32, 0x0a, 0x0c, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x22, 0x0a, 0x0c, 0x73, 0x75, 0x62, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49,
+	0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x73, 0x75, 0x62, 0x6d, 0x69, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x49, 0x44, 0x22, 0x32, 0x0a, 0x0c, 0x53, 0x74, 0x61, 0x67, 0x65, 0x43, 0x68,
+	0x61, 0x6e, 0x67, 0x65, 0x64, 0x12, 0x22, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x0c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x74, 0x61,
+	0x67, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x22, 0x5c, 0x0a, 0x10, 0x54, 0x65, 0x73,
+	0x74, 0x63, 0x61, 0x73, 0x65, 0x46, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x65, 0x64, 0x12, 0x1c, 0x0a,
+	0x09, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x70, 0x61, 0x73, 0x73, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x06, 0x70, 0x61, 0x73, 0x73, 0x65, 0x64, 0x22, 0x94, 0x02, 0x0a, 0x0e, 0x47, 0x72, 0x61, 0x64,
+	0x69, 0x6e, 0x67, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x61,
+	0x73, 0x73, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x70, 0x61, 0x73, 0x73,
+	0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x06, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f,
+	0x74, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c,
+	0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x18,
+	0x0a, 0x07, 0x76, 0x65, 0x72, 0x64, 0x69, 0x63, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x76, 0x65, 0x72, 0x64, 0x69, 0x63, 0x74, 0x12, 0x24, 0x0a, 0x0d, 0x6d, 0x65, 0x6d, 0x6f,
+	0x72, 0x79, 0x55, 0x73, 0x61, 0x67, 0x65, 0x4d, 0x42, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x0d, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x55, 0x73, 0x61, 0x67, 0x65, 0x4d, 0x42, 0x12, 0x1c,
+	0x0a, 0x09, 0x72, 0x75, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x4d, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x09, 0x72, 0x75, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x4d, 0x73, 0x12, 0x14, 0x0a, 0x05,
+	0x73, 0x63, 0x6f, 0x72, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x73, 0x63, 0x6f,
+	0x72, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6d, 0x61, 0x78, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x18, 0x0a,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x6d, 0x61, 0x78, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x22, 0x82,
+	0x02, 0x0a, 0x0c, 0x47, 0x72, 0x61, 0x64, 0x69, 0x6e, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12,
+	0x22, 0x0a, 0x0c, 0x73, 0x75, 0x62, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x44, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x73, 0x75, 0x62, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x49, 0x44, 0x12, 0x2e, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x04, 0x74,
+	0x69, 0x6d, 0x65, 0x12, 0x2b, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x74, 0x61, 0x67, 0x65,
+	0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x48, 0x00, 0x52, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65,
+	0x12, 0x35, 0x0a, 0x08, 0x74, 0x65, 0x73, 0x74, 0x63, 0x61, 0x73, 0x65, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x54, 0x65, 0x73, 0x74, 0x63,
+	0x61, 0x73, 0x65, 0x46, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x65, 0x64, 0x48, 0x00, 0x52, 0x08, 0x74,
+	0x65, 0x73, 0x74, 0x63, 0x61, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61,
+	0x72, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x47, 0x72, 0x61, 0x64, 0x69, 0x6e, 0x67, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x48,
+	0x00, 0x52, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x42, 0x07, 0x0a, 0x05, 0x65, 0x76,
+	0x65, 0x6e, 0x74, 0x2a, 0x99, 0x01, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x67, 0x65, 0x12, 0x15, 0x0a,
+	0x11, 0x53, 0x54, 0x41, 0x47, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49,
+	0x45, 0x44, 0x10, 0x00, 0x12, 0x1a, 0x0a, 0x16, 0x53, 0x54, 0x41, 0x47, 0x45, 0x5f, 0x46, 0x45,
+	0x54, 0x43, 0x48, 0x49, 0x4e, 0x47, 0x5f, 0x50, 0x52, 0x4f, 0x4a, 0x45, 0x43, 0x54, 0x10, 0x01,
+	0x12, 0x13, 0x0a, 0x0f, 0x53, 0x54, 0x41, 0x47, 0x45, 0x5f, 0x43, 0x4f, 0x4d, 0x50, 0x49, 0x4c,
+	0x49, 0x4e, 0x47, 0x10, 0x02, 0x12, 0x17, 0x0a, 0x13, 0x53, 0x54, 0x41, 0x47, 0x45, 0x5f, 0x52,
+	0x55, 0x4e, 0x4e, 0x49, 0x4e, 0x47, 0x5f, 0x54, 0x45, 0x53, 0x54, 0x53, 0x10, 0x03, 0x12, 0x1b,
+	0x0a, 0x17, 0x53, 0x54, 0x41, 0x47, 0x45, 0x5f, 0x55, 0x50, 0x4c, 0x4f, 0x41, 0x44, 0x49, 0x4e,
+	0x47, 0x5f, 0x52, 0x45, 0x53, 0x55, 0x4c, 0x54, 0x53, 0x10, 0x04, 0x12, 0x12, 0x0a, 0x0e, 0x53,
+	0x54, 0x41, 0x47, 0x45, 0x5f, 0x46, 0x49, 0x4e, 0x49, 0x53, 0x48, 0x45, 0x44, 0x10, 0x05, 0x32,
+	0x77, 0x0a, 0x0b, 0x53, 0x6f, 0x6d, 0x65, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x33,
+	0x0a, 0x05, 0x47, 0x72, 0x61, 0x64, 0x65, 0x12, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x47, 0x72, 0x61, 0x64, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x72, 0x61, 0x64, 0x69, 0x6e, 0x67, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x30, 0x01, 0x12, 0x33, 0x0a, 0x05, 0x57, 0x61, 0x74, 0x63, 0x68, 0x12, 0x13, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x72, 0x61, 0x64, 0x69, 0x6e,
+	0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x42, 0x0f, 0x5a, 0x0d, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2f, 0x76, 0x65, 0x72, 0x69, 0x6c, 0x6f, 0x67, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
 })
 
 var (
@@ -140,19 +592,33 @@ func file_proto_verilog_proto_rawDescGZIP() []byte {
 	return file_proto_verilog_proto_rawDescData
 }
 
-var file_proto_verilog_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_verilog_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_verilog_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_proto_verilog_proto_goTypes = []any{
-	(*VerilogRequest)(nil),  // 0: proto.VerilogRequest
-	(*VerilogResponse)(nil), // 1: proto.VerilogResponse
+	(Stage)(0),                    // 0: proto.Stage
+	(*GradeRequest)(nil),          // 1: proto.GradeRequest
+	(*WatchRequest)(nil),          // 2: proto.WatchRequest
+	(*StageChanged)(nil),          // 3: proto.StageChanged
+	(*TestcaseFinished)(nil),      // 4: proto.TestcaseFinished
+	(*GradingSummary)(nil),        // 5: proto.GradingSummary
+	(*GradingEvent)(nil),          // 6: proto.GradingEvent
+	(*timestamppb.Timestamp)(nil), // 7: google.protobuf.Timestamp
 }
 var file_proto_verilog_proto_depIdxs = []int32{
-	0, // 0: proto.SomeService.Run:input_type -> proto.VerilogRequest
-	1, // 1: proto.SomeService.Run:output_type -> proto.VerilogResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: proto.StageChanged.stage:type_name -> proto.Stage
+	7, // 1: proto.GradingEvent.time:type_name -> google.protobuf.Timestamp
+	3, // 2: proto.GradingEvent.stage:type_name -> proto.StageChanged
+	4, // 3: proto.GradingEvent.testcase:type_name -> proto.TestcaseFinished
+	5, // 4: proto.GradingEvent.summary:type_name -> proto.GradingSummary
+	1, // 5: proto.SomeService.Grade:input_type -> proto.GradeRequest
+	2, // 6: proto.SomeService.Watch:input_type -> proto.WatchRequest
+	6, // 7: proto.SomeService.Grade:output_type -> proto.GradingEvent
+	6, // 8: proto.SomeService.Watch:output_type -> proto.GradingEvent
+	7, // [7:9] is the sub-list for method output_type
+	5, // [5:7] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_proto_verilog_proto_init() }
@@ -160,18 +626,24 @@ func file_proto_verilog_proto_init() {
 	if File_proto_verilog_proto != nil {
 		return
 	}
+	file_proto_verilog_proto_msgTypes[5].OneofWrappers = []any{
+		(*GradingEvent_Stage)(nil),
+		(*GradingEvent_Testcase)(nil),
+		(*GradingEvent_Summary)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_verilog_proto_rawDesc), len(file_proto_verilog_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      1,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_verilog_proto_goTypes,
 		DependencyIndexes: file_proto_verilog_proto_depIdxs,
+		EnumInfos:         file_proto_verilog_proto_enumTypes,
 		MessageInfos:      file_proto_verilog_proto_msgTypes,
 	}.Build()
 	File_proto_verilog_proto = out.File
@@ -19,14 +19,18 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	SomeService_Run_FullMethodName = "/proto.SomeService/Run"
+	SomeService_Grade_FullMethodName = "/proto.SomeService/Grade"
+	SomeService_Watch_FullMethodName = "/proto.SomeService/Watch"
 )
 
 // SomeServiceClient is the client API for SomeService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type SomeServiceClient interface {
-	Run(ctx context.Context, in *VerilogRequest, opts ...grpc.CallOption) (*VerilogResponse, error)
+	// Grade grades a submission right away and streams its progress.
+	Grade(ctx context.Context, in *GradeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GradingEvent], error)
+	// Watch streams the progress of a submission that is being graded by the queue worker.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GradingEvent], error)
 }
 
 type someServiceClient struct {
@@ -37,21 +41,52 @@ func NewSomeServiceClient(cc grpc.ClientConnInterface) SomeServiceClient {
 	return &someServiceClient{cc}
 }
 
-func (c *someServiceClient) Run(ctx context.Context, in *VerilogRequest, opts ...grpc.CallOption) (*VerilogResponse, error) {
+func (c *someServiceClient) Grade(ctx context.Context, in *GradeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GradingEvent], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(VerilogResponse)
-	err := c.cc.Invoke(ctx, SomeService_Run_FullMethodName, in, out, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &SomeService_ServiceDesc.Streams[0], SomeService_Grade_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	x := &grpc.GenericClientStream[GradeRequest, GradingEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SomeService_GradeClient = grpc.ServerStreamingClient[GradingEvent]
+
+func (c *someServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GradingEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SomeService_ServiceDesc.Streams[1], SomeService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, GradingEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
 }
 
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SomeService_WatchClient = grpc.ServerStreamingClient[GradingEvent]
+
 // SomeServiceServer is the server API for SomeService service.
 // All implementations must embed UnimplementedSomeServiceServer
 // for forward compatibility.
 type SomeServiceServer interface {
-	Run(context.Context, *VerilogRequest) (*VerilogResponse, error)
+	// Grade grades a submission right away and streams its progress.
+	Grade(*GradeRequest, grpc.ServerStreamingServer[GradingEvent]) error
+	// Watch streams the progress of a submission that is being graded by the queue worker.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[GradingEvent]) error
 	mustEmbedUnimplementedSomeServiceServer()
 }
 
@@ -62,8 +97,11 @@ type SomeServiceServer interface {
 // pointer dereference when methods are called.
 type UnimplementedSomeServiceServer struct{}
 
-func (UnimplementedSomeServiceServer) Run(context.Context, *VerilogRequest) (*VerilogResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Run not implemented")
+func (UnimplementedSomeServiceServer) Grade(*GradeRequest, grpc.ServerStreamingServer[GradingEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Grade not implemented")
+}
+func (UnimplementedSomeServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[GradingEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
 }
 func (UnimplementedSomeServiceServer) mustEmbedUnimplementedSomeServiceServer() {}
 func (UnimplementedSomeServiceServer) testEmbeddedByValue()                     {}
@@ -86,36 +124,46 @@ func RegisterSomeServiceServer(s grpc.ServiceRegistrar, srv SomeServiceServer) {
 	s.RegisterService(&SomeService_ServiceDesc, srv)
 }
 
-func _SomeService_Run_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(VerilogRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(SomeServiceServer).Run(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: SomeService_Run_FullMethodName,
+func _SomeService_Grade_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GradeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(SomeServiceServer).Run(ctx, req.(*VerilogRequest))
+	return srv.(SomeServiceServer).Grade(m, &grpc.GenericServerStream[GradeRequest, GradingEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SomeService_GradeServer = grpc.ServerStreamingServer[GradingEvent]
+
+func _SomeService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
 	}
-	return interceptor(ctx, in, info, handler)
+	return srv.(SomeServiceServer).Watch(m, &grpc.GenericServerStream[WatchRequest, GradingEvent]{ServerStream: stream})
 }
 
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SomeService_WatchServer = grpc.ServerStreamingServer[GradingEvent]
+
 // SomeService_ServiceDesc is the grpc.ServiceDesc for SomeService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var SomeService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.SomeService",
 	HandlerType: (*SomeServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Grade",
+			Handler:       _SomeService_Grade_Handler,
+			ServerStreams: true,
+		},
 		{
-			MethodName: "Run",
-			Handler:    _SomeService_Run_Handler,
+			StreamName:    "Watch",
+			Handler:       _SomeService_Watch_Handler,
+			ServerStreams: true,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/verilog.proto",
 }